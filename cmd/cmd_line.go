@@ -1,10 +1,11 @@
 package main
 
 import (
-	"github.com/pkg/errors"
 	"log"
 	"os"
 
+	"github.com/pkg/errors"
+
 	"github.com/lavinas/cielo-edi/internal/handlers"
 	"github.com/lavinas/cielo-edi/internal/utils/logger"
 )
@@ -13,7 +14,7 @@ func main() {
 	lg := logger.NewLogger()
 	cm := handlers.NewCommandLine(lg)
 	if err := cm.Run(os.Args); err != nil {
-		err = errors.Wrap(err, "Error")
-		log.Println(err)
+		log.Println(errors.Wrap(err, "Error"))
+		os.Exit(handlers.ExitCode(err))
 	}
 }
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	holidayFormat = "2006-01-02"
+)
+
+var (
+	weekdayMap = map[string]time.Weekday{
+		"sunday": time.Sunday, "sun": time.Sunday,
+		"monday": time.Monday, "mon": time.Monday,
+		"tuesday": time.Tuesday, "tue": time.Tuesday,
+		"wednesday": time.Wednesday, "wed": time.Wednesday,
+		"thursday": time.Thursday, "thu": time.Thursday,
+		"friday": time.Friday, "fri": time.Friday,
+		"saturday": time.Saturday, "sat": time.Saturday,
+	}
+)
+
+// Calendar has the days a statement file is expected: the weekdays that are not holidays
+type Calendar struct {
+	weekdays map[time.Weekday]bool
+	holidays map[time.Time]bool
+}
+
+// NewCalendar creates a calendar from weekday names (monday or mon) and holidays (yyyy-mm-dd).
+// Empty weekdays means every day of the week
+func NewCalendar(weekdays []string, holidays []string) (*Calendar, error) {
+	c := &Calendar{weekdays: make(map[time.Weekday]bool), holidays: make(map[time.Time]bool)}
+	for _, name := range weekdays {
+		day, ok := weekdayMap[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("weekday %s not found (should be monday, tuesday, ... or mon, tue, ...)", name)
+		}
+		c.weekdays[day] = true
+	}
+	for _, holiday := range holidays {
+		date, err := time.Parse(holidayFormat, strings.TrimSpace(holiday))
+		if err != nil {
+			return nil, fmt.Errorf("holiday %s error (should be yyyy-mm-dd)", holiday)
+		}
+		c.holidays[date] = true
+	}
+	return c, nil
+}
+
+// IsExpected checks if a file is expected on a date
+func (c Calendar) IsExpected(date time.Time) bool {
+	if len(c.weekdays) > 0 && !c.weekdays[date.Weekday()] {
+		return false
+	}
+	y, m, d := date.Date()
+	return !c.holidays[time.Date(y, m, d, 0, 0, 0, 0, time.UTC)]
+}
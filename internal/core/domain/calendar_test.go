@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendar(t *testing.T) {
+	c, err := NewCalendar([]string{"mon", "Tuesday", "wed", "thu", "fri"}, []string{"2021-12-24"})
+	assert.Nil(t, err)
+	friday, _ := time.Parse(holidayFormat, "2021-12-17")
+	saturday, _ := time.Parse(holidayFormat, "2021-12-18")
+	holiday, _ := time.Parse(holidayFormat, "2021-12-24")
+	assert.True(t, c.IsExpected(friday))
+	assert.False(t, c.IsExpected(saturday))
+	assert.False(t, c.IsExpected(holiday))
+	c, err = NewCalendar(nil, nil)
+	assert.Nil(t, err)
+	assert.True(t, c.IsExpected(saturday))
+	_, err = NewCalendar([]string{"someday"}, nil)
+	assert.NotNil(t, err)
+	_, err = NewCalendar(nil, []string{"24/12/2021"})
+	assert.NotNil(t, err)
+}
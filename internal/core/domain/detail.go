@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+// RecordLayout is the layout of a detail record type from a layout version of the statement on.
+// Record is a pointer to the struct the lines of the type are parsed into
+type RecordLayout struct {
+	Type       string
+	MinVersion int8
+	Record     ports.RecordInterface
+}
+
+// upgrader is a record of an older layout version. Upgrade converts it to the current record struct,
+// so the records of a type are the same whatever the version of the file
+type upgrader interface {
+	Upgrade() ports.RecordInterface
+}
+
+// Detail parses the detail lines of a statement file with the record layouts of its layout version
+type Detail struct {
+	layouts  []RecordLayout
+	versions []int8
+	typeOf   func(string) string
+	validate func([]ports.RecordInterface) error
+	link     func(ports.RecordInterface)
+	parser   ports.StringParserInterface
+}
+
+// NewDetail creates a detail parser. typeOf returns the record type of a line and validate checks
+// the totals of the records of a file (nil when the statement has no totals)
+func NewDetail(layouts []RecordLayout, typeOf func(string) string, validate func([]ports.RecordInterface) error,
+	parser ports.StringParserInterface) *Detail {
+	return &Detail{layouts: layouts, typeOf: typeOf, validate: validate, parser: parser}
+}
+
+// SetVersions sets the layout versions the record layouts are known for. Files of other versions are rejected,
+// so a new version is not read with the layouts of an older one. Without versions all of them are read
+func (d *Detail) SetVersions(versions []int8) {
+	d.versions = versions
+}
+
+// SetLink sets a function called with each record parsed, in the order of the lines, so a record can get the
+// values of the records before it (like the summary of a sale)
+func (d *Detail) SetLink(link func(ports.RecordInterface)) {
+	d.link = link
+}
+
+// Parse parses a line of a file of a layout version. Lines whose record type has no layout
+// (headers and records not modeled) return a nil record
+func (d Detail) Parse(txt string, version int8) (ports.RecordInterface, error) {
+	if err := d.checkVersion(version); err != nil {
+		return nil, err
+	}
+	recordType := d.typeOf(txt)
+	layout, ok := d.getLayout(recordType, version)
+	if !ok {
+		return nil, nil
+	}
+	record := reflect.New(reflect.TypeOf(layout.Record).Elem()).Interface().(ports.RecordInterface)
+	if err := d.parser.Parse(record, txt); err != nil {
+		return nil, fmt.Errorf("record %s: %v", recordType, err)
+	}
+	if u, ok := record.(upgrader); ok {
+		record = u.Upgrade()
+	}
+	if d.link != nil {
+		d.link(record)
+	}
+	return record, nil
+}
+
+// Validate checks the totals of the records of a file
+func (d Detail) Validate(records []ports.RecordInterface) error {
+	if d.validate == nil {
+		return nil
+	}
+	return d.validate(records)
+}
+
+// checkVersion returns an error when the layout version is not one of the known versions
+func (d Detail) checkVersion(version int8) error {
+	if len(d.versions) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(d.versions))
+	for _, v := range d.versions {
+		if v == version {
+			return nil
+		}
+		names = append(names, fmt.Sprint(v))
+	}
+	return fmt.Errorf("layout version %d not supported (should be %s)", version, strings.Join(names, ", "))
+}
+
+// getLayout returns the layout of a record type with the greatest MinVersion up to version
+func (d Detail) getLayout(recordType string, version int8) (RecordLayout, bool) {
+	found := false
+	var layout RecordLayout
+	for _, l := range d.layouts {
+		if l.Type != recordType || l.MinVersion > version {
+			continue
+		}
+		if !found || l.MinVersion > layout.MinVersion {
+			layout, found = l, true
+		}
+	}
+	return layout, found
+}
+
+// positionType returns the record type of positional lines: its first size characters
+func positionType(size int) func(string) string {
+	return func(txt string) string {
+		if len(txt) < size {
+			return txt
+		}
+		return txt[:size]
+	}
+}
+
+// csvType returns the record type of csv lines: its first column
+func csvType(txt string) string {
+	return strings.SplitN(txt, ",", 2)[0]
+}
+
+// totalError describes a total declared by a record, like a trailer or a summary, that does not match the
+// records of the file
+func totalError(name string, declared interface{}, read interface{}) error {
+	return fmt.Errorf("%s %v does not match the %v read", name, declared, read)
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+var (
+	// cieloVersions has the Cielo EDI layout versions the detail record layouts are known for. The layouts of
+	// a record type that changed between versions are registered with the MinVersion they apply from
+	cieloVersions = []int8{13, 14, 15}
+)
+
+// CieloSummary has the fields of an operation summary (RO, 1), that the sales and financial statements
+// share. Amounts are unsigned with a sign field (+ or -) before them
+type CieloSummary struct {
+	RegisterType       int8        `txt:"1"`
+	Establishment      int64       `txt:"10"`
+	SummaryNumber      int64       `txt:"7"`
+	Installment        string      `txt:"2"`
+	Reserved           string      `txt:"1"`
+	Plan               string      `txt:"2"`
+	TransactionType    string      `txt:"2"`
+	PresentationDate   time.Time   `txt:"yymmdd"`
+	PaymentDate        time.Time   `txt:"yymmdd"`
+	BankSendDate       time.Time   `txt:"yymmdd"`
+	GrossSign          string      `txt:"1"`
+	GrossAmount        ports.Money `txt:"13"`
+	FeeSign            string      `txt:"1"`
+	FeeAmount          ports.Money `txt:"13"`
+	RejectedSign       string      `txt:"1"`
+	RejectedAmount     ports.Money `txt:"13"`
+	NetSign            string      `txt:"1"`
+	NetAmount          ports.Money `txt:"13"`
+	BankCode           int16       `txt:"4"`
+	BranchCode         int32       `txt:"5"`
+	AccountNumber      int64       `txt:"14"`
+	PaymentStatus      string      `txt:"2"`
+	SaleCount          int32       `txt:"6"`
+	ProductCode        string      `txt:"2"`
+	RejectedCount      int32       `txt:"6"`
+	Reseller           string      `txt:"1"`
+	CaptureDate        time.Time   `txt:"yymmdd"`
+	ReasonCode         int16       `txt:"2"`
+	ComplementAmount   ports.Money `txt:"13"`
+	AnticipationId     string      `txt:"1"`
+	AnticipationNumber string      `txt:"9"`
+	AnticipatedSign    string      `txt:"1"`
+	AnticipatedAmount  ports.Money `txt:"13"`
+	Brand              string      `txt:"3"`
+}
+
+// newCieloDetail creates the parser of the detail records of a Cielo statement, that rejects files of
+// unknown layout versions. Cielo trailers have no totals, so validate is nil unless the records carry
+// their own totals, as the Alelo summaries do
+func newCieloDetail(layouts []RecordLayout, typeOf func(string) string, validate func([]ports.RecordInterface) error,
+	parser ports.StringParserInterface) *Detail {
+	detail := NewDetail(layouts, typeOf, validate, parser)
+	detail.SetVersions(cieloVersions)
+	return detail
+}
+
+// signed returns the amount negative when its sign field is -
+func signed(sign string, amount ports.Money) ports.Money {
+	if sign == "-" {
+		return -amount
+	}
+	return amount
+}
@@ -0,0 +1,171 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+var (
+	// cieloAleloLayouts has the detail records of the Cielo Alelo voucher statement (10): the sales summaries (1)
+	// and their sales (2)
+	cieloAleloLayouts = []RecordLayout{
+		{Type: "1", Record: &CieloAleloSummary{}},
+		{Type: "2", Record: &CieloAleloSale{}},
+	}
+	// aleloProducts has the names of the Alelo voucher product codes
+	aleloProducts = map[string]string{
+		"01": "ALELO REFEICAO",
+		"02": "ALELO ALIMENTACAO",
+	}
+)
+
+// CieloAleloSummary is a voucher sales summary (1) of a product of an establishment (EC) paid on PaymentDate
+type CieloAleloSummary struct {
+	RegisterType  int8        `txt:"1"`
+	Establishment int64       `txt:"10"`
+	SummaryNumber int64       `txt:"7"`
+	ProductCode   string      `txt:"2"`
+	SaleDate      time.Time   `txt:"yyyymmdd"`
+	PaymentDate   time.Time   `txt:"yyyymmdd"`
+	SaleCount     int32       `txt:"6"`
+	GrossSign     string      `txt:"1"`
+	GrossAmount   ports.Money `txt:"13"`
+	FeeSign       string      `txt:"1"`
+	FeeAmount     ports.Money `txt:"13"`
+	NetSign       string      `txt:"1"`
+	NetAmount     ports.Money `txt:"13"`
+	BankCode      int16       `txt:"4"`
+	BranchCode    int32       `txt:"5"`
+	AccountNumber int64       `txt:"14"`
+}
+
+func (d CieloAleloSummary) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+func (d CieloAleloSummary) GetSalesDate() time.Time {
+	return d.SaleDate
+}
+
+// GetBrand returns the name of the voucher product, the brand of the sales
+func (d CieloAleloSummary) GetBrand() string {
+	return aleloProduct(d.ProductCode)
+}
+func (d CieloAleloSummary) GetSaleCount() int {
+	return int(d.SaleCount)
+}
+func (d CieloAleloSummary) GetGrossAmount() ports.Money {
+	return signed(d.GrossSign, d.GrossAmount)
+}
+func (d CieloAleloSummary) GetDiscountAmount() ports.Money {
+	return signed(d.FeeSign, d.FeeAmount)
+}
+func (d CieloAleloSummary) GetNetAmount() ports.Money {
+	return signed(d.NetSign, d.NetAmount)
+}
+func (d CieloAleloSummary) GetEstablishment() int64 {
+	return d.Establishment
+}
+func (d CieloAleloSummary) GetPaymentDate() time.Time {
+	return d.PaymentDate
+}
+func (d CieloAleloSummary) GetBankAccount() ports.BankAccount {
+	return ports.BankAccount{Bank: int(d.BankCode), Branch: int(d.BranchCode), Account: d.AccountNumber}
+}
+func (d CieloAleloSummary) GetSettledAmount() ports.Money {
+	return signed(d.NetSign, d.NetAmount)
+}
+
+// CieloAleloSale is a voucher sale (2) of a summary. FeeAmount is the fee of the sale
+type CieloAleloSale struct {
+	RegisterType      int8        `txt:"1"`
+	Establishment     int64       `txt:"10"`
+	SummaryNumber     int64       `txt:"7"`
+	ProductCode       string      `txt:"2"`
+	SaleDate          time.Time   `txt:"yyyymmdd"`
+	SaleTime          string      `txt:"6"`
+	CardNumber        string      `txt:"19"`
+	Nsu               int64       `txt:"12"`
+	AuthorizationCode string      `txt:"6"`
+	GrossSign         string      `txt:"1"`
+	GrossAmount       ports.Money `txt:"13"`
+	FeeSign           string      `txt:"1"`
+	FeeAmount         ports.Money `txt:"13"`
+	NetSign           string      `txt:"1"`
+	NetAmount         ports.Money `txt:"13"`
+	TerminalNumber    string      `txt:"8"`
+}
+
+func (d CieloAleloSale) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+
+// GetProduct returns the name of the voucher product of the sale
+func (d CieloAleloSale) GetProduct() string {
+	return aleloProduct(d.ProductCode)
+}
+
+// NewCieloAleloDetail creates the parser of the Cielo Alelo statement detail records
+func NewCieloAleloDetail(parser ports.StringParserInterface) *Detail {
+	return newCieloDetail(cieloAleloLayouts, positionType(1), validateCieloAlelo, parser)
+}
+
+// validateCieloAlelo checks the sale count and the amounts of each summary against its sales. Summary numbers are
+// unique by establishment only. The trailer has only the record count, checked with the trailer of the file
+func validateCieloAlelo(records []ports.RecordInterface) error {
+	type summaryKey struct {
+		establishment, summary int64
+	}
+	type saleTotal struct {
+		count           int
+		gross, fee, net ports.Money
+	}
+	summaries := make([]*CieloAleloSummary, 0)
+	sales := make(map[summaryKey]*saleTotal)
+	for _, record := range records {
+		switch r := record.(type) {
+		case *CieloAleloSummary:
+			summaries = append(summaries, r)
+		case *CieloAleloSale:
+			key := summaryKey{establishment: r.Establishment, summary: r.SummaryNumber}
+			total, ok := sales[key]
+			if !ok {
+				total = &saleTotal{}
+				sales[key] = total
+			}
+			total.count++
+			total.gross += signed(r.GrossSign, r.GrossAmount)
+			total.fee += signed(r.FeeSign, r.FeeAmount)
+			total.net += signed(r.NetSign, r.NetAmount)
+		}
+	}
+	for _, s := range summaries {
+		total, ok := sales[summaryKey{establishment: s.Establishment, summary: s.SummaryNumber}]
+		if !ok {
+			total = &saleTotal{}
+		}
+		name := fmt.Sprintf("establishment %d summary %d", s.Establishment, s.SummaryNumber)
+		if s.GetSaleCount() != total.count {
+			return totalError(name+" sale count", s.GetSaleCount(), total.count)
+		}
+		if s.GetGrossAmount() != total.gross {
+			return totalError(name+" gross amount", s.GetGrossAmount(), total.gross)
+		}
+		if s.GetDiscountAmount() != total.fee {
+			return totalError(name+" fee amount", s.GetDiscountAmount(), total.fee)
+		}
+		if s.GetNetAmount() != total.net {
+			return totalError(name+" net amount", s.GetNetAmount(), total.net)
+		}
+	}
+	return nil
+}
+
+// aleloProduct returns the name of a voucher product code
+func aleloProduct(code string) string {
+	if product, ok := aleloProducts[code]; ok {
+		return product
+	}
+	return "ALELO " + code
+}
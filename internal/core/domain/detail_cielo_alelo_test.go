@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/lavinas/cielo-edi/internal/utils/string_parser"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	cieloAleloSummary = "1" + "1349678200" + "0000301" + "01" + "20201231" + "20210130" + "000002" + "+" + "0000000006000" +
+		"+" + "0000000000300" + "+" + "0000000005700" + "0237" + "00123" + "00000000045678"
+	cieloAleloSale = "2" + "1349678200" + "0000301" + "01" + "20201231" + "121500" + "5067******1234     " + "000000000021" +
+		"A1B2C3" + "+" + "0000000004000" + "+" + "0000000000200" + "+" + "0000000003800" + "TERM0001"
+	cieloAleloSale2 = "2" + "1349678200" + "0000301" + "01" + "20201231" + "123000" + "5067******4321     " + "000000000022" +
+		"D4E5F6" + "+" + "0000000002000" + "+" + "0000000000100" + "+" + "0000000001900" + "TERM0001"
+)
+
+func TestCieloAleloDetail(t *testing.T) {
+	detail := NewCieloAleloDetail(string_parser.NewStringParser("position"))
+	record, err := detail.Parse(cieloAleloSummary, 13)
+	assert.Nil(t, err)
+	summary := record.(*CieloAleloSummary)
+	var _ ports.SalesSummaryInterface = summary
+	var _ ports.SettlementInterface = summary
+	assert.Equal(t, "1", summary.GetRecordType())
+	assert.Equal(t, "ALELO REFEICAO", summary.GetBrand())
+	assert.Equal(t, time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC), summary.GetSalesDate())
+	assert.Equal(t, time.Date(2021, 1, 30, 0, 0, 0, 0, time.UTC), summary.GetPaymentDate())
+	assert.Equal(t, 2, summary.GetSaleCount())
+	assert.Equal(t, ports.Money(6000), summary.GetGrossAmount())
+	assert.Equal(t, ports.Money(300), summary.GetDiscountAmount())
+	assert.Equal(t, ports.Money(5700), summary.GetSettledAmount())
+	assert.Equal(t, ports.BankAccount{Bank: 237, Branch: 123, Account: 45678}, summary.GetBankAccount())
+	record, err = detail.Parse(cieloAleloSale, 13)
+	assert.Nil(t, err)
+	sale := record.(*CieloAleloSale)
+	assert.Equal(t, "2", sale.GetRecordType())
+	assert.Equal(t, int64(21), sale.Nsu)
+	assert.Equal(t, ports.Money(200), sale.FeeAmount)
+	assert.Equal(t, "TERM0001", sale.TerminalNumber)
+	assert.Equal(t, "ALELO REFEICAO", sale.GetProduct())
+	assert.Equal(t, "ALELO ALIMENTACAO", CieloAleloSale{ProductCode: "02"}.GetProduct())
+	assert.Equal(t, "ALELO 09", CieloAleloSale{ProductCode: "09"}.GetProduct())
+}
+
+func TestCieloAleloTotals(t *testing.T) {
+	detail := NewCieloAleloDetail(string_parser.NewStringParser("position"))
+	records := make([]ports.RecordInterface, 0)
+	for _, line := range []string{cieloAleloSummary, cieloAleloSale, cieloAleloSale2} {
+		record, err := detail.Parse(line, 13)
+		assert.Nil(t, err)
+		records = append(records, record)
+	}
+	assert.Nil(t, detail.Validate(records))
+	assert.Equal(t, "establishment 1349678200 summary 301 sale count 2 does not match the 1 read", detail.Validate(records[:2]).Error())
+	records[2].(*CieloAleloSale).FeeAmount = 150
+	records[2].(*CieloAleloSale).NetAmount = 1850
+	assert.Equal(t, "establishment 1349678200 summary 301 fee amount 3.00 does not match the 3.50 read", detail.Validate(records).Error())
+	records[2].(*CieloAleloSale).GrossAmount = 1000
+	assert.Equal(t, "establishment 1349678200 summary 301 gross amount 60.00 does not match the 50.00 read", detail.Validate(records).Error())
+	assert.Nil(t, detail.Validate(nil))
+	// summaries of two establishments with the same number
+	records = make([]ports.RecordInterface, 0)
+	other := func(line string) string {
+		return strings.Replace(line, "1349678200", "1349678201", 1)
+	}
+	for _, line := range []string{cieloAleloSummary, cieloAleloSale, cieloAleloSale2, other(cieloAleloSummary),
+		other(cieloAleloSale), other(cieloAleloSale2)} {
+		record, err := detail.Parse(line, 13)
+		assert.Nil(t, err)
+		records = append(records, record)
+	}
+	assert.Nil(t, detail.Validate(records))
+	assert.Equal(t, "establishment 1349678201 summary 301 sale count 2 does not match the 1 read",
+		detail.Validate(records[:5]).Error())
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+var (
+	// cieloAnticipationLayouts has the detail records of the Cielo anticipation statement (06): the operations (5)
+	// and the operation summaries (RO) anticipated by them (6)
+	cieloAnticipationLayouts = []RecordLayout{
+		{Type: "5", Record: &CieloAnticipation{}},
+		{Type: "6", Record: &CieloAnticipatedSummary{}},
+	}
+)
+
+// CieloAnticipation is an anticipation operation (5) credited on CreditDate on the bank domicile of an
+// establishment (EC). FeeAmount is the cost of the operation
+type CieloAnticipation struct {
+	RegisterType    int8        `txt:"1"`
+	Establishment   int64       `txt:"10"`
+	OperationNumber int64       `txt:"9"`
+	CreditDate      time.Time   `txt:"yyyymmdd"`
+	GrossSign       string      `txt:"1"`
+	GrossAmount     ports.Money `txt:"13"`
+	FeeSign         string      `txt:"1"`
+	FeeAmount       ports.Money `txt:"13"`
+	NetSign         string      `txt:"1"`
+	NetAmount       ports.Money `txt:"13"`
+	BankCode        int16       `txt:"4"`
+	BranchCode      int32       `txt:"5"`
+	AccountNumber   int64       `txt:"14"`
+}
+
+func (d CieloAnticipation) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+func (d CieloAnticipation) GetOperation() int64 {
+	return d.OperationNumber
+}
+func (d CieloAnticipation) GetEstablishment() int64 {
+	return d.Establishment
+}
+func (d CieloAnticipation) GetCreditDate() time.Time {
+	return d.CreditDate
+}
+func (d CieloAnticipation) GetGrossAmount() ports.Money {
+	return signed(d.GrossSign, d.GrossAmount)
+}
+func (d CieloAnticipation) GetNetAmount() ports.Money {
+	return signed(d.NetSign, d.NetAmount)
+}
+func (d CieloAnticipation) GetPaymentDate() time.Time {
+	return d.CreditDate
+}
+func (d CieloAnticipation) GetBankAccount() ports.BankAccount {
+	return ports.BankAccount{Bank: int(d.BankCode), Branch: int(d.BranchCode), Account: d.AccountNumber}
+}
+func (d CieloAnticipation) GetSettledAmount() ports.Money {
+	return signed(d.NetSign, d.NetAmount)
+}
+
+// CieloAnticipatedSummary is an operation summary (RO, 6) anticipated by an operation. OriginalDate is the date
+// it would be paid without the anticipation
+type CieloAnticipatedSummary struct {
+	RegisterType     int8        `txt:"1"`
+	Establishment    int64       `txt:"10"`
+	OperationNumber  int64       `txt:"9"`
+	OriginalDate     time.Time   `txt:"yyyymmdd"`
+	SummaryNumber    int64       `txt:"7"`
+	Installment      int8        `txt:"2"`
+	InstallmentCount int8        `txt:"2"`
+	Brand            string      `txt:"3"`
+	GrossSign        string      `txt:"1"`
+	GrossAmount      ports.Money `txt:"13"`
+	FeeSign          string      `txt:"1"`
+	FeeAmount        ports.Money `txt:"13"`
+	NetSign          string      `txt:"1"`
+	NetAmount        ports.Money `txt:"13"`
+}
+
+func (d CieloAnticipatedSummary) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+func (d CieloAnticipatedSummary) GetOperation() int64 {
+	return d.OperationNumber
+}
+func (d CieloAnticipatedSummary) GetOriginalDate() time.Time {
+	return d.OriginalDate
+}
+func (d CieloAnticipatedSummary) GetGrossAmount() ports.Money {
+	return signed(d.GrossSign, d.GrossAmount)
+}
+
+// NewCieloAnticipationDetail creates the parser of the Cielo anticipation statement detail records
+func NewCieloAnticipationDetail(parser ports.StringParserInterface) *Detail {
+	return newCieloDetail(cieloAnticipationLayouts, positionType(1), nil, parser)
+}
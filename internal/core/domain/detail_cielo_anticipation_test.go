@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/lavinas/cielo-edi/internal/utils/string_parser"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	cieloAnticipation = "5" + "1023863232" + "000000077" + "20210610" + "+" + "0000000010000" + "+" + "0000000000200" +
+		"+" + "0000000009800" + "0341" + "01234" + "00000000123456"
+	cieloAnticipatedSummary = "6" + "1023863232" + "000000077" + "20210710" + "0000101" + "01" + "03" + "001" +
+		"+" + "0000000010000" + "+" + "0000000000200" + "+" + "0000000009800"
+)
+
+func TestCieloAnticipationDetail(t *testing.T) {
+	detail := NewCieloAnticipationDetail(string_parser.NewStringParser("position"))
+	record, err := detail.Parse(cieloAnticipation, 13)
+	assert.Nil(t, err)
+	operation := record.(*CieloAnticipation)
+	var _ ports.AnticipationInterface = operation
+	var _ ports.SettlementInterface = operation
+	assert.Equal(t, "5", operation.GetRecordType())
+	assert.Equal(t, int64(77), operation.GetOperation())
+	assert.Equal(t, int64(1023863232), operation.GetEstablishment())
+	assert.Equal(t, time.Date(2021, 6, 10, 0, 0, 0, 0, time.UTC), operation.GetCreditDate())
+	assert.Equal(t, ports.Money(10000), operation.GetGrossAmount())
+	assert.Equal(t, ports.Money(9800), operation.GetNetAmount())
+	assert.Equal(t, ports.Money(200), operation.FeeAmount)
+	assert.Equal(t, ports.BankAccount{Bank: 341, Branch: 1234, Account: 123456}, operation.GetBankAccount())
+	assert.Equal(t, ports.Money(9800), operation.GetSettledAmount())
+	record, err = detail.Parse(cieloAnticipatedSummary, 13)
+	assert.Nil(t, err)
+	summary := record.(*CieloAnticipatedSummary)
+	var _ ports.AnticipatedInterface = summary
+	assert.Equal(t, "6", summary.GetRecordType())
+	assert.Equal(t, int64(77), summary.GetOperation())
+	assert.Equal(t, int64(101), summary.SummaryNumber)
+	assert.Equal(t, int8(3), summary.InstallmentCount)
+	assert.Equal(t, time.Date(2021, 7, 10, 0, 0, 0, 0, time.UTC), summary.GetOriginalDate())
+	assert.Equal(t, ports.Money(-10000), CieloAnticipatedSummary{GrossSign: "-", GrossAmount: 10000}.GetGrossAmount())
+	record, err = detail.Parse("9"+"00000000004", 13)
+	assert.Nil(t, err)
+	assert.Nil(t, record)
+	_, err = detail.Parse(cieloAnticipation[:30], 13)
+	assert.NotNil(t, err)
+}
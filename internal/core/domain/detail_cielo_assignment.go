@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+var (
+	// cieloAssignmentLayouts has the detail records of the Cielo receivables assignment statement (07, cessão de
+	// recebíveis): the assignment operations (5) and the receivables assigned by them (6)
+	cieloAssignmentLayouts = []RecordLayout{
+		{Type: "5", Record: &CieloAssignment{}},
+		{Type: "6", Record: &CieloAssignedReceivable{}},
+	}
+)
+
+// CieloAssignment is a receivables assignment operation (5) of an establishment (EC) to a bank or fund,
+// credited on CreditDate. FeeAmount is the discount of the operation
+type CieloAssignment struct {
+	RegisterType    int8        `txt:"1"`
+	Establishment   int64       `txt:"10"`
+	OperationNumber int64       `txt:"9"`
+	AssignmentDate  time.Time   `txt:"yyyymmdd"`
+	CreditDate      time.Time   `txt:"yyyymmdd"`
+	Assignee        string      `txt:"14"`
+	GrossSign       string      `txt:"1"`
+	GrossAmount     ports.Money `txt:"13"`
+	FeeSign         string      `txt:"1"`
+	FeeAmount       ports.Money `txt:"13"`
+	NetSign         string      `txt:"1"`
+	NetAmount       ports.Money `txt:"13"`
+	BankCode        int16       `txt:"4"`
+	BranchCode      int32       `txt:"5"`
+	AccountNumber   int64       `txt:"14"`
+}
+
+func (d CieloAssignment) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+func (d CieloAssignment) GetOperation() int64 {
+	return d.OperationNumber
+}
+func (d CieloAssignment) GetEstablishment() int64 {
+	return d.Establishment
+}
+func (d CieloAssignment) GetAssignee() string {
+	return d.Assignee
+}
+func (d CieloAssignment) GetAssignmentDate() time.Time {
+	return d.AssignmentDate
+}
+func (d CieloAssignment) GetGrossAmount() ports.Money {
+	return signed(d.GrossSign, d.GrossAmount)
+}
+func (d CieloAssignment) GetNetAmount() ports.Money {
+	return signed(d.NetSign, d.NetAmount)
+}
+func (d CieloAssignment) GetPaymentDate() time.Time {
+	return d.CreditDate
+}
+func (d CieloAssignment) GetBankAccount() ports.BankAccount {
+	return ports.BankAccount{Bank: int(d.BankCode), Branch: int(d.BranchCode), Account: d.AccountNumber}
+}
+func (d CieloAssignment) GetSettledAmount() ports.Money {
+	return signed(d.NetSign, d.NetAmount)
+}
+
+// CieloAssignedReceivable is a receivable (6) of an operation summary (RO) assigned by an operation. DueDate is
+// the date it would be paid to the establishment
+type CieloAssignedReceivable struct {
+	RegisterType     int8        `txt:"1"`
+	Establishment    int64       `txt:"10"`
+	OperationNumber  int64       `txt:"9"`
+	DueDate          time.Time   `txt:"yyyymmdd"`
+	SummaryNumber    int64       `txt:"7"`
+	Installment      int8        `txt:"2"`
+	InstallmentCount int8        `txt:"2"`
+	Brand            string      `txt:"3"`
+	GrossSign        string      `txt:"1"`
+	GrossAmount      ports.Money `txt:"13"`
+	NetSign          string      `txt:"1"`
+	NetAmount        ports.Money `txt:"13"`
+}
+
+func (d CieloAssignedReceivable) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+func (d CieloAssignedReceivable) GetOperation() int64 {
+	return d.OperationNumber
+}
+func (d CieloAssignedReceivable) GetDueDate() time.Time {
+	return d.DueDate
+}
+func (d CieloAssignedReceivable) GetGrossAmount() ports.Money {
+	return signed(d.GrossSign, d.GrossAmount)
+}
+
+// NewCieloAssignmentDetail creates the parser of the Cielo receivables assignment statement detail records
+func NewCieloAssignmentDetail(parser ports.StringParserInterface) *Detail {
+	return newCieloDetail(cieloAssignmentLayouts, positionType(1), nil, parser)
+}
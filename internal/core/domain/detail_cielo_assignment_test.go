@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/lavinas/cielo-edi/internal/utils/string_parser"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	cieloAssignment = "5" + "1023863232" + "000000088" + "20210609" + "20210610" + "12345678000199" + "+" + "0000000020000" +
+		"+" + "0000000000600" + "+" + "0000000019400" + "0341" + "01234" + "00000000123456"
+	cieloAssignedReceivable = "6" + "1023863232" + "000000088" + "20210810" + "0000101" + "02" + "03" + "002" +
+		"+" + "0000000020000" + "+" + "0000000019400"
+)
+
+func TestCieloAssignmentDetail(t *testing.T) {
+	detail := NewCieloAssignmentDetail(string_parser.NewStringParser("position"))
+	record, err := detail.Parse(cieloAssignment, 14)
+	assert.Nil(t, err)
+	assignment := record.(*CieloAssignment)
+	var _ ports.AssignmentInterface = assignment
+	var _ ports.SettlementInterface = assignment
+	assert.Equal(t, "5", assignment.GetRecordType())
+	assert.Equal(t, int64(88), assignment.GetOperation())
+	assert.Equal(t, "12345678000199", assignment.GetAssignee())
+	assert.Equal(t, time.Date(2021, 6, 9, 0, 0, 0, 0, time.UTC), assignment.GetAssignmentDate())
+	assert.Equal(t, time.Date(2021, 6, 10, 0, 0, 0, 0, time.UTC), assignment.GetPaymentDate())
+	assert.Equal(t, ports.Money(20000), assignment.GetGrossAmount())
+	assert.Equal(t, ports.Money(600), assignment.FeeAmount)
+	assert.Equal(t, ports.Money(19400), assignment.GetSettledAmount())
+	assert.Equal(t, ports.BankAccount{Bank: 341, Branch: 1234, Account: 123456}, assignment.GetBankAccount())
+	record, err = detail.Parse(cieloAssignedReceivable, 14)
+	assert.Nil(t, err)
+	receivable := record.(*CieloAssignedReceivable)
+	assert.Equal(t, int64(88), receivable.GetOperation())
+	assert.Equal(t, int8(2), receivable.Installment)
+	assert.Equal(t, "002", receivable.Brand)
+	assert.Equal(t, time.Date(2021, 8, 10, 0, 0, 0, 0, time.UTC), receivable.GetDueDate())
+	assert.Equal(t, ports.Money(19400), receivable.NetAmount)
+	record, err = detail.Parse("1"+"1023863232", 14)
+	assert.Nil(t, err)
+	assert.Nil(t, record)
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+var (
+	// cieloOpenBalanceLayouts has the detail records of the Cielo open balance statement (08, saldo em aberto)
+	cieloOpenBalanceLayouts = []RecordLayout{
+		{Type: "5", Record: &CieloOpenBalance{}},
+	}
+)
+
+// CieloOpenBalance is the balance (5) of the receivables of an establishment (EC) and card brand still to be
+// paid on DueDate on the date of the statement. ReceivableCount is the number of installments of the balance
+type CieloOpenBalance struct {
+	RegisterType    int8        `txt:"1"`
+	Establishment   int64       `txt:"10"`
+	Brand           string      `txt:"3"`
+	ProductCode     string      `txt:"3"`
+	DueDate         time.Time   `txt:"yyyymmdd"`
+	ReceivableCount int32       `txt:"9"`
+	GrossSign       string      `txt:"1"`
+	GrossAmount     ports.Money `txt:"13"`
+	NetSign         string      `txt:"1"`
+	NetAmount       ports.Money `txt:"13"`
+}
+
+func (d CieloOpenBalance) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+func (d CieloOpenBalance) GetEstablishment() int64 {
+	return d.Establishment
+}
+func (d CieloOpenBalance) GetBrand() string {
+	return d.Brand
+}
+func (d CieloOpenBalance) GetDueDate() time.Time {
+	return d.DueDate
+}
+func (d CieloOpenBalance) GetReceivableCount() int {
+	return int(d.ReceivableCount)
+}
+func (d CieloOpenBalance) GetGrossAmount() ports.Money {
+	return signed(d.GrossSign, d.GrossAmount)
+}
+func (d CieloOpenBalance) GetNetAmount() ports.Money {
+	return signed(d.NetSign, d.NetAmount)
+}
+
+// NewCieloOpenBalanceDetail creates the parser of the Cielo open balance statement detail records
+func NewCieloOpenBalanceDetail(parser ports.StringParserInterface) *Detail {
+	return newCieloDetail(cieloOpenBalanceLayouts, positionType(1), nil, parser)
+}
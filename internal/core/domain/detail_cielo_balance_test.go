@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/lavinas/cielo-edi/internal/utils/string_parser"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	cieloOpenBalance = "5" + "1023863232" + "001" + "040" + "20210810" + "000000003" + "+" + "0000000030000" + "+" + "0000000029100"
+)
+
+func TestCieloOpenBalanceDetail(t *testing.T) {
+	detail := NewCieloOpenBalanceDetail(string_parser.NewStringParser("position"))
+	record, err := detail.Parse(cieloOpenBalance, 14)
+	assert.Nil(t, err)
+	balance := record.(*CieloOpenBalance)
+	var _ ports.ReceivableInterface = balance
+	assert.Equal(t, "5", balance.GetRecordType())
+	assert.Equal(t, int64(1023863232), balance.GetEstablishment())
+	assert.Equal(t, "001", balance.GetBrand())
+	assert.Equal(t, "040", balance.ProductCode)
+	assert.Equal(t, time.Date(2021, 8, 10, 0, 0, 0, 0, time.UTC), balance.GetDueDate())
+	assert.Equal(t, 3, balance.GetReceivableCount())
+	assert.Equal(t, ports.Money(30000), balance.GetGrossAmount())
+	assert.Equal(t, ports.Money(29100), balance.GetNetAmount())
+	assert.Equal(t, ports.Money(-100), CieloOpenBalance{NetSign: "-", NetAmount: 100}.GetNetAmount())
+	record, err = detail.Parse("9"+"00000000003", 14)
+	assert.Nil(t, err)
+	assert.Nil(t, record)
+	_, err = detail.Parse(cieloOpenBalance[:20], 14)
+	assert.NotNil(t, err)
+}
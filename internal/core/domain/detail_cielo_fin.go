@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+const (
+	cieloCreditAdjustment = "02"
+	cieloDebitAdjustment  = "03"
+)
+
+var (
+	// cieloFinLayouts has the detail records of the Cielo financial statement (04). Operation summaries (RO, 1)
+	// of the credit and debit adjustment transaction types are read as adjustments
+	cieloFinLayouts = []RecordLayout{
+		{Type: "1", Record: &CieloPayment{}},
+		{Type: "1" + cieloCreditAdjustment, Record: &CieloAdjustment{}},
+		{Type: "1" + cieloDebitAdjustment, Record: &CieloAdjustment{}},
+	}
+	// cieloAdjustmentReasons has the descriptions of the adjustment reason codes (origem do ajuste)
+	cieloAdjustmentReasons = map[int16]string{
+		1: "monetary correction",
+		2: "payment date",
+		3: "fee rate",
+		4: "amounts not captured",
+		5: "undue amounts",
+		6: "cancellation",
+		7: "chargeback",
+	}
+)
+
+// CieloPayment is an operation summary (RO, 1) paid on PaymentDate on the bank domicile of an establishment (EC)
+type CieloPayment struct {
+	CieloSummary
+}
+
+func (d CieloPayment) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+func (d CieloPayment) GetEstablishment() int64 {
+	return d.Establishment
+}
+func (d CieloPayment) GetPaymentDate() time.Time {
+	return d.PaymentDate
+}
+func (d CieloPayment) GetBankAccount() ports.BankAccount {
+	return ports.BankAccount{Bank: int(d.BankCode), Branch: int(d.BranchCode), Account: d.AccountNumber}
+}
+func (d CieloPayment) GetSettledAmount() ports.Money {
+	return signed(d.NetSign, d.NetAmount)
+}
+
+// CieloAdjustment is an operation summary (RO, 1) of a credit (02) or debit (03) adjustment settled on PaymentDate
+type CieloAdjustment struct {
+	CieloSummary
+}
+
+func (d CieloAdjustment) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType) + d.TransactionType
+}
+
+// GetReason returns the description of the reason code of the adjustment
+func (d CieloAdjustment) GetReason() string {
+	if reason, ok := cieloAdjustmentReasons[d.ReasonCode]; ok {
+		return reason
+	}
+	return fmt.Sprintf("reason %02d", d.ReasonCode)
+}
+func (d CieloAdjustment) GetEstablishment() int64 {
+	return d.Establishment
+}
+func (d CieloAdjustment) GetPaymentDate() time.Time {
+	return d.PaymentDate
+}
+func (d CieloAdjustment) GetBankAccount() ports.BankAccount {
+	return ports.BankAccount{Bank: int(d.BankCode), Branch: int(d.BranchCode), Account: d.AccountNumber}
+}
+
+// GetSettledAmount returns the net amount of the adjustment, negative for debits
+func (d CieloAdjustment) GetSettledAmount() ports.Money {
+	amount := signed(d.NetSign, d.NetAmount)
+	if d.TransactionType == cieloDebitAdjustment && amount > 0 {
+		return -amount
+	}
+	return amount
+}
+
+// NewCieloFinDetail creates the parser of the Cielo financial statement detail records
+func NewCieloFinDetail(parser ports.StringParserInterface) *Detail {
+	return newCieloDetail(cieloFinLayouts, cieloFinType, nil, parser)
+}
+
+// cieloFinType returns the record type of a financial statement line: its first character, followed by
+// the transaction type for adjustment summaries
+func cieloFinType(txt string) string {
+	recordType := positionType(1)(txt)
+	if recordType != "1" || len(txt) < 25 {
+		return recordType
+	}
+	if transaction := txt[23:25]; transaction == cieloCreditAdjustment || transaction == cieloDebitAdjustment {
+		return recordType + transaction
+	}
+	return recordType
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/lavinas/cielo-edi/internal/utils/string_parser"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	// cieloRoTail has the fields of an operation summary (RO) between the adjustment reason and the brand: the
+	// complementary amount and the anticipation operation
+	cieloRoTail     = "0000000000000" + " " + "000000000" + "+" + "0000000000000"
+	cieloFinPayment = "1" + "1023863232" + "0000101" + "00" + " " + "01" + "01" + "210628" + "210630" + "210629" +
+		"+" + "0000000015000" + "-" + "0000000000300" + "+" + "0000000000000" + "+" + "0000000014700" +
+		"0341" + "01234" + "00000000123456" + "00" + "000002" + "01" + "000000" + " " + "210628" + "00" + cieloRoTail + "002"
+	cieloFinAdjustment = "1" + "1023863232" + "0000102" + "00" + " " + "00" + "03" + "210628" + "210630" + "210629" +
+		"-" + "0000000002000" + "+" + "0000000000000" + "+" + "0000000000000" + "-" + "0000000002000" +
+		"0341" + "01234" + "00000000123456" + "00" + "000000" + "00" + "000000" + " " + "210625" + "07" + cieloRoTail + "001"
+)
+
+func TestCieloFinDetail(t *testing.T) {
+	detail := NewCieloFinDetail(string_parser.NewStringParser("position"))
+	account := ports.BankAccount{Bank: 341, Branch: 1234, Account: 123456}
+	paymentDate := time.Date(2021, 6, 30, 0, 0, 0, 0, time.UTC)
+	record, err := detail.Parse(cieloFinPayment, 14)
+	assert.Nil(t, err)
+	payment := record.(*CieloPayment)
+	assert.Equal(t, "1", payment.GetRecordType())
+	assert.Equal(t, int64(101), payment.SummaryNumber)
+	assert.Equal(t, time.Date(2021, 6, 28, 0, 0, 0, 0, time.UTC), payment.PresentationDate)
+	assert.Equal(t, ports.Money(15000), payment.GrossAmount)
+	assert.Equal(t, int32(2), payment.SaleCount)
+	assert.Equal(t, "002", payment.Brand)
+	assert.Equal(t, time.Date(2021, 6, 28, 0, 0, 0, 0, time.UTC), payment.CaptureDate)
+	record, err = detail.Parse(cieloFinAdjustment, 14)
+	assert.Nil(t, err)
+	adjustment := record.(*CieloAdjustment)
+	assert.Equal(t, "103", adjustment.GetRecordType())
+	assert.Equal(t, int16(7), adjustment.ReasonCode)
+	assert.Equal(t, "chargeback", adjustment.GetReason())
+	assert.Equal(t, "reason 99", CieloAdjustment{CieloSummary{ReasonCode: 99}}.GetReason())
+	assert.Equal(t, time.Date(2021, 6, 25, 0, 0, 0, 0, time.UTC), adjustment.CaptureDate)
+	tests := []struct {
+		record  ports.SettlementInterface
+		settled ports.Money
+	}{
+		{payment, 14700},
+		{adjustment, -2000},
+		{CieloAdjustment{CieloSummary{TransactionType: "03", NetSign: "+", NetAmount: 500}}, -500},
+		{CieloAdjustment{CieloSummary{TransactionType: "02", NetSign: "+", NetAmount: 500}}, 500},
+		{CieloPayment{CieloSummary{NetSign: "-", NetAmount: 500}}, -500},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.settled, test.record.GetSettledAmount())
+	}
+	for _, settlement := range []ports.SettlementInterface{payment, adjustment} {
+		assert.Equal(t, int64(1023863232), settlement.GetEstablishment())
+		assert.Equal(t, paymentDate, settlement.GetPaymentDate())
+		assert.Equal(t, account, settlement.GetBankAccount())
+	}
+	record, err = detail.Parse("9"+"00000000004", 14)
+	assert.Nil(t, err)
+	assert.Nil(t, record)
+	assert.Nil(t, detail.Validate([]ports.RecordInterface{payment}))
+}
+
+func TestCieloFinType(t *testing.T) {
+	assert.Equal(t, "1", cieloFinType(cieloFinPayment))
+	assert.Equal(t, "103", cieloFinType(cieloFinAdjustment))
+	assert.Equal(t, "1", cieloFinType("1"))
+	assert.Equal(t, "9", cieloFinType("900000000004"))
+	assert.Equal(t, "", cieloFinType(""))
+}
@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+var (
+	// cieloSalesLayouts has the detail records of the Cielo sales statement (03): the operation summaries (RO, 1)
+	// and their sales (CV, 2). Sales got the 12 digits NSU and the sale time on layout version 15
+	cieloSalesLayouts = []RecordLayout{
+		{Type: "1", Record: &CieloSaleSummary{}},
+		{Type: "2", Record: &CieloSaleV13{}},
+		{Type: "2", MinVersion: 15, Record: &CieloSale{}},
+	}
+)
+
+// CieloSaleSummary is an operation summary (RO, 1) of the sales of an establishment (EC) and card brand
+// captured on CaptureDate, to be paid on PaymentDate
+type CieloSaleSummary struct {
+	CieloSummary
+}
+
+func (d CieloSaleSummary) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+func (d CieloSaleSummary) GetSalesDate() time.Time {
+	return d.CaptureDate
+}
+func (d CieloSaleSummary) GetBrand() string {
+	return d.Brand
+}
+func (d CieloSaleSummary) GetSaleCount() int {
+	return int(d.SaleCount)
+}
+func (d CieloSaleSummary) GetGrossAmount() ports.Money {
+	return signed(d.GrossSign, d.GrossAmount)
+}
+func (d CieloSaleSummary) GetDiscountAmount() ports.Money {
+	return signed(d.FeeSign, d.FeeAmount)
+}
+func (d CieloSaleSummary) GetNetAmount() ports.Money {
+	return signed(d.NetSign, d.NetAmount)
+}
+func (d CieloSaleSummary) GetEstablishment() int64 {
+	return d.Establishment
+}
+func (d CieloSaleSummary) GetSummaryNumber() int64 {
+	return d.SummaryNumber
+}
+func (d CieloSaleSummary) GetInstallment() int {
+	return cieloInstallment(d.Installment)
+}
+func (d CieloSaleSummary) GetScheduledDate() time.Time {
+	return d.PaymentDate
+}
+
+// CieloSale is a sale (CV, 2) of an operation summary. RejectReason is blank for accepted sales. The CV has no
+// brand nor payment date: they are copied from its summary when the sale is parsed
+type CieloSale struct {
+	RegisterType      int8        `txt:"1"`
+	Establishment     int64       `txt:"10"`
+	SummaryNumber     int64       `txt:"7"`
+	CardNumber        string      `txt:"19"`
+	SaleDate          time.Time   `txt:"yyyymmdd"`
+	AmountSign        string      `txt:"1"`
+	Amount            ports.Money `txt:"13"`
+	Installment       int8        `txt:"2"`
+	InstallmentCount  int8        `txt:"2"`
+	RejectReason      string      `txt:"3"`
+	AuthorizationCode string      `txt:"6"`
+	Tid               string      `txt:"20"`
+	Nsu               int64       `txt:"12"`
+	SaleTime          string      `txt:"6"`
+	Brand             string      `txt:"-"`
+	PaymentDate       time.Time   `txt:"-"`
+}
+
+func (d CieloSale) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+
+// CieloSaleV13 is the sale (CV, 2) of the layout versions before 15, with a 6 digits NSU and without the sale time
+type CieloSaleV13 struct {
+	RegisterType      int8        `txt:"1"`
+	Establishment     int64       `txt:"10"`
+	SummaryNumber     int64       `txt:"7"`
+	CardNumber        string      `txt:"19"`
+	SaleDate          time.Time   `txt:"yyyymmdd"`
+	AmountSign        string      `txt:"1"`
+	Amount            ports.Money `txt:"13"`
+	Installment       int8        `txt:"2"`
+	InstallmentCount  int8        `txt:"2"`
+	RejectReason      string      `txt:"3"`
+	AuthorizationCode string      `txt:"6"`
+	Tid               string      `txt:"20"`
+	Nsu               int64       `txt:"6"`
+}
+
+func (d CieloSaleV13) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+
+func (d CieloSaleV13) Upgrade() ports.RecordInterface {
+	return &CieloSale{RegisterType: d.RegisterType, Establishment: d.Establishment, SummaryNumber: d.SummaryNumber,
+		CardNumber: d.CardNumber, SaleDate: d.SaleDate, AmountSign: d.AmountSign, Amount: d.Amount, Installment: d.Installment,
+		InstallmentCount: d.InstallmentCount, RejectReason: d.RejectReason, AuthorizationCode: d.AuthorizationCode,
+		Tid: d.Tid, Nsu: d.Nsu}
+}
+
+// NewCieloSalesDetail creates the parser of the Cielo sales statement detail records
+func NewCieloSalesDetail(parser ports.StringParserInterface) *Detail {
+	detail := newCieloDetail(cieloSalesLayouts, positionType(1), nil, parser)
+	detail.SetLink(cieloSalesLink())
+	return detail
+}
+
+// cieloSalesLink returns the link of the sales statement records: sales get the brand and payment date of the last
+// summary read, when they are of that summary
+func cieloSalesLink() func(ports.RecordInterface) {
+	var summary *CieloSaleSummary
+	return func(record ports.RecordInterface) {
+		switch r := record.(type) {
+		case *CieloSaleSummary:
+			summary = r
+		case *CieloSale:
+			if summary != nil && summary.Establishment == r.Establishment && summary.SummaryNumber == r.SummaryNumber {
+				r.Brand, r.PaymentDate = summary.Brand, summary.PaymentDate
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/lavinas/cielo-edi/internal/utils/string_parser"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	cieloSaleSummary = "1" + "1023863232" + "0000101" + "00" + " " + "00" + "01" + "210310" + "210409" + "210408" +
+		"+" + "0000000015000" + "+" + "0000000000450" + "+" + "0000000000000" + "+" + "0000000014550" +
+		"0341" + "01234" + "00000000123456" + "00" + "000002" + "01" + "000000" + " " + "210310" + "00" + cieloRoTail + "001"
+	cieloSaleV13 = "2" + "1023863232" + "0000101" + "455187******1234   " + "20210310" + "+" + "0000000010000" + "01" + "01" +
+		"   " + "A1B2C3" + "10017348980310A1B2C3" + "123456"
+	cieloSaleV15 = "2" + "1023863232" + "0000101" + "455187******1234   " + "20210310" + "+" + "0000000010000" + "01" + "01" +
+		"   " + "A1B2C3" + "10017348980310A1B2C3" + "000001234567" + "101530"
+)
+
+func TestCieloSalesDetail(t *testing.T) {
+	detail := NewCieloSalesDetail(string_parser.NewStringParser("position"))
+	record, err := detail.Parse(cieloSaleSummary, 13)
+	assert.Nil(t, err)
+	summary := record.(*CieloSaleSummary)
+	var _ ports.SalesSummaryInterface = summary
+	assert.Equal(t, "1", summary.GetRecordType())
+	assert.Equal(t, time.Date(2021, 3, 10, 0, 0, 0, 0, time.UTC), summary.GetSalesDate())
+	assert.Equal(t, time.Date(2021, 4, 9, 0, 0, 0, 0, time.UTC), summary.PaymentDate)
+	assert.Equal(t, "001", summary.GetBrand())
+	assert.Equal(t, 2, summary.GetSaleCount())
+	assert.Equal(t, ports.Money(15000), summary.GetGrossAmount())
+	assert.Equal(t, ports.Money(450), summary.GetDiscountAmount())
+	assert.Equal(t, ports.Money(14550), summary.GetNetAmount())
+	var scheduled ports.ScheduledPaymentInterface = summary
+	assert.Equal(t, int64(1023863232), scheduled.GetEstablishment())
+	assert.Equal(t, int64(101), scheduled.GetSummaryNumber())
+	assert.Equal(t, 0, scheduled.GetInstallment())
+	assert.Equal(t, summary.PaymentDate, scheduled.GetScheduledDate())
+}
+
+func TestCieloSalesVersions(t *testing.T) {
+	detail := NewCieloSalesDetail(string_parser.NewStringParser("position"))
+	for _, version := range []int8{13, 14} {
+		record, err := detail.Parse(cieloSaleV13, version)
+		assert.Nil(t, err)
+		sale := record.(*CieloSale)
+		assert.Equal(t, "2", sale.GetRecordType())
+		assert.Equal(t, int64(123456), sale.Nsu)
+		assert.Equal(t, "", sale.SaleTime)
+		assert.Equal(t, "10017348980310A1B2C3", sale.Tid)
+		assert.Equal(t, ports.Money(10000), sale.Amount)
+	}
+	record, err := detail.Parse(cieloSaleV15, 15)
+	assert.Nil(t, err)
+	sale := record.(*CieloSale)
+	assert.Equal(t, int64(1234567), sale.Nsu)
+	assert.Equal(t, "101530", sale.SaleTime)
+	assert.Equal(t, "A1B2C3", sale.AuthorizationCode)
+	_, err = detail.Parse(cieloSaleV13, 15)
+	assert.NotNil(t, err)
+	_, err = detail.Parse(cieloSaleV15, 16)
+	assert.NotNil(t, err)
+	assert.Equal(t, "layout version 16 not supported (should be 13, 14, 15)", err.Error())
+	_, err = detail.Parse(cieloSaleSummary, 12)
+	assert.Equal(t, "layout version 12 not supported (should be 13, 14, 15)", err.Error())
+	unversioned := NewDetail(cieloSalesLayouts, positionType(1), nil, string_parser.NewStringParser("position"))
+	_, err = unversioned.Parse(cieloSaleV13, 12)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,236 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+const (
+	// cnab240Size and cnab400Size are the lengths, in characters, of the lines of the CNAB 240 and CNAB 400 files
+	cnab240Size = 240
+	cnab400Size = 400
+	// cnab400Return is the start of the header record (0) of a CNAB 400 return file (operation 2, RETORNO)
+	cnab400Return = "02RETORNO"
+)
+
+var (
+	// cnabLayouts has the detail records of the bank statement return files: the entries (segment E, 3E) and batch
+	// trailers (5) of the FEBRABAN CNAB 240 layout and the entries (1) of the CNAB 400 layout. Record types are
+	// prefixed by the line length, the layout of the file
+	cnabLayouts = []RecordLayout{
+		{Type: "240-3E", Record: &Cnab240Entry{}},
+		{Type: "240-5", Record: &Cnab240BatchTrailer{}},
+		{Type: "400-1", Record: &Cnab400Entry{}},
+	}
+)
+
+// Cnab240Entry is an entry (segment E) of a CNAB 240 bank statement on the account of a batch. EntryType is D for
+// debits and C for credits
+type Cnab240Entry struct {
+	BankCode           int16       `txt:"3"`
+	Batch              int32       `txt:"4"`
+	RegisterType       int8        `txt:"1"`
+	Sequence           int32       `txt:"5"`
+	Segment            string      `txt:"1"`
+	Filler1            string      `txt:"3"`
+	InscriptionType    string      `txt:"1"`
+	Inscription        string      `txt:"14"`
+	Agreement          string      `txt:"20"`
+	BranchCode         int32       `txt:"5"`
+	BranchDigit        string      `txt:"1"`
+	AccountNumber      int64       `txt:"12"`
+	AccountDigit       string      `txt:"1"`
+	BranchAccountDigit string      `txt:"1"`
+	CompanyName        string      `txt:"30"`
+	Filler2            string      `txt:"6"`
+	Nature             string      `txt:"3"`
+	ComplementType     string      `txt:"2"`
+	Complement         string      `txt:"20"`
+	Cpmf               string      `txt:"1"`
+	AccountingDate     time.Time   `txt:"ddmmyyyy"`
+	EntryDate          time.Time   `txt:"ddmmyyyy"`
+	Amount             ports.Money `txt:"18"`
+	EntryType          string      `txt:"1"`
+	Category           string      `txt:"3"`
+	HistoryCode        string      `txt:"4"`
+	History            string      `txt:"25"`
+	Document           string      `txt:"39"`
+}
+
+func (d Cnab240Entry) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType) + d.Segment
+}
+func (d Cnab240Entry) GetBankEntry() ports.BankEntry {
+	return ports.BankEntry{Date: d.EntryDate, Account: ports.BankAccount{Bank: int(d.BankCode), Branch: int(d.BranchCode),
+		Account: d.AccountNumber}, AccountDigit: strings.TrimSpace(d.AccountDigit), Amount: bankAmount(d.EntryType, d.Amount),
+		History: strings.TrimSpace(d.History), Document: strings.TrimSpace(d.Document)}
+}
+
+// Cnab240BatchTrailer is the trailer (5) of a batch of a CNAB 240 bank statement with the final balance of the
+// account and the totals of the debit and credit entries of the batch
+type Cnab240BatchTrailer struct {
+	BankCode           int16       `txt:"3"`
+	Batch              int32       `txt:"4"`
+	RegisterType       int8        `txt:"1"`
+	Filler1            string      `txt:"9"`
+	InscriptionType    string      `txt:"1"`
+	Inscription        string      `txt:"14"`
+	Agreement          string      `txt:"20"`
+	BranchCode         int32       `txt:"5"`
+	BranchDigit        string      `txt:"1"`
+	AccountNumber      int64       `txt:"12"`
+	AccountDigit       string      `txt:"1"`
+	BranchAccountDigit string      `txt:"1"`
+	Filler2            string      `txt:"16"`
+	LinkedAmount       string      `txt:"18"`
+	LimitAmount        string      `txt:"18"`
+	BlockedAmount      string      `txt:"18"`
+	BalanceDate        string      `txt:"8"`
+	BalanceAmount      ports.Money `txt:"18"`
+	BalanceStatus      string      `txt:"1"`
+	BalancePosition    string      `txt:"1"`
+	RecordCount        int32       `txt:"6"`
+	DebitAmount        ports.Money `txt:"18"`
+	CreditAmount       ports.Money `txt:"18"`
+	Filler3            string      `txt:"28"`
+}
+
+func (d Cnab240BatchTrailer) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+
+// Cnab400Entry is an entry (1) of a CNAB 400 bank statement. Banks have their own CNAB 400 statement layouts; this one
+// has the account and entry fields of the CNAB 240 segment E. EntryType is D for debits and C for credits
+type Cnab400Entry struct {
+	RegisterType    int8        `txt:"1"`
+	InscriptionType string      `txt:"2"`
+	Inscription     string      `txt:"14"`
+	BankCode        int16       `txt:"3"`
+	BranchCode      int32       `txt:"5"`
+	BranchDigit     string      `txt:"1"`
+	AccountNumber   int64       `txt:"12"`
+	AccountDigit    string      `txt:"1"`
+	Category        string      `txt:"3"`
+	HistoryCode     string      `txt:"4"`
+	History         string      `txt:"25"`
+	Document        string      `txt:"20"`
+	EntryDate       time.Time   `txt:"ddmmyyyy"`
+	Amount          ports.Money `txt:"18"`
+	EntryType       string      `txt:"1"`
+	Filler          string      `txt:"276"`
+	Sequence        int32       `txt:"6"`
+}
+
+func (d Cnab400Entry) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+func (d Cnab400Entry) GetBankEntry() ports.BankEntry {
+	return ports.BankEntry{Date: d.EntryDate, Account: ports.BankAccount{Bank: int(d.BankCode), Branch: int(d.BranchCode),
+		Account: d.AccountNumber}, AccountDigit: strings.TrimSpace(d.AccountDigit), Amount: bankAmount(d.EntryType, d.Amount),
+		History: strings.TrimSpace(d.History), Document: strings.TrimSpace(d.Document)}
+}
+
+// CnabDetail parses the detail records of the CNAB 240 and CNAB 400 bank statements. The layout of each line is
+// given by its length. CNAB 400 records end with their sequence number, so only CNAB 240 lines may have had their
+// trailing blanks trimmed: shorter lines are padded to 240 characters
+type CnabDetail struct {
+	*Detail
+}
+
+// NewCnabDetail creates the parser of the CNAB 240 and CNAB 400 bank statement detail records
+func NewCnabDetail(parser ports.StringParserInterface) *CnabDetail {
+	return &CnabDetail{Detail: NewDetail(cnabLayouts, cnabType, validateCnab, parser)}
+}
+
+// Parse parses a line of a CNAB 240 or CNAB 400 file. The size of the line is counted in characters, not bytes
+func (d CnabDetail) Parse(txt string, version int8) (ports.RecordInterface, error) {
+	size := utf8.RuneCountInString(txt)
+	switch {
+	case size <= cnab240Size:
+		txt += strings.Repeat(" ", cnab240Size-size)
+	case size != cnab400Size:
+		return nil, fmt.Errorf("line has %d characters (should be %d or %d)", size, cnab240Size, cnab400Size)
+	}
+	return d.Detail.Parse(txt, version)
+}
+
+// IsHeader tells whether a line is the header record (0) of a CNAB file: the bank code and a batch 0000 on CNAB 240
+// or the return operation on CNAB 400
+func (d CnabDetail) IsHeader(txt string) bool {
+	if strings.HasPrefix(txt, cnab400Return) {
+		return true
+	}
+	runes := []rune(txt)
+	if len(runes) < 8 || string(runes[3:8]) != "00000" {
+		return false
+	}
+	for _, r := range runes[:3] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// cnabType returns the record type of a CNAB line prefixed by its layout. CNAB 240 detail records (3) are typed by
+// their segment
+func cnabType(txt string) string {
+	runes := []rune(txt)
+	switch len(runes) {
+	case cnab240Size:
+		if runes[7] == '3' {
+			return "240-3" + string(runes[13])
+		}
+		return "240-" + string(runes[7])
+	case cnab400Size:
+		return "400-" + string(runes[0])
+	}
+	return ""
+}
+
+// validateCnab checks the debit and credit totals of the CNAB 240 batch trailers against the entries of their batches
+func validateCnab(records []ports.RecordInterface) error {
+	type batchTotal struct {
+		debits, credits ports.Money
+	}
+	totals := make(map[int32]*batchTotal)
+	for _, record := range records {
+		switch r := record.(type) {
+		case *Cnab240Entry:
+			total, ok := totals[r.Batch]
+			if !ok {
+				total = &batchTotal{}
+				totals[r.Batch] = total
+			}
+			if amount := bankAmount(r.EntryType, r.Amount); amount < 0 {
+				total.debits -= amount
+			} else {
+				total.credits += amount
+			}
+		case *Cnab240BatchTrailer:
+			total, ok := totals[r.Batch]
+			if !ok {
+				total = &batchTotal{}
+			}
+			if r.DebitAmount != total.debits {
+				return totalError(fmt.Sprintf("trailer batch %d debit amount", r.Batch), r.DebitAmount, total.debits)
+			}
+			if r.CreditAmount != total.credits {
+				return totalError(fmt.Sprintf("trailer batch %d credit amount", r.Batch), r.CreditAmount, total.credits)
+			}
+		}
+	}
+	return nil
+}
+
+// bankAmount returns the amount of a bank entry, negative for debits (D)
+func bankAmount(entryType string, amount ports.Money) ports.Money {
+	if strings.TrimSpace(entryType) == "D" {
+		return -amount
+	}
+	return amount
+}
@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/lavinas/cielo-edi/internal/utils/string_parser"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	cnab240Header = "34100000" + "         " + "212345678000199"
+	cnab400Header = "0" + "2RETORNO"
+)
+
+// cnab240Entry returns a segment E line of the account 341/01234/123456-7 on 09/04/2021
+func cnab240Entry(sequence string, amount string, entryType string) string {
+	return "341" + "0001" + "3" + sequence + "E" + "   " + "2" + "12345678000199" + fmt.Sprintf("%-20s", "CONV01") + "01234" +
+		"5" + "000000123456" + "7" + " " + fmt.Sprintf("%-30s", "CLIENTE X") + "      " + "DPV" + "  " + strings.Repeat(" ", 20) +
+		" " + "09042021" + "09042021" + amount + entryType + "213" + "0123" + fmt.Sprintf("%-25s", "CIELO SA") +
+		fmt.Sprintf("%-39s", "1023863232")
+}
+
+// cnab240Trailer returns a batch trailer line with debit and credit totals
+func cnab240Trailer(debits string, credits string) string {
+	return "341" + "0001" + "5" + strings.Repeat(" ", 9) + "2" + "12345678000199" + fmt.Sprintf("%-20s", "CONV01") + "01234" +
+		"5" + "000000123456" + "7" + " " + strings.Repeat(" ", 16) + strings.Repeat("0", 54) + "09042021" +
+		"000000000000100000" + "P" + "F" + "000004" + debits + credits + strings.Repeat(" ", 28)
+}
+
+// cnab400Entry returns an entry line of the account 237/00123/45678-9 on 10/04/2021
+func cnab400Entry(amount string, entryType string) string {
+	return "1" + "02" + "12345678000199" + "237" + "00123" + "4" + "000000045678" + "9" + "213" + "0456" +
+		fmt.Sprintf("%-25s", "REDECARD SA") + fmt.Sprintf("%-20s", "DOC1") + "10042021" + amount + entryType +
+		strings.Repeat(" ", 276) + "000002"
+}
+
+func TestCnabDetail(t *testing.T) {
+	detail := NewCnabDetail(string_parser.NewStringParser("position"))
+	line := cnab240Entry("00001", "000000000000014550", "C")
+	assert.Len(t, line, 240)
+	record, err := detail.Parse(line, 0)
+	assert.Nil(t, err)
+	entry := record.(*Cnab240Entry)
+	var _ ports.BankEntryInterface = entry
+	assert.Equal(t, "3E", entry.GetRecordType())
+	assert.Equal(t, ports.BankEntry{Date: time.Date(2021, 4, 9, 0, 0, 0, 0, time.UTC),
+		Account: ports.BankAccount{Bank: 341, Branch: 1234, Account: 123456}, AccountDigit: "7", Amount: 14550,
+		History: "CIELO SA", Document: "1023863232"}, entry.GetBankEntry())
+	record, err = detail.Parse(cnab240Entry("00002", "000000000000001000", "D"), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, ports.Money(-1000), record.(*Cnab240Entry).GetBankEntry().Amount)
+	line = cnab240Trailer("000000000000001000", "000000000000014550")
+	assert.Len(t, line, 240)
+	record, err = detail.Parse(line, 0)
+	assert.Nil(t, err)
+	trailer := record.(*Cnab240BatchTrailer)
+	assert.Equal(t, "5", trailer.GetRecordType())
+	assert.Equal(t, ports.Money(14550), trailer.CreditAmount)
+	line = cnab400Entry("000000000000009700", "C")
+	assert.Len(t, line, 400)
+	record, err = detail.Parse(line, 0)
+	assert.Nil(t, err)
+	entry400 := record.(*Cnab400Entry)
+	var _ ports.BankEntryInterface = entry400
+	assert.Equal(t, "1", entry400.GetRecordType())
+	assert.Equal(t, ports.BankEntry{Date: time.Date(2021, 4, 10, 0, 0, 0, 0, time.UTC),
+		Account: ports.BankAccount{Bank: 237, Branch: 123, Account: 45678}, AccountDigit: "9", Amount: 9700,
+		History: "REDECARD SA", Document: "DOC1"}, entry400.GetBankEntry())
+	record, err = detail.Parse(cnab400Entry("000000000000001000", "D"), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, ports.Money(-1000), record.(*Cnab400Entry).GetBankEntry().Amount)
+	// headers and other segments are skipped
+	for _, line := range []string{cnab240Header + strings.Repeat(" ", 240-len(cnab240Header)), cnab240Header,
+		cnab400Header + strings.Repeat(" ", 385) + "000001", "9"} {
+		record, err = detail.Parse(line, 0)
+		assert.Nil(t, err)
+		assert.Nil(t, record)
+	}
+	// lines are measured in characters and the trimmed ones are padded
+	line = strings.Replace(cnab240Entry("00004", "000000000000014550", "C"), "CLIENTE X", "JOSÉ LIMA", 1)
+	assert.Len(t, []rune(line), 240)
+	record, err = detail.Parse(line, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "JOSÉ LIMA", strings.TrimSpace(record.(*Cnab240Entry).CompanyName))
+	assert.Equal(t, ports.Money(14550), record.(*Cnab240Entry).GetBankEntry().Amount)
+	record, err = detail.Parse(strings.TrimRight(cnab240Entry("00005", "000000000000014550", "C"), " "), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "1023863232", record.(*Cnab240Entry).GetBankEntry().Document)
+	_, err = detail.Parse(cnab240Entry("00006", "000000000000014550", "C")+" ", 0)
+	assert.NotNil(t, err)
+	assert.Equal(t, "line has 241 characters (should be 240 or 400)", err.Error())
+	_, err = detail.Parse(cnab400Entry("000000000000009700", "C")[:399], 0)
+	assert.NotNil(t, err)
+	assert.Equal(t, "line has 399 characters (should be 240 or 400)", err.Error())
+	assert.True(t, detail.IsHeader(cnab240Header))
+	assert.True(t, detail.IsHeader(cnab400Header))
+	for _, line := range []string{cnab240Trailer("0", "0"), cnab400Entry("0", "C"), "header", "ABC00000", "3410000"} {
+		assert.False(t, detail.IsHeader(line))
+	}
+	_, err = detail.Parse(cnab240Entry("00003", "00000000000000ABCD", "C"), 0)
+	assert.NotNil(t, err)
+}
+
+func TestCnabValidate(t *testing.T) {
+	detail := NewCnabDetail(string_parser.NewStringParser("position"))
+	records := make([]ports.RecordInterface, 0)
+	for _, line := range []string{cnab240Entry("00001", "000000000000014550", "C"), cnab240Entry("00002", "000000000000001000", "D"),
+		cnab240Trailer("000000000000001000", "000000000000014550"), cnab400Entry("000000000000009700", "C")} {
+		record, err := detail.Parse(line, 0)
+		assert.Nil(t, err)
+		records = append(records, record)
+	}
+	assert.Nil(t, detail.Validate(records))
+	records[0].(*Cnab240Entry).Amount = 14500
+	err := detail.Validate(records)
+	assert.NotNil(t, err)
+	assert.Equal(t, "trailer batch 1 credit amount 145.50 does not match the 145.00 read", err.Error())
+	records[1].(*Cnab240Entry).EntryType = "C"
+	err = detail.Validate(records)
+	assert.NotNil(t, err)
+	assert.Equal(t, "trailer batch 1 debit amount 10.00 does not match the 0.00 read", err.Error())
+}
@@ -0,0 +1,255 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+var (
+	// getnetLayouts has the detail records of the Getnet statement. Analytical sales (2) got the
+	// card brand and terminal fields on layout version 10
+	getnetLayouts = []RecordLayout{
+		{Type: "1", Record: &GetnetSummary{}},
+		{Type: "2", Record: &GetnetSaleV9{}},
+		{Type: "2", MinVersion: 10, Record: &GetnetSale{}},
+		{Type: "3", Record: &GetnetAdjustment{}},
+		{Type: "4", Record: &GetnetAnticipation{}},
+		{Type: "9", Record: &GetnetTrailer{}},
+	}
+)
+
+// GetnetSummary is a sales summary (1) of an establishment with the payment of its net amount
+type GetnetSummary struct {
+	RegisterType   int8        `txt:"1"`
+	Establishment  string      `txt:"15"`
+	ProductCode    string      `txt:"2"`
+	CaptureType    string      `txt:"3"`
+	SummaryNumber  int64       `txt:"9"`
+	SummaryDate    time.Time   `txt:"ddmmyyyy"`
+	PaymentDate    time.Time   `txt:"ddmmyyyy"`
+	BankCode       int16       `txt:"3"`
+	BranchCode     int32       `txt:"6"`
+	AccountNumber  int64       `txt:"11"`
+	SaleCount      int32       `txt:"9"`
+	GrossAmount    ports.Money `txt:"12"`
+	DiscountAmount ports.Money `txt:"12"`
+	NetAmount      ports.Money `txt:"12"`
+	Brand          string      `txt:"3"`
+}
+
+func (d GetnetSummary) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+func (d GetnetSummary) GetSalesDate() time.Time {
+	return d.SummaryDate
+}
+func (d GetnetSummary) GetBrand() string {
+	return d.Brand
+}
+func (d GetnetSummary) GetSaleCount() int {
+	return int(d.SaleCount)
+}
+func (d GetnetSummary) GetGrossAmount() ports.Money {
+	return d.GrossAmount
+}
+func (d GetnetSummary) GetDiscountAmount() ports.Money {
+	return d.DiscountAmount
+}
+func (d GetnetSummary) GetNetAmount() ports.Money {
+	return d.NetAmount
+}
+func (d GetnetSummary) GetEstablishment() int64 {
+	return getnetEstablishment(d.Establishment)
+}
+func (d GetnetSummary) GetPaymentDate() time.Time {
+	return d.PaymentDate
+}
+func (d GetnetSummary) GetBankAccount() ports.BankAccount {
+	return ports.BankAccount{Bank: int(d.BankCode), Branch: int(d.BranchCode), Account: d.AccountNumber}
+}
+func (d GetnetSummary) GetSettledAmount() ports.Money {
+	return d.NetAmount
+}
+
+// GetnetSale is an analytical sale (2) of a summary. Installment sales have an InstallmentCount greater than 1
+type GetnetSale struct {
+	RegisterType      int8        `txt:"1"`
+	Establishment     string      `txt:"15"`
+	SummaryNumber     int64       `txt:"9"`
+	Nsu               int64       `txt:"12"`
+	SaleDate          time.Time   `txt:"ddmmyyyy"`
+	SaleTime          string      `txt:"6"`
+	CardNumber        string      `txt:"19"`
+	GrossAmount       ports.Money `txt:"12"`
+	DiscountAmount    ports.Money `txt:"12"`
+	NetAmount         ports.Money `txt:"12"`
+	InstallmentNumber int8        `txt:"2"`
+	InstallmentCount  int8        `txt:"2"`
+	AuthorizationCode string      `txt:"6"`
+	Brand             string      `txt:"3"`
+	TerminalNumber    string      `txt:"8"`
+}
+
+func (d GetnetSale) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+
+// GetnetSaleV9 is the analytical sale (2) of the layout versions before 10, without brand and terminal
+type GetnetSaleV9 struct {
+	RegisterType      int8        `txt:"1"`
+	Establishment     string      `txt:"15"`
+	SummaryNumber     int64       `txt:"9"`
+	Nsu               int64       `txt:"12"`
+	SaleDate          time.Time   `txt:"ddmmyyyy"`
+	SaleTime          string      `txt:"6"`
+	CardNumber        string      `txt:"19"`
+	GrossAmount       ports.Money `txt:"12"`
+	DiscountAmount    ports.Money `txt:"12"`
+	NetAmount         ports.Money `txt:"12"`
+	InstallmentNumber int8        `txt:"2"`
+	InstallmentCount  int8        `txt:"2"`
+	AuthorizationCode string      `txt:"6"`
+}
+
+func (d GetnetSaleV9) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+
+func (d GetnetSaleV9) Upgrade() ports.RecordInterface {
+	return &GetnetSale{RegisterType: d.RegisterType, Establishment: d.Establishment, SummaryNumber: d.SummaryNumber,
+		Nsu: d.Nsu, SaleDate: d.SaleDate, SaleTime: d.SaleTime, CardNumber: d.CardNumber, GrossAmount: d.GrossAmount,
+		DiscountAmount: d.DiscountAmount, NetAmount: d.NetAmount, InstallmentNumber: d.InstallmentNumber,
+		InstallmentCount: d.InstallmentCount, AuthorizationCode: d.AuthorizationCode}
+}
+
+// GetnetAdjustment is a financial adjustment (3) credited or debited on PaymentDate. Kind is C for credits and D for debits
+type GetnetAdjustment struct {
+	RegisterType   int8        `txt:"1"`
+	Establishment  string      `txt:"15"`
+	SummaryNumber  int64       `txt:"9"`
+	AdjustmentDate time.Time   `txt:"ddmmyyyy"`
+	PaymentDate    time.Time   `txt:"ddmmyyyy"`
+	Kind           string      `txt:"1"`
+	Amount         ports.Money `txt:"12"`
+	ReasonCode     int16       `txt:"2"`
+	Reason         string      `txt:"30"`
+	BankCode       int16       `txt:"3"`
+	BranchCode     int32       `txt:"6"`
+	AccountNumber  int64       `txt:"11"`
+}
+
+func (d GetnetAdjustment) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+func (d GetnetAdjustment) GetEstablishment() int64 {
+	return getnetEstablishment(d.Establishment)
+}
+func (d GetnetAdjustment) GetPaymentDate() time.Time {
+	return d.PaymentDate
+}
+func (d GetnetAdjustment) GetBankAccount() ports.BankAccount {
+	return ports.BankAccount{Bank: int(d.BankCode), Branch: int(d.BranchCode), Account: d.AccountNumber}
+}
+
+// GetSettledAmount returns the amount of the adjustment, negative for debits
+func (d GetnetAdjustment) GetSettledAmount() ports.Money {
+	if d.Kind == "D" {
+		return -d.Amount
+	}
+	return d.Amount
+}
+
+// GetnetAnticipation is an anticipation (4): receivables of OriginalDate paid on PaymentDate with a fee
+type GetnetAnticipation struct {
+	RegisterType    int8        `txt:"1"`
+	Establishment   string      `txt:"15"`
+	OperationNumber int64       `txt:"12"`
+	PaymentDate     time.Time   `txt:"ddmmyyyy"`
+	OriginalDate    time.Time   `txt:"ddmmyyyy"`
+	GrossAmount     ports.Money `txt:"12"`
+	FeeAmount       ports.Money `txt:"12"`
+	NetAmount       ports.Money `txt:"12"`
+	BankCode        int16       `txt:"3"`
+	BranchCode      int32       `txt:"6"`
+	AccountNumber   int64       `txt:"11"`
+}
+
+func (d GetnetAnticipation) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+func (d GetnetAnticipation) GetEstablishment() int64 {
+	return getnetEstablishment(d.Establishment)
+}
+func (d GetnetAnticipation) GetPaymentDate() time.Time {
+	return d.PaymentDate
+}
+func (d GetnetAnticipation) GetBankAccount() ports.BankAccount {
+	return ports.BankAccount{Bank: int(d.BankCode), Branch: int(d.BranchCode), Account: d.AccountNumber}
+}
+func (d GetnetAnticipation) GetSettledAmount() ports.Money {
+	return d.NetAmount
+}
+
+// GetnetTrailer is the file trailer (9) with the totals of the summaries and sales of the file
+type GetnetTrailer struct {
+	RegisterType int8        `txt:"1"`
+	RecordCount  int         `txt:"9"`
+	SummaryCount int         `txt:"9"`
+	SaleCount    int         `txt:"9"`
+	GrossAmount  ports.Money `txt:"15"`
+	NetAmount    ports.Money `txt:"15"`
+}
+
+func (d GetnetTrailer) GetRecordType() string {
+	return fmt.Sprint(d.RegisterType)
+}
+
+// NewGetnetDetail creates the parser of the Getnet detail records
+func NewGetnetDetail(parser ports.StringParserInterface) *Detail {
+	return NewDetail(getnetLayouts, positionType(1), validateGetnet, parser)
+}
+
+// validateGetnet checks the totals of the trailer against the summaries and sales of the file
+func validateGetnet(records []ports.RecordInterface) error {
+	var trailer *GetnetTrailer
+	summaries, sales := 0, 0
+	var gross, net ports.Money
+	for _, record := range records {
+		switch r := record.(type) {
+		case *GetnetSummary:
+			summaries++
+			gross += r.GrossAmount
+			net += r.NetAmount
+		case *GetnetSale:
+			sales++
+		case *GetnetTrailer:
+			trailer = r
+		}
+	}
+	if trailer == nil {
+		return fmt.Errorf("trailer not found")
+	}
+	if trailer.SummaryCount != summaries {
+		return totalError("trailer summary count", trailer.SummaryCount, summaries)
+	}
+	if trailer.SaleCount != sales {
+		return totalError("trailer sale count", trailer.SaleCount, sales)
+	}
+	if trailer.GrossAmount != gross {
+		return totalError("trailer gross amount", trailer.GrossAmount, gross)
+	}
+	if trailer.NetAmount != net {
+		return totalError("trailer net amount", trailer.NetAmount, net)
+	}
+	return nil
+}
+
+// getnetEstablishment returns the establishment number of a Getnet code, left aligned with trailing spaces
+func getnetEstablishment(code string) int64 {
+	n, _ := strconv.ParseInt(strings.TrimSpace(code), 10, 64)
+	return n
+}
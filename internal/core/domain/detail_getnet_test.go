@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/lavinas/cielo-edi/internal/utils/string_parser"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	getnetEstablishmentCode = "1447355        "
+	getnetSummary           = "1" + getnetEstablishmentCode + "SM" + "POS" + "000000101" + "23072021" + "24082021" + "033" + "001234" +
+		"00000654321" + "000000002" + "000000015000" + "000000000300" + "000000014700" + "MC "
+	getnetSaleV9 = "2" + getnetEstablishmentCode + "000000101" + "000000000011" + "23072021" + "101530" + "123456******1234   " +
+		"000000010000" + "000000000200" + "000000009800" + "01" + "01" + "A1B2C3"
+	getnetSale  = getnetSaleV9 + "MC " + "TERM0001"
+	getnetSale2 = "2" + getnetEstablishmentCode + "000000101" + "000000000012" + "23072021" + "113000" + "654321******4321   " +
+		"000000005000" + "000000000100" + "000000004900" + "01" + "01" + "D4E5F6" + "MC " + "TERM0001"
+	getnetAdjustment = "3" + getnetEstablishmentCode + "000000101" + "25072021" + "24082021" + "D" + "000000001000" + "17" +
+		"CANCELAMENTO DE VENDA         " + "033" + "001234" + "00000654321"
+	getnetAnticipation = "4" + getnetEstablishmentCode + "000000000077" + "24082021" + "23092021" + "000000020000" +
+		"000000000600" + "000000019400" + "033" + "001234" + "00000654321"
+	getnetTrailer = "9" + "000000007" + "000000001" + "000000002" + "000000000015000" + "000000000014700"
+)
+
+func TestGetnetDetail(t *testing.T) {
+	detail := NewGetnetDetail(string_parser.NewStringParser("position"))
+	account := ports.BankAccount{Bank: 33, Branch: 1234, Account: 654321}
+	paymentDate := time.Date(2021, 8, 24, 0, 0, 0, 0, time.UTC)
+	record, err := detail.Parse(getnetSummary, 10)
+	assert.Nil(t, err)
+	summary := record.(*GetnetSummary)
+	assert.Equal(t, "1", summary.GetRecordType())
+	assert.Equal(t, int64(1447355), summary.GetEstablishment())
+	assert.Equal(t, time.Date(2021, 7, 23, 0, 0, 0, 0, time.UTC), summary.GetSalesDate())
+	assert.Equal(t, 2, summary.GetSaleCount())
+	assert.Equal(t, ports.Money(15000), summary.GetGrossAmount())
+	assert.Equal(t, ports.Money(300), summary.GetDiscountAmount())
+	tests := []struct {
+		line    string
+		settled ports.Money
+	}{
+		{getnetSummary, 14700},
+		{getnetAdjustment, -1000},
+		{getnetAnticipation, 19400},
+	}
+	for _, test := range tests {
+		record, err := detail.Parse(test.line, 10)
+		assert.Nil(t, err)
+		settlement := record.(ports.SettlementInterface)
+		assert.Equal(t, int64(1447355), settlement.GetEstablishment())
+		assert.Equal(t, paymentDate, settlement.GetPaymentDate())
+		assert.Equal(t, account, settlement.GetBankAccount())
+		assert.Equal(t, test.settled, settlement.GetSettledAmount())
+	}
+	record, _ = detail.Parse(getnetAnticipation, 10)
+	assert.Equal(t, time.Date(2021, 9, 23, 0, 0, 0, 0, time.UTC), record.(*GetnetAnticipation).OriginalDate)
+	record, err = detail.Parse("0"+"23072021", 10)
+	assert.Nil(t, err)
+	assert.Nil(t, record)
+}
+
+func TestGetnetSaleVersions(t *testing.T) {
+	detail := NewGetnetDetail(string_parser.NewStringParser("position"))
+	record, err := detail.Parse(getnetSaleV9, 9)
+	assert.Nil(t, err)
+	sale := record.(*GetnetSale)
+	assert.Equal(t, "2", sale.GetRecordType())
+	assert.Equal(t, int64(11), sale.Nsu)
+	assert.Equal(t, ports.Money(9800), sale.NetAmount)
+	assert.Equal(t, "", sale.Brand)
+	record, err = detail.Parse(getnetSale, 10)
+	assert.Nil(t, err)
+	sale = record.(*GetnetSale)
+	assert.Equal(t, "MC ", sale.Brand)
+	assert.Equal(t, "TERM0001", sale.TerminalNumber)
+	_, err = detail.Parse(getnetSaleV9, 10)
+	assert.NotNil(t, err)
+}
+
+func TestGetnetTotals(t *testing.T) {
+	detail := NewGetnetDetail(string_parser.NewStringParser("position"))
+	records := make([]ports.RecordInterface, 0)
+	for _, line := range []string{getnetSummary, getnetSale, getnetSale2, getnetAdjustment, getnetAnticipation, getnetTrailer} {
+		record, err := detail.Parse(line, 10)
+		assert.Nil(t, err)
+		records = append(records, record)
+	}
+	assert.Nil(t, detail.Validate(records))
+	withoutSale := append(append([]ports.RecordInterface{}, records[:1]...), records[2:]...)
+	assert.Equal(t, "trailer sale count 2 does not match the 1 read", detail.Validate(withoutSale).Error())
+	records[0].(*GetnetSummary).NetAmount = 14600
+	assert.Equal(t, "trailer net amount 147.00 does not match the 146.00 read", detail.Validate(records).Error())
+	assert.Equal(t, "trailer not found", detail.Validate(records[:5]).Error())
+	trailer := NewTrailer(NewTrailerGetnet(), string_parser.NewStringParser("position"))
+	assert.Nil(t, trailer.Parse(getnetTrailer))
+	assert.Nil(t, trailer.Validate(7))
+	assert.Nil(t, trailer.Parse("2000000007"))
+	assert.Equal(t, "trailer not found (last record type 2 should be 9)", trailer.Validate(7).Error())
+}
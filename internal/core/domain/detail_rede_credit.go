@@ -0,0 +1,278 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+var (
+	// redeCreditLayouts has the detail records of the EEVC (credit sales) statement. Sales (008) got the
+	// terminal, capture type and brand fields on layout version 3
+	redeCreditLayouts = []RecordLayout{
+		{Type: "006", Record: &RedeCreditSummary{}},
+		{Type: "008", Record: &RedeCreditSaleV2{}},
+		{Type: "008", MinVersion: 3, Record: &RedeCreditSale{}},
+		{Type: "010", Record: &RedeCreditSummary{}},
+		{Type: "011", Record: &RedeCreditAdjustment{}},
+		{Type: "012", Record: &RedeCreditInstallmentSale{}},
+		{Type: "014", Record: &RedeCreditInstallment{}},
+		{Type: "028", Record: &RedeCreditTrailer{}},
+	}
+)
+
+// RedeCreditSummary is a sales summary (RV) of an establishment (PV): 006 for single payment
+// sales and 010 for installment sales without interest
+type RedeCreditSummary struct {
+	RegisterType   int16       `txt:"3"`
+	Establishment  int64       `txt:"9"`
+	SummaryNumber  int64       `txt:"9"`
+	BankCode       int16       `txt:"3"`
+	BranchCode     int32       `txt:"5"`
+	AccountNumber  int64       `txt:"11"`
+	SummaryDate    time.Time   `txt:"ddmmyyyy"`
+	SaleCount      int32       `txt:"5"`
+	GrossAmount    ports.Money `txt:"15"`
+	TipAmount      ports.Money `txt:"15"`
+	RejectedAmount ports.Money `txt:"15"`
+	DiscountAmount ports.Money `txt:"15"`
+	NetAmount      ports.Money `txt:"15"`
+	CreditDate     time.Time   `txt:"ddmmyyyy"`
+	Brand          string      `txt:"1"`
+}
+
+func (d RedeCreditSummary) GetRecordType() string {
+	return fmt.Sprintf("%03d", d.RegisterType)
+}
+
+// IsInstallment tells if the summary has installment sales
+func (d RedeCreditSummary) IsInstallment() bool {
+	return d.RegisterType == 10
+}
+func (d RedeCreditSummary) GetSalesDate() time.Time {
+	return d.SummaryDate
+}
+func (d RedeCreditSummary) GetBrand() string {
+	return d.Brand
+}
+func (d RedeCreditSummary) GetSaleCount() int {
+	return int(d.SaleCount)
+}
+func (d RedeCreditSummary) GetGrossAmount() ports.Money {
+	return d.GrossAmount
+}
+func (d RedeCreditSummary) GetDiscountAmount() ports.Money {
+	return d.DiscountAmount
+}
+func (d RedeCreditSummary) GetNetAmount() ports.Money {
+	return d.NetAmount
+}
+func (d RedeCreditSummary) GetEstablishment() int64 {
+	return d.Establishment
+}
+func (d RedeCreditSummary) GetSummaryNumber() int64 {
+	return d.SummaryNumber
+}
+
+// GetInstallment returns zero: installment summaries are scheduled by their installments (014)
+func (d RedeCreditSummary) GetInstallment() int {
+	return 0
+}
+func (d RedeCreditSummary) GetScheduledDate() time.Time {
+	return d.CreditDate
+}
+
+// RedeCreditSale is a single payment sale (CV) of a summary (008)
+type RedeCreditSale struct {
+	RegisterType      int16       `txt:"3"`
+	Establishment     int64       `txt:"9"`
+	SummaryNumber     int64       `txt:"9"`
+	SaleDate          time.Time   `txt:"ddmmyyyy"`
+	GrossAmount       ports.Money `txt:"15"`
+	TipAmount         ports.Money `txt:"15"`
+	CardNumber        string      `txt:"16"`
+	Status            string      `txt:"3"`
+	Nsu               int64       `txt:"12"`
+	Reference         string      `txt:"13"`
+	AuthorizationCode string      `txt:"6"`
+	SaleTime          string      `txt:"6"`
+	TerminalNumber    string      `txt:"8"`
+	CaptureType       string      `txt:"2"`
+	Brand             string      `txt:"1"`
+}
+
+func (d RedeCreditSale) GetRecordType() string {
+	return fmt.Sprintf("%03d", d.RegisterType)
+}
+
+// RedeCreditSaleV2 is the sale (008) of the layout versions before 3, without terminal, capture type and brand
+type RedeCreditSaleV2 struct {
+	RegisterType      int16       `txt:"3"`
+	Establishment     int64       `txt:"9"`
+	SummaryNumber     int64       `txt:"9"`
+	SaleDate          time.Time   `txt:"ddmmyyyy"`
+	GrossAmount       ports.Money `txt:"15"`
+	TipAmount         ports.Money `txt:"15"`
+	CardNumber        string      `txt:"16"`
+	Status            string      `txt:"3"`
+	Nsu               int64       `txt:"12"`
+	Reference         string      `txt:"13"`
+	AuthorizationCode string      `txt:"6"`
+	SaleTime          string      `txt:"6"`
+}
+
+func (d RedeCreditSaleV2) GetRecordType() string {
+	return fmt.Sprintf("%03d", d.RegisterType)
+}
+
+func (d RedeCreditSaleV2) Upgrade() ports.RecordInterface {
+	return &RedeCreditSale{RegisterType: d.RegisterType, Establishment: d.Establishment, SummaryNumber: d.SummaryNumber,
+		SaleDate: d.SaleDate, GrossAmount: d.GrossAmount, TipAmount: d.TipAmount, CardNumber: d.CardNumber, Status: d.Status,
+		Nsu: d.Nsu, Reference: d.Reference, AuthorizationCode: d.AuthorizationCode, SaleTime: d.SaleTime}
+}
+
+// RedeCreditInstallmentSale is an installment sale without interest (CV) of a summary (012)
+type RedeCreditInstallmentSale struct {
+	RegisterType      int16       `txt:"3"`
+	Establishment     int64       `txt:"9"`
+	SummaryNumber     int64       `txt:"9"`
+	SaleDate          time.Time   `txt:"ddmmyyyy"`
+	GrossAmount       ports.Money `txt:"15"`
+	EntryAmount       ports.Money `txt:"15"`
+	InstallmentAmount ports.Money `txt:"15"`
+	InstallmentCount  int8        `txt:"2"`
+	CardNumber        string      `txt:"16"`
+	Status            string      `txt:"3"`
+	Nsu               int64       `txt:"12"`
+	Reference         string      `txt:"13"`
+	AuthorizationCode string      `txt:"6"`
+}
+
+func (d RedeCreditInstallmentSale) GetRecordType() string {
+	return fmt.Sprintf("%03d", d.RegisterType)
+}
+
+// RedeCreditInstallment is an installment of an installment summary with its credit date (014)
+type RedeCreditInstallment struct {
+	RegisterType      int16       `txt:"3"`
+	Establishment     int64       `txt:"9"`
+	SummaryNumber     int64       `txt:"9"`
+	InstallmentNumber int8        `txt:"2"`
+	InstallmentCount  int8        `txt:"2"`
+	GrossAmount       ports.Money `txt:"15"`
+	DiscountAmount    ports.Money `txt:"15"`
+	NetAmount         ports.Money `txt:"15"`
+	CreditDate        time.Time   `txt:"ddmmyyyy"`
+}
+
+func (d RedeCreditInstallment) GetRecordType() string {
+	return fmt.Sprintf("%03d", d.RegisterType)
+}
+func (d RedeCreditInstallment) GetEstablishment() int64 {
+	return d.Establishment
+}
+func (d RedeCreditInstallment) GetSummaryNumber() int64 {
+	return d.SummaryNumber
+}
+func (d RedeCreditInstallment) GetInstallment() int {
+	return int(d.InstallmentNumber)
+}
+func (d RedeCreditInstallment) GetScheduledDate() time.Time {
+	return d.CreditDate
+}
+func (d RedeCreditInstallment) GetNetAmount() ports.Money {
+	return d.NetAmount
+}
+
+// RedeCreditAdjustment is an adjustment of a summary (011). Kind is C for credits and D for debits
+type RedeCreditAdjustment struct {
+	RegisterType   int16       `txt:"3"`
+	Establishment  int64       `txt:"9"`
+	SummaryNumber  int64       `txt:"9"`
+	AdjustmentDate time.Time   `txt:"ddmmyyyy"`
+	Amount         ports.Money `txt:"15"`
+	Kind           string      `txt:"1"`
+	ReasonCode     int16       `txt:"2"`
+	Reason         string      `txt:"28"`
+	CardNumber     string      `txt:"16"`
+	SaleDate       time.Time   `txt:"ddmmyyyy"`
+	CreditDate     time.Time   `txt:"ddmmyyyy"`
+}
+
+func (d RedeCreditAdjustment) GetRecordType() string {
+	return fmt.Sprintf("%03d", d.RegisterType)
+}
+
+// GetAmount returns the amount of the adjustment, negative for debits
+func (d RedeCreditAdjustment) GetAmount() ports.Money {
+	if d.Kind == "D" {
+		return -d.Amount
+	}
+	return d.Amount
+}
+
+// RedeCreditTrailer is the file trailer (028) with the totals of the summaries and sales of the file
+type RedeCreditTrailer struct {
+	RegisterType     int16       `txt:"3"`
+	HeadquarterCount int         `txt:"4"`
+	RecordCount      int         `txt:"6"`
+	SummaryCount     int         `txt:"6"`
+	SaleCount        int         `txt:"6"`
+	GrossAmount      ports.Money `txt:"15"`
+	RejectedAmount   ports.Money `txt:"15"`
+	DiscountAmount   ports.Money `txt:"15"`
+	NetAmount        ports.Money `txt:"15"`
+}
+
+func (d RedeCreditTrailer) GetRecordType() string {
+	return fmt.Sprintf("%03d", d.RegisterType)
+}
+
+// NewRedeCreditDetail creates the parser of the EEVC detail records
+func NewRedeCreditDetail(parser ports.StringParserInterface) *Detail {
+	return NewDetail(redeCreditLayouts, positionType(3), validateRedeCredit, parser)
+}
+
+// validateRedeCredit checks the totals of the trailer against the summaries and sales of the file
+func validateRedeCredit(records []ports.RecordInterface) error {
+	var trailer *RedeCreditTrailer
+	summaries, sales := 0, 0
+	var gross, rejected, discount, net ports.Money
+	for _, record := range records {
+		switch r := record.(type) {
+		case *RedeCreditSummary:
+			summaries++
+			gross += r.GrossAmount
+			rejected += r.RejectedAmount
+			discount += r.DiscountAmount
+			net += r.NetAmount
+		case *RedeCreditSale, *RedeCreditInstallmentSale:
+			sales++
+		case *RedeCreditTrailer:
+			trailer = r
+		}
+	}
+	if trailer == nil {
+		return fmt.Errorf("trailer not found")
+	}
+	if trailer.SummaryCount != summaries {
+		return totalError("trailer summary count", trailer.SummaryCount, summaries)
+	}
+	if trailer.SaleCount != sales {
+		return totalError("trailer sale count", trailer.SaleCount, sales)
+	}
+	if trailer.GrossAmount != gross {
+		return totalError("trailer gross amount", trailer.GrossAmount, gross)
+	}
+	if trailer.RejectedAmount != rejected {
+		return totalError("trailer rejected amount", trailer.RejectedAmount, rejected)
+	}
+	if trailer.DiscountAmount != discount {
+		return totalError("trailer discount amount", trailer.DiscountAmount, discount)
+	}
+	if trailer.NetAmount != net {
+		return totalError("trailer net amount", trailer.NetAmount, net)
+	}
+	return nil
+}
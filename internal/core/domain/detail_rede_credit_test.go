@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/lavinas/cielo-edi/internal/utils/string_parser"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	redeCreditSummary = "006" + "012345678" + "000000101" + "341" + "01234" + "00000123456" + "10032021" + "00002" +
+		"000000000015000" + "000000000000000" + "000000000000000" + "000000000000300" + "000000000014700" + "11042021" + "M"
+	redeCreditSaleV2 = "008" + "012345678" + "000000101" + "10032021" + "000000000010000" + "000000000000000" +
+		"123456******1234" + "APR" + "000000000001" + "REF0000000001" + "A1B2C3" + "101530"
+	redeCreditSaleV3 = redeCreditSaleV2 + "TERM0001" + "01" + "M"
+	redeCreditSale2  = "008" + "012345678" + "000000101" + "10032021" + "000000000005000" + "000000000000000" +
+		"654321******4321" + "APR" + "000000000002" + "REF0000000002" + "D4E5F6" + "113000"
+	redeCreditInstallmentSummary = "010" + "012345678" + "000000102" + "341" + "01234" + "00000123456" + "10032021" + "00001" +
+		"000000000030000" + "000000000000000" + "000000000000000" + "000000000000900" + "000000000029100" + "11042021" + "V"
+	redeCreditInstallmentSale = "012" + "012345678" + "000000102" + "10032021" + "000000000030000" + "000000000000000" +
+		"000000000010000" + "03" + "111111******1111" + "APR" + "000000000003" + "REF0000000003" + "G7H8I9"
+	redeCreditInstallment = "014" + "012345678" + "000000102" + "01" + "03" + "000000000010000" + "000000000000300" +
+		"000000000009700" + "11042021"
+	redeCreditAdjustment = "011" + "012345678" + "000000101" + "15032021" + "000000000002500" + "D" + "17" +
+		"CANCELAMENTO DE VENDA       " + "123456******1234" + "10032021" + "16032021"
+	redeCreditTrailer = "028" + "0001" + "000009" + "000002" + "000003" + "000000000045000" + "000000000000000" +
+		"000000000001200" + "000000000043800"
+)
+
+func TestRedeCreditDetailV2(t *testing.T) {
+	detail := NewRedeCreditDetail(string_parser.NewStringParser("position"))
+	record, err := detail.Parse(redeCreditSummary, 2)
+	assert.Nil(t, err)
+	summary := record.(*RedeCreditSummary)
+	assert.Equal(t, "006", summary.GetRecordType())
+	assert.False(t, summary.IsInstallment())
+	assert.Equal(t, int64(12345678), summary.Establishment)
+	assert.Equal(t, int64(101), summary.SummaryNumber)
+	assert.Equal(t, int16(341), summary.BankCode)
+	assert.Equal(t, int64(123456), summary.AccountNumber)
+	assert.Equal(t, ports.Money(15000), summary.GrossAmount)
+	assert.Equal(t, ports.Money(14700), summary.NetAmount)
+	assert.Equal(t, time.Date(2021, 4, 11, 0, 0, 0, 0, time.UTC), summary.CreditDate)
+	record, err = detail.Parse(redeCreditSaleV2, 2)
+	assert.Nil(t, err)
+	sale := record.(*RedeCreditSale)
+	assert.Equal(t, "008", sale.GetRecordType())
+	assert.Equal(t, ports.Money(10000), sale.GrossAmount)
+	assert.Equal(t, int64(1), sale.Nsu)
+	assert.Equal(t, "A1B2C3", sale.AuthorizationCode)
+	assert.Equal(t, "", sale.TerminalNumber)
+	record, err = detail.Parse("002"+"07022021REDECARD", 2)
+	assert.Nil(t, err)
+	assert.Nil(t, record)
+	_, err = detail.Parse(redeCreditSaleV2[:50], 2)
+	assert.NotNil(t, err)
+	assert.Equal(t, "record 008: TipAmount: unexpected end of txt for parsing this field", err.Error())
+}
+
+func TestRedeCreditDetailV3(t *testing.T) {
+	detail := NewRedeCreditDetail(string_parser.NewStringParser("position"))
+	record, err := detail.Parse(redeCreditSaleV3, 3)
+	assert.Nil(t, err)
+	sale := record.(*RedeCreditSale)
+	assert.Equal(t, "TERM0001", sale.TerminalNumber)
+	assert.Equal(t, "01", sale.CaptureType)
+	assert.Equal(t, "M", sale.Brand)
+	_, err = detail.Parse(redeCreditSaleV2, 3)
+	assert.NotNil(t, err)
+}
+
+func TestRedeCreditInstallmentsAndAdjustments(t *testing.T) {
+	detail := NewRedeCreditDetail(string_parser.NewStringParser("position"))
+	record, err := detail.Parse(redeCreditInstallmentSummary, 2)
+	assert.Nil(t, err)
+	assert.True(t, record.(*RedeCreditSummary).IsInstallment())
+	record, err = detail.Parse(redeCreditInstallmentSale, 2)
+	assert.Nil(t, err)
+	sale := record.(*RedeCreditInstallmentSale)
+	assert.Equal(t, int8(3), sale.InstallmentCount)
+	assert.Equal(t, ports.Money(10000), sale.InstallmentAmount)
+	record, err = detail.Parse(redeCreditInstallment, 2)
+	assert.Nil(t, err)
+	installment := record.(*RedeCreditInstallment)
+	assert.Equal(t, int8(1), installment.InstallmentNumber)
+	assert.Equal(t, ports.Money(9700), installment.NetAmount)
+	var scheduled ports.ScheduledPaymentInterface = installment
+	assert.Equal(t, int64(102), scheduled.GetSummaryNumber())
+	assert.Equal(t, 1, scheduled.GetInstallment())
+	assert.Equal(t, time.Date(2021, 4, 11, 0, 0, 0, 0, time.UTC), scheduled.GetScheduledDate())
+	record, err = detail.Parse(redeCreditAdjustment, 2)
+	assert.Nil(t, err)
+	adjustment := record.(*RedeCreditAdjustment)
+	assert.Equal(t, ports.Money(-2500), adjustment.GetAmount())
+	assert.Equal(t, int16(17), adjustment.ReasonCode)
+	assert.Equal(t, time.Date(2021, 3, 16, 0, 0, 0, 0, time.UTC), adjustment.CreditDate)
+}
+
+func TestRedeCreditTotals(t *testing.T) {
+	detail := NewRedeCreditDetail(string_parser.NewStringParser("position"))
+	records := make([]ports.RecordInterface, 0)
+	for _, line := range []string{redeCreditSummary, redeCreditSaleV2, redeCreditSale2, redeCreditInstallmentSummary,
+		redeCreditInstallmentSale, redeCreditInstallment, redeCreditAdjustment, redeCreditTrailer} {
+		record, err := detail.Parse(line, 2)
+		assert.Nil(t, err)
+		records = append(records, record)
+	}
+	assert.Nil(t, detail.Validate(records))
+	records[0].(*RedeCreditSummary).NetAmount = 14600
+	assert.Equal(t, "trailer net amount 438.00 does not match the 437.00 read", detail.Validate(records).Error())
+	withoutSale := append(append([]ports.RecordInterface{}, records[:2]...), records[3:]...)
+	assert.Equal(t, "trailer sale count 3 does not match the 2 read", detail.Validate(withoutSale).Error())
+	assert.Equal(t, "trailer summary count 2 does not match the 1 read", detail.Validate(records[1:]).Error())
+	assert.Equal(t, "trailer not found", detail.Validate(records[:7]).Error())
+	trailer := NewTrailer(&TrailerRede{Statement: "credito"}, string_parser.NewStringParser("position"))
+	assert.Nil(t, trailer.Parse(redeCreditTrailer))
+	assert.Nil(t, trailer.Validate(9))
+}
@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+var (
+	// redeDebtLayouts has the detail records of the EEVD (debit sales) statement. Its lines are csv
+	// and the amounts have two implied decimal places or a decimal point
+	redeDebtLayouts = []RecordLayout{
+		{Type: "01", Record: &RedeDebtSummary{}},
+		{Type: "04", Record: &RedeDebtTrailer{}},
+		{Type: "05", Record: &RedeDebtSale{}},
+	}
+)
+
+// RedeDebtSummary is a debit sales summary (RV) of an establishment (01)
+type RedeDebtSummary struct {
+	RegisterType   int8        `txt:"2"`
+	Establishment  int64       `txt:"9"`
+	SummaryNumber  int64       `txt:"9"`
+	SummaryDate    time.Time   `txt:"ddmmyyyy"`
+	CreditDate     time.Time   `txt:"ddmmyyyy"`
+	SaleCount      int32       `txt:"6"`
+	GrossAmount    ports.Money `txt:"15"`
+	DiscountAmount ports.Money `txt:"15"`
+	NetAmount      ports.Money `txt:"15"`
+	Brand          string      `txt:"20"`
+	BankCode       int16       `txt:"3"`
+	BranchCode     int32       `txt:"5"`
+	AccountNumber  int64       `txt:"11"`
+}
+
+func (d RedeDebtSummary) GetRecordType() string {
+	return fmt.Sprintf("%02d", d.RegisterType)
+}
+func (d RedeDebtSummary) GetSalesDate() time.Time {
+	return d.SummaryDate
+}
+func (d RedeDebtSummary) GetBrand() string {
+	return d.Brand
+}
+func (d RedeDebtSummary) GetSaleCount() int {
+	return int(d.SaleCount)
+}
+func (d RedeDebtSummary) GetGrossAmount() ports.Money {
+	return d.GrossAmount
+}
+func (d RedeDebtSummary) GetDiscountAmount() ports.Money {
+	return d.DiscountAmount
+}
+func (d RedeDebtSummary) GetNetAmount() ports.Money {
+	return d.NetAmount
+}
+func (d RedeDebtSummary) GetEstablishment() int64 {
+	return d.Establishment
+}
+func (d RedeDebtSummary) GetSummaryNumber() int64 {
+	return d.SummaryNumber
+}
+func (d RedeDebtSummary) GetInstallment() int {
+	return 0
+}
+func (d RedeDebtSummary) GetScheduledDate() time.Time {
+	return d.CreditDate
+}
+
+// RedeDebtSale is a debit sale (CV) of a summary (05). FeeAmount is the discount of the sale
+type RedeDebtSale struct {
+	RegisterType      int8        `txt:"2"`
+	Establishment     int64       `txt:"9"`
+	SummaryNumber     int64       `txt:"9"`
+	SaleDate          time.Time   `txt:"ddmmyyyy"`
+	SaleTime          string      `txt:"6"`
+	Nsu               int64       `txt:"12"`
+	CardNumber        string      `txt:"16"`
+	Brand             string      `txt:"20"`
+	GrossAmount       ports.Money `txt:"15"`
+	FeeAmount         ports.Money `txt:"15"`
+	NetAmount         ports.Money `txt:"15"`
+	AuthorizationCode string      `txt:"6"`
+	TerminalNumber    string      `txt:"8"`
+}
+
+func (d RedeDebtSale) GetRecordType() string {
+	return fmt.Sprintf("%02d", d.RegisterType)
+}
+
+// RedeDebtTrailer is the file trailer (04) with the totals of the summaries and sales of the file
+type RedeDebtTrailer struct {
+	RegisterType     int8        `txt:"2"`
+	HeadquarterCount int         `txt:"4"`
+	RecordCount      int         `txt:"6"`
+	SummaryCount     int         `txt:"6"`
+	SaleCount        int         `txt:"6"`
+	GrossAmount      ports.Money `txt:"15"`
+	DiscountAmount   ports.Money `txt:"15"`
+	NetAmount        ports.Money `txt:"15"`
+}
+
+func (d RedeDebtTrailer) GetRecordType() string {
+	return fmt.Sprintf("%02d", d.RegisterType)
+}
+
+// NewRedeDebtDetail creates the parser of the EEVD detail records. The parser should be a csv one
+func NewRedeDebtDetail(parser ports.StringParserInterface) *Detail {
+	return NewDetail(redeDebtLayouts, csvType, validateRedeDebt, parser)
+}
+
+// validateRedeDebt checks the totals of the trailer against the summaries of the file and the amounts
+// of the summaries against their sales
+func validateRedeDebt(records []ports.RecordInterface) error {
+	var trailer *RedeDebtTrailer
+	summaries, sales := 0, 0
+	var gross, discount, net, saleGross ports.Money
+	for _, record := range records {
+		switch r := record.(type) {
+		case *RedeDebtSummary:
+			summaries++
+			gross += r.GrossAmount
+			discount += r.DiscountAmount
+			net += r.NetAmount
+		case *RedeDebtSale:
+			sales++
+			saleGross += r.GrossAmount
+		case *RedeDebtTrailer:
+			trailer = r
+		}
+	}
+	if trailer == nil {
+		return fmt.Errorf("trailer not found")
+	}
+	if trailer.SummaryCount != summaries {
+		return totalError("trailer summary count", trailer.SummaryCount, summaries)
+	}
+	if trailer.SaleCount != sales {
+		return totalError("trailer sale count", trailer.SaleCount, sales)
+	}
+	if trailer.GrossAmount != gross {
+		return totalError("trailer gross amount", trailer.GrossAmount, gross)
+	}
+	if trailer.DiscountAmount != discount {
+		return totalError("trailer discount amount", trailer.DiscountAmount, discount)
+	}
+	if trailer.NetAmount != net {
+		return totalError("trailer net amount", trailer.NetAmount, net)
+	}
+	if saleGross != gross {
+		return fmt.Errorf("summaries gross amount %v does not match the %v of the sales", gross, saleGross)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/lavinas/cielo-edi/internal/utils/string_parser"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	redeDebtSummary  = "01,012345678,000000201,21092021,22092021,000002,150.00,3.00,147.00,MAESTRO,341,01234,00000123456"
+	redeDebtSummary2 = "01,012345678,000000202,21092021,22092021,000001,000000000008000,000000000000160,000000000007840,ELO,341,01234,00000123456"
+	redeDebtSale     = "05,012345678,000000201,21092021,101530,000000000011,123456******1234,MAESTRO,100.00,2.00,98.00,A1B2C3,TERM0001"
+	redeDebtSale2    = "05,012345678,000000201,21092021,113000,000000000012,654321******4321,MAESTRO,50.00,1.00,49.00,D4E5F6,TERM0001"
+	redeDebtSale3    = "05,012345678,000000202,21092021,120000,000000000013,111111******1111,ELO,80.00,1.60,78.40,G7H8I9,TERM0002"
+	redeDebtTrailer  = "04,0001,000007,000002,000003,230.00,4.60,225.40"
+)
+
+func TestRedeDebtDetail(t *testing.T) {
+	detail := NewRedeDebtDetail(string_parser.NewStringParser("csv"))
+	record, err := detail.Parse(redeDebtSummary, 4)
+	assert.Nil(t, err)
+	summary := record.(*RedeDebtSummary)
+	assert.Equal(t, "01", summary.GetRecordType())
+	assert.Equal(t, int64(12345678), summary.Establishment)
+	assert.Equal(t, time.Date(2021, 9, 21, 0, 0, 0, 0, time.UTC), summary.GetSalesDate())
+	assert.Equal(t, time.Date(2021, 9, 22, 0, 0, 0, 0, time.UTC), summary.CreditDate)
+	assert.Equal(t, "MAESTRO", summary.GetBrand())
+	assert.Equal(t, 2, summary.GetSaleCount())
+	assert.Equal(t, ports.Money(15000), summary.GetGrossAmount())
+	assert.Equal(t, ports.Money(300), summary.GetDiscountAmount())
+	assert.Equal(t, ports.Money(14700), summary.GetNetAmount())
+	var scheduled ports.ScheduledPaymentInterface = summary
+	assert.Equal(t, int64(201), scheduled.GetSummaryNumber())
+	assert.Equal(t, 0, scheduled.GetInstallment())
+	assert.Equal(t, summary.CreditDate, scheduled.GetScheduledDate())
+	record, err = detail.Parse(redeDebtSummary2, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, ports.Money(7840), record.(*RedeDebtSummary).NetAmount)
+	record, err = detail.Parse(redeDebtSale, 4)
+	assert.Nil(t, err)
+	sale := record.(*RedeDebtSale)
+	assert.Equal(t, "05", sale.GetRecordType())
+	assert.Equal(t, int64(11), sale.Nsu)
+	assert.Equal(t, "MAESTRO", sale.Brand)
+	assert.Equal(t, ports.Money(10000), sale.GrossAmount)
+	assert.Equal(t, ports.Money(200), sale.FeeAmount)
+	assert.Equal(t, ports.Money(9800), sale.NetAmount)
+	assert.Equal(t, "TERM0001", sale.TerminalNumber)
+	record, err = detail.Parse("02,012345678", 4)
+	assert.Nil(t, err)
+	assert.Nil(t, record)
+	_, err = detail.Parse("05,012345678,000000201,21092021,101530,000000000011,123456******1234,MAESTRO,1O0.00", 4)
+	assert.NotNil(t, err)
+}
+
+func TestRedeDebtTotals(t *testing.T) {
+	detail := NewRedeDebtDetail(string_parser.NewStringParser("csv"))
+	records := make([]ports.RecordInterface, 0)
+	for _, line := range []string{redeDebtSummary, redeDebtSale, redeDebtSale2, redeDebtSummary2, redeDebtSale3, redeDebtTrailer} {
+		record, err := detail.Parse(line, 4)
+		assert.Nil(t, err)
+		records = append(records, record)
+	}
+	assert.Nil(t, detail.Validate(records))
+	records[4].(*RedeDebtSale).GrossAmount = 7000
+	assert.Equal(t, "summaries gross amount 230.00 does not match the 220.00 of the sales", detail.Validate(records).Error())
+	records[3].(*RedeDebtSummary).DiscountAmount = 150
+	assert.Equal(t, "trailer discount amount 4.60 does not match the 4.50 read", detail.Validate(records).Error())
+	assert.Equal(t, "trailer summary count 2 does not match the 1 read", detail.Validate(records[1:]).Error())
+	withoutSale := append(append([]ports.RecordInterface{}, records[:1]...), records[2:]...)
+	assert.Equal(t, "trailer sale count 3 does not match the 2 read", detail.Validate(withoutSale).Error())
+	assert.Equal(t, "trailer not found", detail.Validate(records[:5]).Error())
+	trailer := NewTrailer(NewTrailerRedeDebt(), string_parser.NewStringParser("csv"))
+	assert.Nil(t, trailer.Parse(redeDebtTrailer))
+	assert.Nil(t, trailer.Validate(7))
+}
@@ -0,0 +1,224 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+var (
+	// redeFinLayouts has the detail records of the EEFI (financial) statement
+	redeFinLayouts = []RecordLayout{
+		{Type: "034", Record: &RedeFinPayment{}},
+		{Type: "035", Record: &RedeFinAdjustment{}},
+		{Type: "036", Record: &RedeFinAnticipation{}},
+		{Type: "038", Record: &RedeFinDebit{}},
+		{Type: "052", Record: &RedeFinTrailer{}},
+	}
+)
+
+// RedeFinPayment is a credit order (034): the payment of a sales summary on the bank account of an establishment
+type RedeFinPayment struct {
+	RegisterType  int16       `txt:"3"`
+	Establishment int64       `txt:"9"`
+	OrderNumber   int64       `txt:"11"`
+	OrderDate     time.Time   `txt:"ddmmyyyy"`
+	PaymentDate   time.Time   `txt:"ddmmyyyy"`
+	Amount        ports.Money `txt:"15"`
+	BankCode      int16       `txt:"3"`
+	BranchCode    int32       `txt:"5"`
+	AccountNumber int64       `txt:"11"`
+	SummaryNumber int64       `txt:"9"`
+	SummaryDate   time.Time   `txt:"ddmmyyyy"`
+	Brand         string      `txt:"1"`
+	Status        string      `txt:"2"`
+}
+
+func (d RedeFinPayment) GetRecordType() string {
+	return fmt.Sprintf("%03d", d.RegisterType)
+}
+func (d RedeFinPayment) GetEstablishment() int64 {
+	return d.Establishment
+}
+func (d RedeFinPayment) GetPaymentDate() time.Time {
+	return d.PaymentDate
+}
+func (d RedeFinPayment) GetBankAccount() ports.BankAccount {
+	return ports.BankAccount{Bank: int(d.BankCode), Branch: int(d.BranchCode), Account: d.AccountNumber}
+}
+func (d RedeFinPayment) GetSettledAmount() ports.Money {
+	return d.Amount
+}
+
+// RedeFinAdjustment is a NET adjustment (035) credited or debited with the payments of a date.
+// Kind is C for credits and D for debits
+type RedeFinAdjustment struct {
+	RegisterType     int16       `txt:"3"`
+	Establishment    int64       `txt:"9"`
+	AdjustmentNumber int64       `txt:"11"`
+	AdjustmentDate   time.Time   `txt:"ddmmyyyy"`
+	PaymentDate      time.Time   `txt:"ddmmyyyy"`
+	Amount           ports.Money `txt:"15"`
+	Kind             string      `txt:"1"`
+	ReasonCode       int16       `txt:"2"`
+	Reason           string      `txt:"28"`
+	BankCode         int16       `txt:"3"`
+	BranchCode       int32       `txt:"5"`
+	AccountNumber    int64       `txt:"11"`
+}
+
+func (d RedeFinAdjustment) GetRecordType() string {
+	return fmt.Sprintf("%03d", d.RegisterType)
+}
+func (d RedeFinAdjustment) GetEstablishment() int64 {
+	return d.Establishment
+}
+func (d RedeFinAdjustment) GetPaymentDate() time.Time {
+	return d.PaymentDate
+}
+func (d RedeFinAdjustment) GetBankAccount() ports.BankAccount {
+	return ports.BankAccount{Bank: int(d.BankCode), Branch: int(d.BranchCode), Account: d.AccountNumber}
+}
+
+// GetSettledAmount returns the amount of the adjustment, negative for debits
+func (d RedeFinAdjustment) GetSettledAmount() ports.Money {
+	if d.Kind == "D" {
+		return -d.Amount
+	}
+	return d.Amount
+}
+
+// RedeFinAnticipation is an anticipation (036): receivables of OriginalDate paid on PaymentDate. Amount is
+// the net amount credited, GrossAmount the anticipated amount and FeeAmount the anticipation fee
+type RedeFinAnticipation struct {
+	RegisterType  int16       `txt:"3"`
+	Establishment int64       `txt:"9"`
+	OrderNumber   int64       `txt:"11"`
+	PaymentDate   time.Time   `txt:"ddmmyyyy"`
+	Amount        ports.Money `txt:"15"`
+	GrossAmount   ports.Money `txt:"15"`
+	FeeAmount     ports.Money `txt:"15"`
+	BankCode      int16       `txt:"3"`
+	BranchCode    int32       `txt:"5"`
+	AccountNumber int64       `txt:"11"`
+	OriginalDate  time.Time   `txt:"ddmmyyyy"`
+	Brand         string      `txt:"1"`
+}
+
+func (d RedeFinAnticipation) GetRecordType() string {
+	return fmt.Sprintf("%03d", d.RegisterType)
+}
+func (d RedeFinAnticipation) GetEstablishment() int64 {
+	return d.Establishment
+}
+func (d RedeFinAnticipation) GetPaymentDate() time.Time {
+	return d.PaymentDate
+}
+func (d RedeFinAnticipation) GetBankAccount() ports.BankAccount {
+	return ports.BankAccount{Bank: int(d.BankCode), Branch: int(d.BranchCode), Account: d.AccountNumber}
+}
+func (d RedeFinAnticipation) GetSettledAmount() ports.Money {
+	return d.Amount
+}
+
+// RedeFinDebit is a net debit (038) taken from the payments of PaymentDate. OriginalAmount is the debt
+// and Amount the part settled on the date
+type RedeFinDebit struct {
+	RegisterType   int16       `txt:"3"`
+	Establishment  int64       `txt:"9"`
+	DebitNumber    int64       `txt:"11"`
+	DebitDate      time.Time   `txt:"ddmmyyyy"`
+	PaymentDate    time.Time   `txt:"ddmmyyyy"`
+	Amount         ports.Money `txt:"15"`
+	OriginalAmount ports.Money `txt:"15"`
+	ReasonCode     int16       `txt:"2"`
+	Reason         string      `txt:"28"`
+	BankCode       int16       `txt:"3"`
+	BranchCode     int32       `txt:"5"`
+	AccountNumber  int64       `txt:"11"`
+}
+
+func (d RedeFinDebit) GetRecordType() string {
+	return fmt.Sprintf("%03d", d.RegisterType)
+}
+func (d RedeFinDebit) GetEstablishment() int64 {
+	return d.Establishment
+}
+func (d RedeFinDebit) GetPaymentDate() time.Time {
+	return d.PaymentDate
+}
+func (d RedeFinDebit) GetBankAccount() ports.BankAccount {
+	return ports.BankAccount{Bank: int(d.BankCode), Branch: int(d.BranchCode), Account: d.AccountNumber}
+}
+
+// GetSettledAmount returns the amount debited, negative
+func (d RedeFinDebit) GetSettledAmount() ports.Money {
+	return -d.Amount
+}
+
+// RedeFinTrailer is the file trailer (052) with the totals of payments, anticipations and debits of the file
+type RedeFinTrailer struct {
+	RegisterType       int16       `txt:"3"`
+	HeadquarterCount   int         `txt:"4"`
+	RecordCount        int         `txt:"6"`
+	PaymentCount       int         `txt:"6"`
+	PaymentAmount      ports.Money `txt:"15"`
+	AnticipationCount  int         `txt:"6"`
+	AnticipationAmount ports.Money `txt:"15"`
+	DebitCount         int         `txt:"6"`
+	DebitAmount        ports.Money `txt:"15"`
+}
+
+func (d RedeFinTrailer) GetRecordType() string {
+	return fmt.Sprintf("%03d", d.RegisterType)
+}
+
+// NewRedeFinDetail creates the parser of the EEFI detail records
+func NewRedeFinDetail(parser ports.StringParserInterface) *Detail {
+	return NewDetail(redeFinLayouts, positionType(3), validateRedeFin, parser)
+}
+
+// validateRedeFin checks the totals of the trailer against the payments, anticipations and debits of the file
+func validateRedeFin(records []ports.RecordInterface) error {
+	var trailer *RedeFinTrailer
+	payments, anticipations, debits := 0, 0, 0
+	var paid, anticipated, debited ports.Money
+	for _, record := range records {
+		switch r := record.(type) {
+		case *RedeFinPayment:
+			payments++
+			paid += r.Amount
+		case *RedeFinAnticipation:
+			anticipations++
+			anticipated += r.Amount
+		case *RedeFinDebit:
+			debits++
+			debited += r.Amount
+		case *RedeFinTrailer:
+			trailer = r
+		}
+	}
+	if trailer == nil {
+		return fmt.Errorf("trailer not found")
+	}
+	if trailer.PaymentCount != payments {
+		return totalError("trailer payment count", trailer.PaymentCount, payments)
+	}
+	if trailer.PaymentAmount != paid {
+		return totalError("trailer payment amount", trailer.PaymentAmount, paid)
+	}
+	if trailer.AnticipationCount != anticipations {
+		return totalError("trailer anticipation count", trailer.AnticipationCount, anticipations)
+	}
+	if trailer.AnticipationAmount != anticipated {
+		return totalError("trailer anticipation amount", trailer.AnticipationAmount, anticipated)
+	}
+	if trailer.DebitCount != debits {
+		return totalError("trailer debit count", trailer.DebitCount, debits)
+	}
+	if trailer.DebitAmount != debited {
+		return totalError("trailer debit amount", trailer.DebitAmount, debited)
+	}
+	return nil
+}
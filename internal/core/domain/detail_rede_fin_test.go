@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/lavinas/cielo-edi/internal/utils/string_parser"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	redeFinPayment = "034" + "012345678" + "00000000001" + "10032021" + "11042021" + "000000000014700" + "341" + "01234" +
+		"00000123456" + "000000101" + "10032021" + "M" + "01"
+	redeFinAdjustment = "035" + "012345678" + "00000000002" + "15032021" + "11042021" + "000000000002500" + "D" + "17" +
+		"CANCELAMENTO DE VENDA       " + "341" + "01234" + "00000123456"
+	redeFinAnticipation = "036" + "012345678" + "00000000003" + "11042021" + "000000000009500" + "000000000010000" +
+		"000000000000500" + "341" + "01234" + "00000123456" + "10052021" + "V"
+	redeFinDebit = "038" + "012345678" + "00000000004" + "01042021" + "11042021" + "000000000001000" + "000000000003000" +
+		"21" + "ALUGUEL DE EQUIPAMENTO      " + "341" + "01234" + "00000123456"
+	redeFinTrailer = "052" + "0001" + "000007" + "000001" + "000000000014700" + "000001" + "000000000009500" +
+		"000001" + "000000000001000"
+)
+
+func TestRedeFinDetail(t *testing.T) {
+	detail := NewRedeFinDetail(string_parser.NewStringParser("position"))
+	account := ports.BankAccount{Bank: 341, Branch: 1234, Account: 123456}
+	paymentDate := time.Date(2021, 4, 11, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		line    string
+		rType   string
+		settled ports.Money
+	}{
+		{redeFinPayment, "034", 14700},
+		{redeFinAdjustment, "035", -2500},
+		{redeFinAnticipation, "036", 9500},
+		{redeFinDebit, "038", -1000},
+	}
+	for _, test := range tests {
+		record, err := detail.Parse(test.line, 3)
+		assert.Nil(t, err)
+		assert.Equal(t, test.rType, record.GetRecordType())
+		settlement := record.(ports.SettlementInterface)
+		assert.Equal(t, int64(12345678), settlement.GetEstablishment())
+		assert.Equal(t, paymentDate, settlement.GetPaymentDate())
+		assert.Equal(t, account, settlement.GetBankAccount())
+		assert.Equal(t, test.settled, settlement.GetSettledAmount())
+	}
+	record, _ := detail.Parse(redeFinAnticipation, 3)
+	anticipation := record.(*RedeFinAnticipation)
+	assert.Equal(t, ports.Money(500), anticipation.FeeAmount)
+	assert.Equal(t, time.Date(2021, 5, 10, 0, 0, 0, 0, time.UTC), anticipation.OriginalDate)
+	record, _ = detail.Parse(redeFinDebit, 3)
+	assert.Equal(t, ports.Money(3000), record.(*RedeFinDebit).OriginalAmount)
+	record, err := detail.Parse("032"+"012345678", 3)
+	assert.Nil(t, err)
+	assert.Nil(t, record)
+}
+
+func TestRedeFinTotals(t *testing.T) {
+	detail := NewRedeFinDetail(string_parser.NewStringParser("position"))
+	records := make([]ports.RecordInterface, 0)
+	for _, line := range []string{redeFinPayment, redeFinAdjustment, redeFinAnticipation, redeFinDebit, redeFinTrailer} {
+		record, err := detail.Parse(line, 3)
+		assert.Nil(t, err)
+		records = append(records, record)
+	}
+	assert.Nil(t, detail.Validate(records))
+	records[3].(*RedeFinDebit).Amount = 900
+	assert.Equal(t, "trailer debit amount 10.00 does not match the 9.00 read", detail.Validate(records).Error())
+	assert.Equal(t, "trailer payment count 1 does not match the 0 read", detail.Validate(records[1:]).Error())
+	assert.Equal(t, "trailer not found", detail.Validate(records[:4]).Error())
+	trailer := NewTrailer(&TrailerRede{Statement: "financeiro"}, string_parser.NewStringParser("position"))
+	assert.Nil(t, trailer.Parse(redeFinTrailer))
+	assert.Nil(t, trailer.Validate(7))
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+const (
+	// ErpRecordType is the record type of the lines of ERP sales exports, that have a single record type
+	ErpRecordType = "erp"
+)
+
+// ErpSale is a sale of the csv export of the ERP or POS of a client. The columns of the fields are given by the
+// layout of the client, so the tags only tell the default date format and that amounts are in units (150 or 1.234,56)
+type ErpSale struct {
+	Date          time.Time   `txt:"yyyy-mm-dd"`
+	Amount        ports.Money `txt:"units"`
+	Nsu           string      `txt:"12"`
+	Authorization string      `txt:"6"`
+	Card          string      `txt:"19"`
+}
+
+func (d ErpSale) GetRecordType() string {
+	return ErpRecordType
+}
+
+// GetErpSale returns the sale. NSUs that are not numeric are left zero
+func (d ErpSale) GetErpSale() ports.ErpSale {
+	nsu, _ := strconv.ParseInt(strings.TrimSpace(d.Nsu), 10, 64)
+	return ports.ErpSale{Date: d.Date, Amount: d.Amount, Nsu: nsu,
+		Authorization: strings.ToUpper(strings.TrimSpace(d.Authorization)), Card: strings.TrimSpace(d.Card)}
+}
+
+// NewErpDetail creates the parser of the lines of ERP sales exports. The parser should read the named columns
+// of the layout of the client
+func NewErpDetail(parser ports.StringParserInterface) *Detail {
+	layouts := []RecordLayout{{Type: ErpRecordType, Record: &ErpSale{}}}
+	return NewDetail(layouts, func(string) string { return ErpRecordType }, nil, parser)
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/lavinas/cielo-edi/internal/utils/string_parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErpDetail(t *testing.T) {
+	parser := string_parser.NewStringParser("csv")
+	columns := map[string]string{"date": "Data", "amount": "Valor", "nsu": "NSU", "authorization": "Autorizacao"}
+	assert.Nil(t, parser.SetColumns("Data;Valor;NSU;Autorizacao;Loja", columns))
+	assert.Nil(t, parser.SetDateFormat("dd/mm/yyyy"))
+	detail := NewErpDetail(parser)
+	record, err := detail.Parse("10/03/2021;123,45;000123; a1b2c3 ;Centro", 0)
+	assert.Nil(t, err)
+	sale := record.(*ErpSale)
+	var _ ports.ErpSaleInterface = sale
+	assert.Equal(t, ErpRecordType, sale.GetRecordType())
+	assert.Equal(t, ports.ErpSale{Date: time.Date(2021, 3, 10, 0, 0, 0, 0, time.UTC), Amount: 12345, Nsu: 123,
+		Authorization: "A1B2C3"}, sale.GetErpSale())
+	record, err = detail.Parse("10/03/2021;150;;;Centro", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, ports.Money(15000), record.(*ErpSale).GetErpSale().Amount)
+	record, err = detail.Parse(`10/03/2021;"1.234,56";;;Centro`, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, ports.Money(123456), record.(*ErpSale).GetErpSale().Amount)
+	_, err = detail.Parse("2021-03-10;123,45;000123;A1B2C3;Centro", 0)
+	assert.NotNil(t, err)
+}
@@ -28,3 +28,13 @@ func (h Header) GetData() ports.HeaderDataInterface {
 func (h Header) IsValid() bool {
 	return h.data.IsValid()
 }
+
+// Validate returns the check of the header data validation that failed
+func (h Header) Validate() error {
+	return h.data.Validate()
+}
+
+// Inspect describes how each field of the header data is parsed from txt
+func (h Header) Inspect(txt string) []ports.FieldInspection {
+	return h.parser.Inspect(h.data, txt)
+}
@@ -11,6 +11,8 @@ var (
 		"vendas":       int8(3),
 		"financeiro":   int8(4),
 		"antecipacoes": int8(6),
+		"cessoes":      int8(7),
+		"saldo":        int8(8),
 		"alelo": int8(10),
 	}
 )
@@ -45,6 +47,9 @@ func (d HeaderCielo) GetPeriodInit() time.Time {
 func (d HeaderCielo) GetPeriodEnd() time.Time {
 	return d.PeriodEnd
 }
+func (d HeaderCielo) GetSequence() int {
+	return d.Sequence
+}
 func (d HeaderCielo) GetStatementId() string {
 	return fmt.Sprintf("%02d", d.StatementId)
 }
@@ -54,6 +59,9 @@ func (d HeaderCielo) GetLayoutVersion() int8 {
 func (d HeaderCielo) GetAcquirer() string {
 	return strings.ToUpper(d.Acquirer)
 }
+func (d HeaderCielo) GetLayoutName() string {
+	return "cielo" + d.Statement
+}
 func (d HeaderCielo) IsReprocessed() bool {
 	return d.Sequence == 9999999
 }
@@ -71,20 +79,25 @@ func (d HeaderCielo) GetPeriodDates() ([]time.Time, error) {
 	return times, nil
 }
 func (d HeaderCielo) IsValid() bool {
+	return d.Validate() == nil
+}
+
+// Validate returns the check of IsValid that failed
+func (d HeaderCielo) Validate() error {
 	if d.ProcessingDate.Equal(time.Time{}) {
-		return false
+		return fmt.Errorf("processing date is empty")
 	}
 	if d.Acquirer != "CIELO" {
-		return false
+		return fmt.Errorf("acquirer %s should be CIELO", d.Acquirer)
 	}
 	if d.LayoutVersion == 0 {
-		return false
+		return fmt.Errorf("layout version is empty")
 	}
 	if _, ok := cieloMap[d.Statement]; !ok {
-		return false
+		return fmt.Errorf("statement %s not found", d.Statement)
 	}
 	if cieloMap[d.Statement] != d.StatementId {
-		return false
+		return fmt.Errorf("statement id %02d should be %02d (%s)", d.StatementId, cieloMap[d.Statement], d.Statement)
 	}
-	return true
+	return nil
 }
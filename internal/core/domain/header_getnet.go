@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -21,8 +22,7 @@ type HeaderGetnet struct {
 }
 
 func (d HeaderGetnet) GetHeadquarter() int64 {
-	n, _ := strconv.ParseInt(strings.TrimSpace(d.Headquarter), 10, 64)
-	return n
+	return getnetEstablishment(d.Headquarter)
 }
 func NewHeaderGetnet() *HeaderGetnet {
 	return &HeaderGetnet{}
@@ -36,15 +36,31 @@ func (d HeaderGetnet) GetPeriodInit() time.Time {
 func (d HeaderGetnet) GetPeriodEnd() time.Time {
 	return d.PeriodDate
 }
+func (d HeaderGetnet) GetSequence() int {
+	return d.Sequence
+}
 func (d HeaderGetnet) GetStatementId() string {
 	return "GETNET"
 }
+
+// GetLayoutVersion returns the major version of LayoutVersion (V10.1 is 10) or 0 when it is blank
 func (d HeaderGetnet) GetLayoutVersion() int8 {
-	return int8(0)
+	version := strings.TrimLeft(strings.TrimSpace(d.LayoutVersion), "Vv")
+	if i := strings.Index(version, "."); i >= 0 {
+		version = version[:i]
+	}
+	v, err := strconv.ParseInt(version, 10, 8)
+	if err != nil {
+		return int8(0)
+	}
+	return int8(v)
 }
 func (d HeaderGetnet) GetAcquirer() string {
 	return "GETNET"
 }
+func (d HeaderGetnet) GetLayoutName() string {
+	return "getnet"
+}
 func (d HeaderGetnet) IsReprocessed() bool {
 	return false
 }
@@ -54,5 +70,13 @@ func (d HeaderGetnet) GetPeriodDates() ([]time.Time, error) {
 	return ret, nil
 }
 func (d HeaderGetnet) IsValid() bool {
-	return d.AcquirerCNPJ == "10440482000154"
+	return d.Validate() == nil
+}
+
+// Validate returns the check of IsValid that failed
+func (d HeaderGetnet) Validate() error {
+	if d.AcquirerCNPJ != "10440482000154" {
+		return fmt.Errorf("acquirer cnpj %s should be 10440482000154", d.AcquirerCNPJ)
+	}
+	return nil
 }
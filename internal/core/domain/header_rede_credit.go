@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -40,6 +41,9 @@ func (d HeaderRedeCredit) GetPeriodInit() time.Time {
 func (d HeaderRedeCredit) GetPeriodEnd() time.Time {
 	return d.ProcessingDate
 }
+func (d HeaderRedeCredit) GetSequence() int {
+	return d.Sequence
+}
 func (d HeaderRedeCredit) GetStatementId() string {
 	return d.LayoutVersion[16:20]
 }
@@ -53,6 +57,9 @@ func (d HeaderRedeCredit) GetLayoutVersion() int8 {
 func (d HeaderRedeCredit) GetAcquirer() string {
 	return strings.ToUpper(d.Acquirer)
 }
+func (d HeaderRedeCredit) GetLayoutName() string {
+	return "rede" + d.Statement
+}
 func (d HeaderRedeCredit) IsReprocessed() bool {
 	return strings.Contains(strings.ToLower(d.ProcessingType), "repro")
 }
@@ -63,20 +70,25 @@ func (d HeaderRedeCredit) GetPeriodDates() ([]time.Time, error) {
 }
 
 func (d HeaderRedeCredit) IsValid() bool {
+	return d.Validate() == nil
+}
+
+// Validate returns the check of IsValid that failed
+func (d HeaderRedeCredit) Validate() error {
 	if d.ProcessingDate.Equal(time.Time{}) {
-		return false
+		return fmt.Errorf("processing date is empty")
 	}
 	if !strings.Contains(strings.ToLower(d.Acquirer), "rede") {
-		return false
+		return fmt.Errorf("acquirer %s should be Redecard", d.Acquirer)
 	}
 	if d.LayoutVersion == "" {
-		return false
+		return fmt.Errorf("layout version is empty")
 	}
 	if _, ok := redeCreditoMap[d.Statement]; !ok {
-		return false
+		return fmt.Errorf("statement %s not found", d.Statement)
 	}
 	if !strings.Contains(d.LayoutVersion, redeCreditoMap[d.Statement]) {
-		return false
+		return fmt.Errorf("layout version %s should have statement id %s (%s)", d.LayoutVersion, redeCreditoMap[d.Statement], d.Statement)
 	}
-	return true
+	return nil
 }
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -41,6 +42,9 @@ func (d HeaderRedeDebt) GetPeriodInit() time.Time {
 func (d HeaderRedeDebt) GetPeriodEnd() time.Time {
 	return d.PeriodDate
 }
+func (d HeaderRedeDebt) GetSequence() int {
+	return d.Sequence
+}
 func (d HeaderRedeDebt) GetStatementId() string {
 	return d.LayoutVersion[16:20]
 }
@@ -54,6 +58,9 @@ func (d HeaderRedeDebt) GetLayoutVersion() int8 {
 func (d HeaderRedeDebt) GetAcquirer() string {
 	return strings.ToUpper(d.Acquirer)
 }
+func (d HeaderRedeDebt) GetLayoutName() string {
+	return "rede" + d.Statement
+}
 func (d HeaderRedeDebt) IsReprocessed() bool {
 	return strings.Contains(strings.ToLower(d.ProcessingType), "repro")
 }
@@ -63,20 +70,25 @@ func (d HeaderRedeDebt) GetPeriodDates() ([]time.Time, error) {
 	return ret, nil
 }
 func (d HeaderRedeDebt) IsValid() bool {
+	return d.Validate() == nil
+}
+
+// Validate returns the check of IsValid that failed
+func (d HeaderRedeDebt) Validate() error {
 	if d.ProcessingDate.Equal(time.Time{}) {
-		return false
+		return fmt.Errorf("processing date is empty")
 	}
 	if !strings.Contains(strings.ToLower(d.Acquirer), "rede") {
-		return false
+		return fmt.Errorf("acquirer %s should be Redecard", d.Acquirer)
 	}
 	if d.LayoutVersion == "" {
-		return false
+		return fmt.Errorf("layout version is empty")
 	}
 	if _, ok := redeDebitoMap[d.Statement]; !ok {
-		return false
+		return fmt.Errorf("statement %s not found", d.Statement)
 	}
 	if !strings.Contains(d.LayoutVersion, redeDebitoMap[d.Statement]) {
-		return false
+		return fmt.Errorf("layout version %s should have statement id %s (%s)", d.LayoutVersion, redeDebitoMap[d.Statement], d.Statement)
 	}
-	return true
+	return nil
 }
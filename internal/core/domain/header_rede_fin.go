@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -40,6 +41,9 @@ func (d HeaderRedeFin) GetPeriodInit() time.Time {
 func (d HeaderRedeFin) GetPeriodEnd() time.Time {
 	return d.ProcessingDate
 }
+func (d HeaderRedeFin) GetSequence() int {
+	return d.Sequence
+}
 func (d HeaderRedeFin) GetStatementId() string {
 	return d.LayoutVersion[16:20]
 }
@@ -53,6 +57,9 @@ func (d HeaderRedeFin) GetLayoutVersion() int8 {
 func (d HeaderRedeFin) GetAcquirer() string {
 	return strings.ToUpper(d.Acquirer)
 }
+func (d HeaderRedeFin) GetLayoutName() string {
+	return "rede" + d.Statement
+}
 func (d HeaderRedeFin) IsReprocessed() bool {
 	return strings.Contains(strings.ToLower(d.ProcessingType), "repro")
 }
@@ -63,20 +70,25 @@ func (d HeaderRedeFin) GetPeriodDates() ([]time.Time, error) {
 }
 
 func (d HeaderRedeFin) IsValid() bool {
+	return d.Validate() == nil
+}
+
+// Validate returns the check of IsValid that failed
+func (d HeaderRedeFin) Validate() error {
 	if d.ProcessingDate.Equal(time.Time{}) {
-		return false
+		return fmt.Errorf("processing date is empty")
 	}
 	if !strings.Contains(strings.ToLower(d.Acquirer), "rede") {
-		return false
+		return fmt.Errorf("acquirer %s should be Redecard", d.Acquirer)
 	}
 	if d.LayoutVersion == "" {
-		return false
+		return fmt.Errorf("layout version is empty")
 	}
 	if _, ok := redeFinMap[d.Statement]; !ok {
-		return false
+		return fmt.Errorf("statement %s not found", d.Statement)
 	}
 	if !strings.Contains(d.LayoutVersion, redeFinMap[d.Statement]) {
-		return false
+		return fmt.Errorf("layout version %s should have statement id %s (%s)", d.LayoutVersion, redeFinMap[d.Statement], d.Statement)
 	}
-	return true
+	return nil
 }
@@ -1,7 +1,9 @@
 package domain
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/lavinas/cielo-edi/internal/utils/string_parser"
 	"github.com/stretchr/testify/assert"
@@ -38,3 +40,53 @@ func TestParseRedeOk(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, "2021-03-26", data.GetPeriodInit().Format("2006-01-02"))
 }
+
+func TestGetLayoutName(t *testing.T) {
+	assert.Equal(t, "cielovendas", HeaderCielo{Statement: "vendas"}.GetLayoutName())
+	assert.Equal(t, "redecredito", HeaderRedeCredit{Statement: "credito"}.GetLayoutName())
+	assert.Equal(t, "rededebito", HeaderRedeDebt{Statement: "debito"}.GetLayoutName())
+	assert.Equal(t, "redefinanceiro", HeaderRedeFin{Statement: "financeiro"}.GetLayoutName())
+	assert.Equal(t, "getnet", HeaderGetnet{}.GetLayoutName())
+}
+
+func TestGetnetLayoutVersion(t *testing.T) {
+	assert.Equal(t, int8(0), HeaderGetnet{}.GetLayoutVersion())
+	assert.Equal(t, int8(10), HeaderGetnet{LayoutVersion: "V10.1                    "}.GetLayoutVersion())
+	assert.Equal(t, int8(9), HeaderGetnet{LayoutVersion: "9"}.GetLayoutVersion())
+	assert.Equal(t, int8(0), HeaderGetnet{LayoutVersion: "VX"}.GetLayoutVersion())
+}
+
+func TestValidate(t *testing.T) {
+	parser := string_parser.NewStringParser("position")
+	header := NewHeader(&HeaderCielo{Statement: "vendas"}, parser)
+	err := header.Parse(cieloheaderline)
+	assert.Nil(t, err)
+	assert.Equal(t, "statement id 04 should be 03 (vendas)", header.Validate().Error())
+	header = NewHeader(&HeaderCielo{Statement: "financeiro"}, parser)
+	header.Parse(cieloheaderline)
+	assert.Nil(t, header.Validate())
+	header = NewHeader(&HeaderCielo{Statement: "cessoes"}, parser)
+	header.Parse(strings.Replace(cieloheaderline, "CIELO04", "CIELO07", 1))
+	assert.Nil(t, header.Validate())
+	header = NewHeader(&HeaderCielo{Statement: "saldo"}, parser)
+	header.Parse(strings.Replace(cieloheaderline, "CIELO04", "CIELO08", 1))
+	assert.Nil(t, header.Validate())
+	assert.Equal(t, "statement id 08 should be 07 (cessoes)", HeaderCielo{Statement: "cessoes", StatementId: 8,
+		ProcessingDate: time.Now(), Acquirer: "CIELO", LayoutVersion: 14}.Validate().Error())
+	assert.Equal(t, "acquirer REDE should be CIELO", HeaderCielo{ProcessingDate: time.Now(), Acquirer: "REDE"}.Validate().Error())
+	assert.Equal(t, "processing date is empty", HeaderCielo{}.Validate().Error())
+	rede := HeaderRedeCredit{Statement: "credito", ProcessingDate: time.Now(), Acquirer: "Redecard", LayoutVersion: "V3.01 - 09/06 - EEFI"}
+	assert.Equal(t, "layout version V3.01 - 09/06 - EEFI should have statement id EEVC (credito)", rede.Validate().Error())
+	assert.Equal(t, "acquirer cnpj 1 should be 10440482000154", HeaderGetnet{AcquirerCNPJ: "1"}.Validate().Error())
+}
+
+func TestInspect(t *testing.T) {
+	parser := string_parser.NewStringParser("position")
+	header := NewHeader(&HeaderCielo{Statement: "vendas"}, parser)
+	fields := header.Inspect(cieloheaderline)
+	assert.Len(t, fields, 11)
+	assert.Equal(t, "StatementId", fields[7].Name)
+	assert.Equal(t, 47, fields[7].Offset)
+	assert.Equal(t, "04", fields[7].Raw)
+	assert.Equal(t, "4", fields[7].Value)
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+type Trailer struct {
+	data   ports.TrailerDataInterface
+	parser ports.StringParserInterface
+}
+
+func NewTrailer(data ports.TrailerDataInterface, parser ports.StringParserInterface) *Trailer {
+	return &Trailer{data: data, parser: parser}
+}
+
+func (t Trailer) Parse(txt string) error {
+	return t.parser.Parse(t.data, txt)
+}
+
+func (t Trailer) GetData() ports.TrailerDataInterface {
+	return t.data
+}
+
+// Validate checks that the parsed line is a trailer record and that it declares the number of records read
+func (t Trailer) Validate(records int) error {
+	if err := t.data.Validate(); err != nil {
+		return err
+	}
+	if declared := t.data.GetRecordCount(); declared != records {
+		return fmt.Errorf("trailer declares %d records but %d were read", declared, records)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"fmt"
+)
+
+const (
+	cieloTrailerType = int8(9)
+)
+
+// TrailerCielo is the last record (type 9) of a Cielo statement. RecordCount has
+// the number of records of the file, header and trailer included
+type TrailerCielo struct {
+	RegisterType int8 `txt:"1"`
+	RecordCount  int  `txt:"11"`
+}
+
+func NewTrailerCielo() *TrailerCielo {
+	return &TrailerCielo{}
+}
+func (d TrailerCielo) GetRecordCount() int {
+	return d.RecordCount
+}
+func (d TrailerCielo) Validate() error {
+	if d.RegisterType != cieloTrailerType {
+		return fmt.Errorf("trailer not found (last record type %d should be %d)", d.RegisterType, cieloTrailerType)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"fmt"
+)
+
+const (
+	getnetTrailerType = int8(9)
+)
+
+// TrailerGetnet is the last record (type 9) of a Getnet statement. RecordCount has
+// the number of records of the file, header and trailer included
+type TrailerGetnet struct {
+	RegisterType int8 `txt:"1"`
+	RecordCount  int  `txt:"9"`
+}
+
+func NewTrailerGetnet() *TrailerGetnet {
+	return &TrailerGetnet{}
+}
+func (d TrailerGetnet) GetRecordCount() int {
+	return d.RecordCount
+}
+func (d TrailerGetnet) Validate() error {
+	if d.RegisterType != getnetTrailerType {
+		return fmt.Errorf("trailer not found (last record type %d should be %d)", d.RegisterType, getnetTrailerType)
+	}
+	return nil
+}
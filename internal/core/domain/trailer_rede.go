@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"fmt"
+)
+
+var (
+	redeTrailerMap = map[string]int16{
+		"credito":    int16(28),
+		"financeiro": int16(52),
+	}
+)
+
+// TrailerRede is the file trailer of the Rede positional statements (028 on EEVC and 052 on EEFI).
+// RecordCount has the number of records of the file, header and trailer included
+type TrailerRede struct {
+	Statement        string `txt:"-"`
+	RegisterType     int16  `txt:"3"`
+	HeadquarterCount int    `txt:"4"`
+	RecordCount      int    `txt:"6"`
+}
+
+func NewTrailerRede() *TrailerRede {
+	return &TrailerRede{}
+}
+func (d TrailerRede) GetRecordCount() int {
+	return d.RecordCount
+}
+func (d TrailerRede) Validate() error {
+	registerType, ok := redeTrailerMap[d.Statement]
+	if !ok {
+		return fmt.Errorf("statement %s not found", d.Statement)
+	}
+	if d.RegisterType != registerType {
+		return fmt.Errorf("trailer not found (last record type %03d should be %03d)", d.RegisterType, registerType)
+	}
+	return nil
+}
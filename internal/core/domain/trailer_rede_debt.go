@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"fmt"
+)
+
+const (
+	redeDebtTrailerType = int8(4)
+)
+
+// TrailerRedeDebt is the file trailer (04) of the Rede debit statement (EEVD, csv).
+// RecordCount has the number of records of the file, header and trailer included
+type TrailerRedeDebt struct {
+	RegisterType     int8 `txt:"2"`
+	HeadquarterCount int  `txt:"4"`
+	RecordCount      int  `txt:"6"`
+}
+
+func NewTrailerRedeDebt() *TrailerRedeDebt {
+	return &TrailerRedeDebt{}
+}
+func (d TrailerRedeDebt) GetRecordCount() int {
+	return d.RecordCount
+}
+func (d TrailerRedeDebt) Validate() error {
+	if d.RegisterType != redeDebtTrailerType {
+		return fmt.Errorf("trailer not found (last record type %02d should be %02d)", d.RegisterType, redeDebtTrailerType)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/lavinas/cielo-edi/internal/utils/string_parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrailerCielo(t *testing.T) {
+	trailer := NewTrailer(NewTrailerCielo(), string_parser.NewStringParser("position"))
+	err := trailer.Parse("900000000012" + "                    ")
+	assert.Nil(t, err)
+	assert.Equal(t, 12, trailer.GetData().GetRecordCount())
+	assert.Nil(t, trailer.Validate(12))
+	assert.Equal(t, "trailer declares 12 records but 11 were read", trailer.Validate(11).Error())
+	err = trailer.Parse("200000000150000")
+	assert.Nil(t, err)
+	assert.Equal(t, "trailer not found (last record type 2 should be 9)", trailer.Validate(1).Error())
+}
+
+func TestTrailerRede(t *testing.T) {
+	trailer := NewTrailer(&TrailerRede{Statement: "financeiro"}, string_parser.NewStringParser("position"))
+	err := trailer.Parse("0520001000025")
+	assert.Nil(t, err)
+	assert.Nil(t, trailer.Validate(25))
+	trailer = NewTrailer(&TrailerRede{Statement: "credito"}, string_parser.NewStringParser("position"))
+	trailer.Parse("0520001000025")
+	assert.Equal(t, "trailer not found (last record type 052 should be 028)", trailer.Validate(25).Error())
+	trailer = NewTrailer(NewTrailerRedeDebt(), string_parser.NewStringParser("csv"))
+	err = trailer.Parse("04,0001,000012")
+	assert.Nil(t, err)
+	assert.Nil(t, trailer.Validate(12))
+	trailer.Parse("01,0001,000012")
+	assert.Equal(t, "trailer not found (last record type 01 should be 04)", trailer.Validate(12).Error())
+}
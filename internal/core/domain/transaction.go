@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"strings"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+// newTransaction creates a transaction of a kind with the gross and net amounts of a record, taking the fee as
+// their difference so Net is always Gross less Fee whatever the sign convention of the statement
+func newTransaction(kind string, establishment int64, gross ports.Money, net ports.Money) ports.Transaction {
+	return ports.Transaction{Kind: kind, Establishment: establishment, Gross: gross, Fee: gross - net, Net: net}
+}
+
+// adjustmentKind returns the chargeback kind for adjustments with a chargeback reason and the adjustment
+// kind for the others
+func adjustmentKind(reason string) string {
+	if strings.Contains(strings.ToUpper(reason), "CHARGEBACK") {
+		return ports.ChargebackTransaction
+	}
+	return ports.AdjustmentTransaction
+}
+
+// brandName returns the card brand name of a brand code of an acquirer. Unknown codes are kept as they are
+func brandName(brands map[string]string, code string) string {
+	code = strings.TrimSpace(code)
+	if brand, ok := brands[code]; ok {
+		return brand
+	}
+	return strings.ToUpper(code)
+}
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+const (
+	aleloBrand = "ALELO"
+)
+
+var (
+	// cieloBrands has the card brand names of the Cielo brand codes
+	cieloBrands = map[string]string{
+		"001": "VISA",
+		"002": "MASTERCARD",
+		"003": "AMEX",
+		"006": "SOROCRED",
+		"007": "ELO",
+		"009": "DINERS",
+	}
+)
+
+// GetTransaction maps the paid operation summary to a settlement. The sale date is the presentation date of the summary
+func (d CieloPayment) GetTransaction() ports.Transaction {
+	t := newTransaction(ports.SettlementTransaction, d.Establishment, signed(d.GrossSign, d.GrossAmount), d.GetSettledAmount())
+	t.Brand, t.Product, t.Summary = brandName(cieloBrands, d.Brand), d.ProductCode, d.SummaryNumber
+	t.Installment, t.SaleDate, t.PaymentDate = cieloInstallment(d.Installment), d.PresentationDate, d.PaymentDate
+	return t
+}
+
+// GetTransaction maps the adjustment summary to an adjustment, or a chargeback by its reason. Debit adjustments
+// have negative amounts
+func (d CieloAdjustment) GetTransaction() ports.Transaction {
+	gross := signed(d.GrossSign, d.GrossAmount)
+	if d.TransactionType == cieloDebitAdjustment && gross > 0 {
+		gross = -gross
+	}
+	t := newTransaction(adjustmentKind(d.GetReason()), d.Establishment, gross, d.GetSettledAmount())
+	t.Brand, t.Product, t.Summary = brandName(cieloBrands, d.Brand), d.ProductCode, d.SummaryNumber
+	t.Installment, t.SaleDate, t.PaymentDate = cieloInstallment(d.Installment), d.CaptureDate, d.PaymentDate
+	return t
+}
+
+// GetTransaction maps the sale to a sale with the brand and payment date of its summary. The statement has no fee
+// of the sale, so Net is the gross amount
+func (d CieloSale) GetTransaction() ports.Transaction {
+	amount := signed(d.AmountSign, d.Amount)
+	t := newTransaction(ports.SaleTransaction, d.Establishment, amount, amount)
+	t.Brand, t.PaymentDate = brandName(cieloBrands, d.Brand), d.PaymentDate
+	t.Summary, t.Nsu, t.Authorization, t.Card = d.SummaryNumber, d.Nsu, d.AuthorizationCode, strings.TrimSpace(d.CardNumber)
+	t.Installment, t.InstallmentCount, t.SaleDate = int(d.Installment), int(d.InstallmentCount), d.SaleDate
+	return t
+}
+
+// GetTransaction maps the anticipation operation to an anticipation credited on the credit date
+func (d CieloAnticipation) GetTransaction() ports.Transaction {
+	t := newTransaction(ports.AnticipationTransaction, d.Establishment, d.GetGrossAmount(), d.GetNetAmount())
+	t.Summary, t.PaymentDate = d.OperationNumber, d.CreditDate
+	return t
+}
+
+// GetTransaction maps the assignment operation to an assignment credited on the credit date
+func (d CieloAssignment) GetTransaction() ports.Transaction {
+	t := newTransaction(ports.AssignmentTransaction, d.Establishment, d.GetGrossAmount(), d.GetNetAmount())
+	t.Summary, t.PaymentDate = d.OperationNumber, d.CreditDate
+	return t
+}
+
+// GetTransaction maps the voucher sales summary to a settlement with the voucher product
+func (d CieloAleloSummary) GetTransaction() ports.Transaction {
+	t := newTransaction(ports.SettlementTransaction, d.Establishment, d.GetGrossAmount(), d.GetNetAmount())
+	t.Brand, t.Product, t.Summary = aleloBrand, aleloProduct(d.ProductCode), d.SummaryNumber
+	t.SaleDate, t.PaymentDate = d.SaleDate, d.PaymentDate
+	return t
+}
+
+// GetTransaction maps the voucher sale to a sale with the voucher product
+func (d CieloAleloSale) GetTransaction() ports.Transaction {
+	t := newTransaction(ports.SaleTransaction, d.Establishment, signed(d.GrossSign, d.GrossAmount), signed(d.NetSign, d.NetAmount))
+	t.Brand, t.Product, t.Summary = aleloBrand, aleloProduct(d.ProductCode), d.SummaryNumber
+	t.Nsu, t.Authorization, t.Card, t.SaleDate = d.Nsu, d.AuthorizationCode, strings.TrimSpace(d.CardNumber), d.SaleDate
+	return t
+}
+
+// cieloInstallment returns the installment number of an operation summary, zero for single payment sales
+func cieloInstallment(installment string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(installment))
+	return n
+}
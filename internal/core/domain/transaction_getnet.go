@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"strings"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+var (
+	// getnetBrands has the card brand names of the Getnet brand codes
+	getnetBrands = map[string]string{
+		"AM": "AMEX",
+		"EL": "ELO",
+		"HC": "HIPERCARD",
+		"MC": "MASTERCARD",
+		"VI": "VISA",
+	}
+)
+
+// GetTransaction maps the summary to a settlement of its sales
+func (d GetnetSummary) GetTransaction() ports.Transaction {
+	t := newTransaction(ports.SettlementTransaction, d.GetEstablishment(), d.GrossAmount, d.NetAmount)
+	t.Brand, t.Product, t.Summary = brandName(getnetBrands, d.Brand), strings.TrimSpace(d.ProductCode), d.SummaryNumber
+	t.SaleDate, t.PaymentDate = d.SummaryDate, d.PaymentDate
+	return t
+}
+
+// GetTransaction maps the sale to a sale with its fee
+func (d GetnetSale) GetTransaction() ports.Transaction {
+	t := newTransaction(ports.SaleTransaction, getnetEstablishment(d.Establishment), d.GrossAmount, d.NetAmount)
+	t.Brand, t.Summary, t.Nsu, t.Authorization = brandName(getnetBrands, d.Brand), d.SummaryNumber, d.Nsu, d.AuthorizationCode
+	t.Card, t.Installment, t.InstallmentCount = strings.TrimSpace(d.CardNumber), int(d.InstallmentNumber), int(d.InstallmentCount)
+	t.SaleDate = d.SaleDate
+	return t
+}
+
+// GetTransaction maps the adjustment to an adjustment, or a chargeback by its reason
+func (d GetnetAdjustment) GetTransaction() ports.Transaction {
+	t := newTransaction(adjustmentKind(d.Reason), d.GetEstablishment(), d.GetSettledAmount(), d.GetSettledAmount())
+	t.Summary, t.PaymentDate = d.SummaryNumber, d.PaymentDate
+	return t
+}
+
+// GetTransaction maps the anticipation to an anticipation paid on the payment date
+func (d GetnetAnticipation) GetTransaction() ports.Transaction {
+	t := newTransaction(ports.AnticipationTransaction, d.GetEstablishment(), d.GrossAmount, d.NetAmount)
+	t.Summary, t.PaymentDate = d.OperationNumber, d.PaymentDate
+	return t
+}
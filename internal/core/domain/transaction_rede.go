@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"strings"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+var (
+	// redeBrands has the card brand names of the Rede brand codes
+	redeBrands = map[string]string{
+		"A": "AMEX",
+		"D": "DINERS",
+		"E": "ELO",
+		"H": "HIPERCARD",
+		"M": "MASTERCARD",
+		"V": "VISA",
+	}
+)
+
+// GetTransaction maps the credit sale to a sale. The statement has no fee of the sale, so Net is the gross amount
+func (d RedeCreditSale) GetTransaction() ports.Transaction {
+	t := newTransaction(ports.SaleTransaction, d.Establishment, d.GrossAmount, d.GrossAmount)
+	t.Brand, t.Summary, t.Nsu, t.Authorization = brandName(redeBrands, d.Brand), d.SummaryNumber, d.Nsu, d.AuthorizationCode
+	t.Card, t.SaleDate = strings.TrimSpace(d.CardNumber), d.SaleDate
+	return t
+}
+
+// GetTransaction maps the installment sale to a sale with its installment count. Its installments are
+// mapped by the installment records
+func (d RedeCreditInstallmentSale) GetTransaction() ports.Transaction {
+	t := newTransaction(ports.SaleTransaction, d.Establishment, d.GrossAmount, d.GrossAmount)
+	t.Summary, t.Nsu, t.Authorization, t.Card = d.SummaryNumber, d.Nsu, d.AuthorizationCode, strings.TrimSpace(d.CardNumber)
+	t.InstallmentCount, t.SaleDate = int(d.InstallmentCount), d.SaleDate
+	return t
+}
+
+// GetTransaction maps the installment to an installment paid on the credit date
+func (d RedeCreditInstallment) GetTransaction() ports.Transaction {
+	t := newTransaction(ports.InstallmentTransaction, d.Establishment, d.GrossAmount, d.NetAmount)
+	t.Summary, t.Installment, t.InstallmentCount = d.SummaryNumber, int(d.InstallmentNumber), int(d.InstallmentCount)
+	t.PaymentDate = d.CreditDate
+	return t
+}
+
+// GetTransaction maps the sales adjustment to an adjustment, or a chargeback by its reason
+func (d RedeCreditAdjustment) GetTransaction() ports.Transaction {
+	t := newTransaction(adjustmentKind(d.Reason), d.Establishment, d.GetAmount(), d.GetAmount())
+	t.Summary, t.Card, t.SaleDate, t.PaymentDate = d.SummaryNumber, strings.TrimSpace(d.CardNumber), d.SaleDate, d.CreditDate
+	return t
+}
+
+// GetTransaction maps the debit sale to a sale with its fee
+func (d RedeDebtSale) GetTransaction() ports.Transaction {
+	t := newTransaction(ports.SaleTransaction, d.Establishment, d.GrossAmount, d.NetAmount)
+	t.Brand, t.Summary, t.Nsu, t.Authorization = brandName(redeBrands, d.Brand), d.SummaryNumber, d.Nsu, d.AuthorizationCode
+	t.Card, t.SaleDate = strings.TrimSpace(d.CardNumber), d.SaleDate
+	return t
+}
+
+// GetTransaction maps the payment to a settlement of its sales summary (RV)
+func (d RedeFinPayment) GetTransaction() ports.Transaction {
+	t := newTransaction(ports.SettlementTransaction, d.Establishment, d.Amount, d.Amount)
+	t.Brand, t.Summary, t.SaleDate, t.PaymentDate = brandName(redeBrands, d.Brand), d.SummaryNumber, d.SummaryDate, d.PaymentDate
+	return t
+}
+
+// GetTransaction maps the financial adjustment to an adjustment, or a chargeback by its reason
+func (d RedeFinAdjustment) GetTransaction() ports.Transaction {
+	t := newTransaction(adjustmentKind(d.Reason), d.Establishment, d.GetSettledAmount(), d.GetSettledAmount())
+	t.Summary, t.PaymentDate = d.AdjustmentNumber, d.PaymentDate
+	return t
+}
+
+// GetTransaction maps the anticipation to an anticipation. Net is the amount credited
+func (d RedeFinAnticipation) GetTransaction() ports.Transaction {
+	t := newTransaction(ports.AnticipationTransaction, d.Establishment, d.GrossAmount, d.Amount)
+	t.Brand, t.Summary, t.PaymentDate = brandName(redeBrands, d.Brand), d.OrderNumber, d.PaymentDate
+	return t
+}
+
+// GetTransaction maps the debit to an adjustment, or a chargeback by its reason
+func (d RedeFinDebit) GetTransaction() ports.Transaction {
+	t := newTransaction(adjustmentKind(d.Reason), d.Establishment, d.GetSettledAmount(), d.GetSettledAmount())
+	t.Summary, t.PaymentDate = d.DebitNumber, d.PaymentDate
+	return t
+}
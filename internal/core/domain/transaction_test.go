@@ -0,0 +1,215 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/lavinas/cielo-edi/internal/utils/string_parser"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	// transactionLayouts has the detail layouts of all acquirers. Each record must map to a transaction or be
+	// listed on notTransactions
+	transactionLayouts = map[string][]RecordLayout{
+		"cielofinanceiro":   cieloFinLayouts,
+		"cieloantecipacoes": cieloAnticipationLayouts,
+		"cielocessoes":      cieloAssignmentLayouts,
+		"cielosaldo":        cieloOpenBalanceLayouts,
+		"cieloalelo":        cieloAleloLayouts,
+		"cielovendas":       cieloSalesLayouts,
+		"redecredito":       redeCreditLayouts,
+		"rededebito":        redeDebtLayouts,
+		"redefinanceiro":    redeFinLayouts,
+		"getnet":            getnetLayouts,
+	}
+	// notTransactions has the records that are not transactions: summaries of other records, balances and trailers
+	notTransactions = map[string]bool{
+		"*domain.CieloSaleSummary":        true,
+		"*domain.CieloAnticipatedSummary": true,
+		"*domain.CieloAssignedReceivable": true,
+		"*domain.CieloOpenBalance":        true,
+		"*domain.RedeCreditSummary":       true,
+		"*domain.RedeCreditTrailer":       true,
+		"*domain.RedeDebtSummary":         true,
+		"*domain.RedeDebtTrailer":         true,
+		"*domain.RedeFinTrailer":          true,
+		"*domain.GetnetTrailer":           true,
+	}
+	// withoutBrand has the sales of layouts without the card brand
+	withoutBrand = map[string]bool{
+		"redecredito 2 *domain.RedeCreditSale":            true,
+		"redecredito 3 *domain.RedeCreditInstallmentSale": true,
+		"getnet 9 *domain.GetnetSale":                     true,
+	}
+	// withoutPaymentDate has the sales whose payment date is only on their summaries
+	withoutPaymentDate = map[string]bool{
+		"cieloalelo 14 *domain.CieloAleloSale":            true,
+		"redecredito 2 *domain.RedeCreditSale":            true,
+		"redecredito 3 *domain.RedeCreditSale":            true,
+		"redecredito 3 *domain.RedeCreditInstallmentSale": true,
+		"rededebito 4 *domain.RedeDebtSale":               true,
+		"getnet 9 *domain.GetnetSale":                     true,
+		"getnet 10 *domain.GetnetSale":                    true,
+	}
+	// transactionFixtures has a line of each transaction record of each acquirer and the kind it maps to. previous
+	// has the summary line parsed before the record, whose values the record gets
+	transactionFixtures = []struct {
+		acquirer string
+		detail   *Detail
+		version  int8
+		previous string
+		line     string
+		kind     string
+	}{
+		{"cielofinanceiro", NewCieloFinDetail(string_parser.NewStringParser("position")), 14, "", cieloFinPayment, ports.SettlementTransaction},
+		{"cielofinanceiro", NewCieloFinDetail(string_parser.NewStringParser("position")), 14, "", cieloFinAdjustment, ports.ChargebackTransaction},
+		{"cieloantecipacoes", NewCieloAnticipationDetail(string_parser.NewStringParser("position")), 14, "", cieloAnticipation, ports.AnticipationTransaction},
+		{"cielocessoes", NewCieloAssignmentDetail(string_parser.NewStringParser("position")), 14, "", cieloAssignment, ports.AssignmentTransaction},
+		{"cieloalelo", NewCieloAleloDetail(string_parser.NewStringParser("position")), 14, "", cieloAleloSummary, ports.SettlementTransaction},
+		{"cieloalelo", NewCieloAleloDetail(string_parser.NewStringParser("position")), 14, "", cieloAleloSale, ports.SaleTransaction},
+		{"cielovendas", NewCieloSalesDetail(string_parser.NewStringParser("position")), 13, cieloSaleSummary, cieloSaleV13, ports.SaleTransaction},
+		{"cielovendas", NewCieloSalesDetail(string_parser.NewStringParser("position")), 15, cieloSaleSummary, cieloSaleV15, ports.SaleTransaction},
+		{"redecredito", NewRedeCreditDetail(string_parser.NewStringParser("position")), 2, "", redeCreditSaleV2, ports.SaleTransaction},
+		{"redecredito", NewRedeCreditDetail(string_parser.NewStringParser("position")), 3, "", redeCreditSaleV3, ports.SaleTransaction},
+		{"redecredito", NewRedeCreditDetail(string_parser.NewStringParser("position")), 3, "", redeCreditInstallmentSale, ports.SaleTransaction},
+		{"redecredito", NewRedeCreditDetail(string_parser.NewStringParser("position")), 3, "", redeCreditInstallment, ports.InstallmentTransaction},
+		{"redecredito", NewRedeCreditDetail(string_parser.NewStringParser("position")), 3, "", redeCreditAdjustment, ports.AdjustmentTransaction},
+		{"rededebito", NewRedeDebtDetail(string_parser.NewStringParser("csv")), 4, "", redeDebtSale, ports.SaleTransaction},
+		{"redefinanceiro", NewRedeFinDetail(string_parser.NewStringParser("position")), 3, "", redeFinPayment, ports.SettlementTransaction},
+		{"redefinanceiro", NewRedeFinDetail(string_parser.NewStringParser("position")), 3, "", redeFinAdjustment, ports.AdjustmentTransaction},
+		{"redefinanceiro", NewRedeFinDetail(string_parser.NewStringParser("position")), 3, "", redeFinAnticipation, ports.AnticipationTransaction},
+		{"redefinanceiro", NewRedeFinDetail(string_parser.NewStringParser("position")), 3, "", redeFinDebit, ports.AdjustmentTransaction},
+		{"getnet", NewGetnetDetail(string_parser.NewStringParser("position")), 10, "", getnetSummary, ports.SettlementTransaction},
+		{"getnet", NewGetnetDetail(string_parser.NewStringParser("position")), 9, "", getnetSaleV9, ports.SaleTransaction},
+		{"getnet", NewGetnetDetail(string_parser.NewStringParser("position")), 10, "", getnetSale, ports.SaleTransaction},
+		{"getnet", NewGetnetDetail(string_parser.NewStringParser("position")), 10, "", getnetAdjustment, ports.AdjustmentTransaction},
+		{"getnet", NewGetnetDetail(string_parser.NewStringParser("position")), 10, "", getnetAnticipation, ports.AnticipationTransaction},
+	}
+)
+
+// checkTransaction checks the rules of the canonical schema that every acquirer mapping must follow
+func checkTransaction(t *testing.T, name string, tx ports.Transaction) {
+	assert.Contains(t, []string{ports.SaleTransaction, ports.InstallmentTransaction, ports.SettlementTransaction,
+		ports.AdjustmentTransaction, ports.ChargebackTransaction, ports.AnticipationTransaction,
+		ports.AssignmentTransaction}, tx.Kind, name)
+	assert.Equal(t, "", tx.Acquirer, name)
+	assert.Greater(t, tx.Establishment, int64(0), name)
+	assert.NotEqual(t, ports.Money(0), tx.Gross, name)
+	assert.Equal(t, tx.Gross-tx.Fee, tx.Net, name)
+	assert.Equal(t, strings.ToUpper(strings.TrimSpace(tx.Brand)), tx.Brand, name)
+	assert.Equal(t, strings.TrimSpace(tx.Card), tx.Card, name)
+	assert.LessOrEqual(t, tx.Installment, tx.InstallmentCount, name)
+	switch tx.Kind {
+	case ports.SaleTransaction:
+		assert.NotEqual(t, time.Time{}, tx.SaleDate, name)
+		assert.True(t, tx.Brand != "" || withoutBrand[name], name)
+		assert.True(t, !tx.PaymentDate.IsZero() || withoutPaymentDate[name], name)
+		assert.True(t, tx.Nsu > 0 || tx.Authorization != "", name)
+		assert.NotEqual(t, "", tx.Card, name)
+		assert.True(t, tx.Fee >= 0 && tx.Gross > 0, name)
+	case ports.InstallmentTransaction:
+		assert.Greater(t, tx.Installment, 0, name)
+		assert.NotEqual(t, time.Time{}, tx.PaymentDate, name)
+	case ports.SettlementTransaction:
+		assert.NotEqual(t, time.Time{}, tx.PaymentDate, name)
+		assert.NotEqual(t, "", tx.Brand, name)
+	default:
+		assert.NotEqual(t, time.Time{}, tx.PaymentDate, name)
+	}
+}
+
+func TestTransactionLayouts(t *testing.T) {
+	for acquirer, layouts := range transactionLayouts {
+		for _, layout := range layouts {
+			var record ports.RecordInterface = layout.Record
+			if u, ok := record.(upgrader); ok {
+				record = u.Upgrade()
+			}
+			name := fmt.Sprintf("%s %s %T", acquirer, layout.Type, record)
+			_, ok := record.(ports.TransactionInterface)
+			assert.True(t, ok != notTransactions[fmt.Sprintf("%T", record)], name)
+		}
+	}
+}
+
+func TestTransactionConformance(t *testing.T) {
+	for _, fixture := range transactionFixtures {
+		if fixture.previous != "" {
+			_, err := fixture.detail.Parse(fixture.previous, fixture.version)
+			assert.Nil(t, err, fixture.acquirer)
+		}
+		record, err := fixture.detail.Parse(fixture.line, fixture.version)
+		assert.Nil(t, err, fixture.acquirer)
+		name := fmt.Sprintf("%s %d %T", fixture.acquirer, fixture.version, record)
+		r, ok := record.(ports.TransactionInterface)
+		if !assert.True(t, ok, name) {
+			continue
+		}
+		tx := r.GetTransaction()
+		assert.Equal(t, fixture.kind, tx.Kind, name)
+		checkTransaction(t, name, tx)
+	}
+}
+
+func TestTransactionMapping(t *testing.T) {
+	date := func(txt string) time.Time {
+		d, _ := time.Parse("2006-01-02", txt)
+		return d
+	}
+	parse := func(detail *Detail, line string, version int8) ports.Transaction {
+		record, err := detail.Parse(line, version)
+		assert.Nil(t, err)
+		return record.(ports.TransactionInterface).GetTransaction()
+	}
+	cieloFin := NewCieloFinDetail(string_parser.NewStringParser("position"))
+	assert.Equal(t, ports.Transaction{Kind: ports.SettlementTransaction, Establishment: 1023863232, Brand: "MASTERCARD",
+		Product: "01", Summary: 101,
+		Gross: 15000, Fee: 300, Net: 14700, SaleDate: date("2021-06-28"), PaymentDate: date("2021-06-30")},
+		parse(cieloFin, cieloFinPayment, 14))
+	assert.Equal(t, ports.Transaction{Kind: ports.ChargebackTransaction, Establishment: 1023863232, Brand: "VISA",
+		Product: "00", Summary: 102,
+		Gross: -2000, Net: -2000, SaleDate: date("2021-06-25"), PaymentDate: date("2021-06-30")},
+		parse(cieloFin, cieloFinAdjustment, 14))
+	cieloSales := NewCieloSalesDetail(string_parser.NewStringParser("position"))
+	assert.Equal(t, ports.Transaction{Kind: ports.SaleTransaction, Establishment: 1023863232, Summary: 101, Nsu: 1234567,
+		Authorization: "A1B2C3", Card: "455187******1234", Installment: 1, InstallmentCount: 1, Gross: 10000, Net: 10000,
+		SaleDate: date("2021-03-10")}, parse(cieloSales, cieloSaleV15, 15))
+	_, err := cieloSales.Parse(cieloSaleSummary, 15)
+	assert.Nil(t, err)
+	sale := parse(cieloSales, cieloSaleV15, 15)
+	assert.Equal(t, "VISA", sale.Brand)
+	assert.Equal(t, date("2021-04-09"), sale.PaymentDate)
+	// sales of another summary do not get its values
+	other := parse(cieloSales, strings.Replace(cieloSaleV15, "0000101", "0000102", 1), 15)
+	assert.Equal(t, "", other.Brand)
+	alelo := parse(NewCieloAleloDetail(string_parser.NewStringParser("position")), cieloAleloSale, 14)
+	assert.Equal(t, "ALELO", alelo.Brand)
+	assert.Equal(t, "ALELO REFEICAO", alelo.Product)
+	redeCredit := NewRedeCreditDetail(string_parser.NewStringParser("position"))
+	assert.Equal(t, "MASTERCARD", parse(redeCredit, redeCreditSaleV3, 3).Brand)
+	assert.Equal(t, ports.Transaction{Kind: ports.InstallmentTransaction, Establishment: 12345678, Summary: 102, Installment: 1,
+		InstallmentCount: 3, Gross: 10000, Fee: 300, Net: 9700, PaymentDate: date("2021-04-11")},
+		parse(redeCredit, redeCreditInstallment, 3))
+	adjustment := parse(redeCredit, redeCreditAdjustment, 3)
+	assert.Equal(t, ports.Money(-2500), adjustment.Net)
+	assert.Equal(t, date("2021-03-10"), adjustment.SaleDate)
+	debt := parse(NewRedeDebtDetail(string_parser.NewStringParser("csv")), redeDebtSale, 4)
+	assert.Equal(t, "MAESTRO", debt.Brand)
+	assert.Equal(t, ports.Money(200), debt.Fee)
+	redeFin := NewRedeFinDetail(string_parser.NewStringParser("position"))
+	anticipation := parse(redeFin, redeFinAnticipation, 3)
+	assert.Equal(t, ports.Money(500), anticipation.Fee)
+	assert.Equal(t, "VISA", anticipation.Brand)
+	assert.Equal(t, ports.Money(-1000), parse(redeFin, redeFinDebit, 3).Net)
+	getnet := NewGetnetDetail(string_parser.NewStringParser("position"))
+	sale = parse(getnet, getnetSale, 10)
+	assert.Equal(t, int64(1447355), sale.Establishment)
+	assert.Equal(t, "MASTERCARD", sale.Brand)
+	assert.Equal(t, "", parse(getnet, getnetSaleV9, 9).Brand)
+	assert.Equal(t, ports.ChargebackTransaction, adjustmentKind("Chargeback"))
+	assert.Equal(t, "XYZ", brandName(cieloBrands, " xyz "))
+}
@@ -0,0 +1,454 @@
+package ports
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RenamedStatus     = "renamed"
+	FailedStatus      = "failed"
+	QuarantinedStatus = "quarantined"
+	RequeuedStatus    = "requeued"
+	DateFormat        = "2006-01-02"
+	printDateFormat   = "02/01/2006"
+)
+
+// Reconciliation statuses of a scheduled payment
+const (
+	OnTimeStatus    = "ontime"
+	LateStatus      = "late"
+	DivergentStatus = "divergent"
+	OpenStatus      = "open"
+	// ErpOnlyStatus and AcquirerOnlyStatus are the ERP sales not found at the acquirer and the acquirer sales
+	// not found on the ERP export
+	ErpOnlyStatus      = "erponly"
+	AcquirerOnlyStatus = "acquireronly"
+	// DepositedStatus and MissingStatus are the acquirer credits found and not found on the bank statements
+	DepositedStatus = "deposited"
+	MissingStatus   = "missing"
+)
+
+// Transaction kinds of the canonical schema shared by all acquirers
+const (
+	SaleTransaction         = "sale"
+	InstallmentTransaction  = "installment"
+	SettlementTransaction   = "settlement"
+	AdjustmentTransaction   = "adjustment"
+	ChargebackTransaction   = "chargeback"
+	AnticipationTransaction = "anticipation"
+	AssignmentTransaction   = "assignment"
+)
+
+// Money is an amount in cents. Statement amounts are digits with two implied decimal places
+type Money int64
+
+// ParseMoney parses an amount with an optional sign. Amounts with a decimal separator (. or ,)
+// are in units, like 12.34, and amounts without it are in cents, like 1234
+func ParseMoney(txt string) (Money, error) {
+	txt = strings.TrimSpace(txt)
+	sign := int64(1)
+	if strings.HasPrefix(txt, "-") || strings.HasPrefix(txt, "+") {
+		if txt[0] == '-' {
+			sign = -1
+		}
+		txt = strings.TrimSpace(txt[1:])
+	}
+	units, cents := txt, ""
+	if i := strings.LastIndexAny(txt, ".,"); i >= 0 {
+		units, cents = txt[:i], txt[i+1:]
+		if len(cents) > 2 || units == "" && cents == "" {
+			return 0, fmt.Errorf("money %s should have two decimal places", txt)
+		}
+		cents += strings.Repeat("0", 2-len(cents))
+		if units == "" {
+			units = "0"
+		}
+	}
+	if units == "" {
+		return 0, fmt.Errorf("money is empty")
+	}
+	value, err := strconv.ParseInt(units+cents, 10, 64)
+	if err != nil || strings.ContainsAny(units+cents, "+-") {
+		return 0, fmt.Errorf("money %s is not numeric", txt)
+	}
+	return Money(sign * value), nil
+}
+
+// ParseUnits parses an amount in units with an optional sign, like the amounts of spreadsheets and ERP exports: 150
+// is 150.00. Thousands separators and a decimal point or comma are accepted, like 1.234,56 or 1,234.56. A single
+// separator followed by three digits is a thousands separator, so 1.234 is 1234.00
+func ParseUnits(txt string) (Money, error) {
+	value := strings.TrimSpace(txt)
+	sign := ""
+	if strings.HasPrefix(value, "-") || strings.HasPrefix(value, "+") {
+		sign, value = value[:1], strings.TrimSpace(value[1:])
+	}
+	if value == "" {
+		return 0, fmt.Errorf("amount is empty")
+	}
+	units, cents := value, "00"
+	if i := strings.LastIndexAny(value, ".,"); i >= 0 {
+		separator, other := value[i:i+1], ","
+		if separator == "," {
+			other = "."
+		}
+		switch {
+		case strings.Contains(value[:i], other):
+			units, cents = strings.ReplaceAll(value[:i], other, ""), value[i+1:]
+		case strings.Count(value, separator) > 1 || len(value)-i-1 == 3:
+			units = strings.ReplaceAll(value, separator, "")
+		default:
+			units, cents = value[:i], value[i+1:]
+		}
+	}
+	if strings.ContainsAny(units, ".,") {
+		return 0, fmt.Errorf("amount %s is not valid", txt)
+	}
+	money, err := ParseMoney(sign + units + "." + cents)
+	if err != nil {
+		return 0, fmt.Errorf("amount %s is not valid", strings.TrimSpace(txt))
+	}
+	return money, nil
+}
+
+func (m Money) String() string {
+	sign, value := "", int64(m)
+	if value < 0 {
+		sign, value = "-", -value
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, value/100, value%100)
+}
+
+// MarshalJSON writes the amount as a number in units, like 12.34
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	txt := string(data)
+	if !strings.ContainsAny(txt, ".") {
+		txt += ".00"
+	}
+	value, err := ParseMoney(txt)
+	if err != nil {
+		return err
+	}
+	*m = value
+	return nil
+}
+
+// FieldError is an error parsing a field of a record, like a header field that does not fit the layout
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+// DateRange is a continuous period of days from Start to End (inclusive)
+type DateRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+type dateRangeJSON struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+func (d DateRange) String() string {
+	return fmt.Sprintf("%s - %s", d.Start.Format(printDateFormat), d.End.Format(printDateFormat))
+}
+
+// Days returns the number of days of the range
+func (d DateRange) Days() int {
+	return int(d.End.Sub(d.Start).Hours()/24) + 1
+}
+
+func (d DateRange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(dateRangeJSON{Start: d.Start.Format(DateFormat), End: d.End.Format(DateFormat)})
+}
+
+func (d *DateRange) UnmarshalJSON(data []byte) error {
+	var v dateRangeJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	start, err := time.Parse(DateFormat, v.Start)
+	if err != nil {
+		return err
+	}
+	end, err := time.Parse(DateFormat, v.End)
+	if err != nil {
+		return err
+	}
+	d.Start, d.End = start, end
+	return nil
+}
+
+// RenameResult is the result of renaming, quarantining or requeuing a file. NewName has the new name
+// of a renamed file or the destination path of a moved one and Reason explains a failure or a quarantine
+type RenameResult struct {
+	File    string `json:"file"`
+	NewName string `json:"newName,omitempty"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// DuplicateGroup holds files that are the same statement. Kind is Exact when the files have the same
+// content and Logical when they share acquirer, headquarter, statement, period and sequence but differ in content;
+// a logical group lists one file of each content, latest processed first, as the exact copies are in their own group
+// Quarantine has the results of moving the redundant copies
+type DuplicateGroup struct {
+	Kind       string         `json:"kind"`
+	Key        string         `json:"key"`
+	Files      []string       `json:"files"`
+	Quarantine []RenameResult `json:"quarantine,omitempty"`
+}
+
+// FileInventory describes a file of a path and the header data found on it
+type FileInventory struct {
+	Name           string    `json:"name"`
+	Size           int64     `json:"size"`
+	Valid          bool      `json:"valid"`
+	Error          string    `json:"error,omitempty"`
+	Acquirer       string    `json:"acquirer,omitempty"`
+	Headquarter    int64     `json:"headquarter,omitempty"`
+	StatementId    string    `json:"statementId,omitempty"`
+	PeriodInit     time.Time `json:"periodInit"`
+	PeriodEnd      time.Time `json:"periodEnd"`
+	ProcessingDate time.Time `json:"processingDate"`
+	Sequence       int       `json:"sequence,omitempty"`
+	LayoutVersion  int8      `json:"layoutVersion,omitempty"`
+	Reprocessed    bool      `json:"reprocessed,omitempty"`
+}
+
+// GapSummary has the missing days of a statement of a headquarter (EC) in a period.
+// OldestGap is nil when the period is fully covered
+type GapSummary struct {
+	Statement   string      `json:"statement"`
+	Headquarter int64       `json:"headquarter"`
+	MissingDays int         `json:"missingDays"`
+	OldestGap   *DateRange  `json:"oldestGap,omitempty"`
+	Gaps        []DateRange `json:"gaps"`
+}
+
+// FieldInspection describes how a struct field was parsed from a txt line. Offset is the position
+// of the field on the line (the column on csv), Length is the expected length and Raw has the text found
+type FieldInspection struct {
+	Name   string `json:"name"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+	Raw    string `json:"raw"`
+	Value  string `json:"value,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// LayoutInspection is the result of parsing a file header with a layout. Stage and Error tell
+// where it was rejected (parse or validate) and Fields has each field of the layout
+type LayoutInspection struct {
+	Layout string            `json:"layout"`
+	Valid  bool              `json:"valid"`
+	Stage  string            `json:"stage,omitempty"`
+	Error  string            `json:"error,omitempty"`
+	Fields []FieldInspection `json:"fields"`
+}
+
+// FileValidation is the result of checking that a file is complete. Records has the number of records
+// read, DeclaredRecords the number declared on the trailer and Stage where the file was rejected
+type FileValidation struct {
+	File            string `json:"file"`
+	Valid           bool   `json:"valid"`
+	Records         int    `json:"records"`
+	DeclaredRecords int    `json:"declaredRecords"`
+	Stage           string `json:"stage,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// BankAccount is the bank account where an establishment receives its payments
+type BankAccount struct {
+	Bank    int   `json:"bank"`
+	Branch  int   `json:"branch"`
+	Account int64 `json:"account"`
+}
+
+func (b BankAccount) String() string {
+	return fmt.Sprintf("%03d/%05d/%d", b.Bank, b.Branch, b.Account)
+}
+
+// DailySettlement is the net amount settled on a bank account of an establishment (EC) on a date, that
+// matches a bank deposit. Records is the number of records settled and Negative flags a net debited
+// from the establishment
+type DailySettlement struct {
+	Date          time.Time   `json:"date"`
+	Establishment int64       `json:"establishment"`
+	Account       BankAccount `json:"account"`
+	Credits       Money       `json:"credits"`
+	Debits        Money       `json:"debits"`
+	Net           Money       `json:"net"`
+	Records       int         `json:"records"`
+	Negative      bool        `json:"negative"`
+}
+
+// SalesTotal has the sales of a date and card brand. Sales is the number of sales
+type SalesTotal struct {
+	Date     time.Time `json:"date"`
+	Brand    string    `json:"brand"`
+	Sales    int       `json:"sales"`
+	Gross    Money     `json:"gross"`
+	Discount Money     `json:"discount"`
+	Net      Money     `json:"net"`
+}
+
+// AnticipationCost is the effective rate of an anticipation operation. Days is the average number of days
+// anticipated weighted by the gross amount of the receivables and the rates are fractions (0.02 is 2%)
+type AnticipationCost struct {
+	Operation     int64     `json:"operation"`
+	Establishment int64     `json:"establishment"`
+	Date          time.Time `json:"date"`
+	Gross         Money     `json:"gross"`
+	Fee           Money     `json:"fee"`
+	Net           Money     `json:"net"`
+	Days          float64   `json:"days"`
+	MonthlyRate   float64   `json:"monthlyRate"`
+	AnnualRate    float64   `json:"annualRate"`
+}
+
+// OutstandingReceivable is the amount still to be paid to an establishment (EC) for a card brand on a due date.
+// Receivables is the number of installments
+type OutstandingReceivable struct {
+	Establishment int64     `json:"establishment"`
+	Brand         string    `json:"brand"`
+	DueDate       time.Time `json:"dueDate"`
+	Receivables   int       `json:"receivables"`
+	Gross         Money     `json:"gross"`
+	Net           Money     `json:"net"`
+}
+
+// Transaction is a detail record in the canonical schema shared by all acquirers. Kind is one of the transaction
+// kinds, amounts are signed (debits are negative) and Net is always Gross less Fee. Summary is the operation summary
+// (RO/RV) or the operation number of the record, Card the masked card number and Brand the card brand name. Fields
+// the statement does not have are zero
+type Transaction struct {
+	Kind             string    `json:"kind"`
+	Acquirer         string    `json:"acquirer"`
+	Establishment    int64     `json:"establishment"`
+	Brand            string    `json:"brand,omitempty"`
+	Product          string    `json:"product,omitempty"`
+	Summary          int64     `json:"summary,omitempty"`
+	Nsu              int64     `json:"nsu,omitempty"`
+	Authorization    string    `json:"authorization,omitempty"`
+	Card             string    `json:"card,omitempty"`
+	Installment      int       `json:"installment,omitempty"`
+	InstallmentCount int       `json:"installmentCount,omitempty"`
+	Gross            Money     `json:"gross"`
+	Fee              Money     `json:"fee"`
+	Net              Money     `json:"net"`
+	SaleDate         time.Time `json:"saleDate"`
+	PaymentDate      time.Time `json:"paymentDate"`
+}
+
+// ScheduledPayment is the net amount an acquirer will pay an establishment (EC) on a date for an operation summary
+// (RO/RV) installment of a sales statement. Single payment summaries have installment zero
+type ScheduledPayment struct {
+	Acquirer      string    `json:"acquirer"`
+	Establishment int64     `json:"establishment"`
+	Summary       int64     `json:"summary"`
+	Installment   int       `json:"installment"`
+	Date          time.Time `json:"date"`
+	Net           Money     `json:"net"`
+}
+
+// Reconciliation is a scheduled payment matched to the settlements that paid it. Status is on time, late, divergent
+// (paid with a different amount) or open (not paid). PaymentDate is the date of the last settlement
+type Reconciliation struct {
+	Acquirer      string    `json:"acquirer"`
+	Establishment int64     `json:"establishment"`
+	Summary       int64     `json:"summary"`
+	Installment   int       `json:"installment"`
+	ScheduledDate time.Time `json:"scheduledDate"`
+	Expected      Money     `json:"expected"`
+	PaymentDate   time.Time `json:"paymentDate"`
+	Paid          Money     `json:"paid"`
+	Settlements   int       `json:"settlements"`
+	Status        string    `json:"status"`
+}
+
+// ErpLayout is the layout of the csv sales export of the ERP or POS of a client. Columns maps the ErpSale fields
+// (date, amount, nsu, authorization and card) to the names of their columns and DateFormat is the format of the
+// date column (ex dd/mm/yyyy). A sale matches an acquirer sale with an amount up to AmountTolerance apart and a date
+// up to DayTolerance days apart
+type ErpLayout struct {
+	Columns         map[string]string
+	DateFormat      string
+	AmountTolerance Money
+	DayTolerance    int
+}
+
+// ErpSale is a sale of the csv export of the ERP or POS of a client. Line is its line on File and Card has the
+// card number or its last digits. Fields without a column on the layout are zero
+type ErpSale struct {
+	File          string    `json:"file"`
+	Line          int       `json:"line"`
+	Date          time.Time `json:"date"`
+	Amount        Money     `json:"amount"`
+	Nsu           int64     `json:"nsu,omitempty"`
+	Authorization string    `json:"authorization,omitempty"`
+	Card          string    `json:"card,omitempty"`
+}
+
+// SaleReconciliation is an ERP sale and an acquirer sale that do not reconcile. Status is erponly (the sale was not
+// found at the acquirer), acquireronly (the acquirer sale was not found on the ERP) or divergent (the amounts differ).
+// The fields of the missing side are zero
+type SaleReconciliation struct {
+	Status         string    `json:"status"`
+	File           string    `json:"file,omitempty"`
+	Line           int       `json:"line,omitempty"`
+	Acquirer       string    `json:"acquirer,omitempty"`
+	Establishment  int64     `json:"establishment,omitempty"`
+	Date           time.Time `json:"date"`
+	Nsu            int64     `json:"nsu,omitempty"`
+	Authorization  string    `json:"authorization,omitempty"`
+	Card           string    `json:"card,omitempty"`
+	ErpAmount      Money     `json:"erpAmount"`
+	AcquirerAmount Money     `json:"acquirerAmount"`
+}
+
+// BankEntry is an entry of a bank statement, like the CNAB return files, on a date. Line is its line on File,
+// AccountDigit the check digit of the account and the amount is positive for credits and negative for debits
+type BankEntry struct {
+	File         string      `json:"file"`
+	Line         int         `json:"line"`
+	Date         time.Time   `json:"date"`
+	Account      BankAccount `json:"account"`
+	AccountDigit string      `json:"accountDigit,omitempty"`
+	Amount       Money       `json:"amount"`
+	History      string      `json:"history,omitempty"`
+	Document     string      `json:"document,omitempty"`
+}
+
+// Deposit is a credit an acquirer settles on a bank account of an establishment (EC) on a date and the bank entry
+// that deposited it. Status is deposited or missing (no bank entry with the date, amount and account). The fields of
+// the entry are zero for missing deposits
+type Deposit struct {
+	Acquirer      string      `json:"acquirer"`
+	Date          time.Time   `json:"date"`
+	Establishment int64       `json:"establishment"`
+	Account       BankAccount `json:"account"`
+	Expected      Money       `json:"expected"`
+	File          string      `json:"file,omitempty"`
+	Line          int         `json:"line,omitempty"`
+	History       string      `json:"history,omitempty"`
+	Document      string      `json:"document,omitempty"`
+	Status        string      `json:"status"`
+}
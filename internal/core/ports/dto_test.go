@@ -0,0 +1,70 @@
+package ports
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		txt   string
+		money Money
+		err   bool
+	}{
+		{"000000000012345", 12345, false},
+		{"123.45", 12345, false},
+		{"-123,4", -12340, false},
+		{" +0.05 ", 5, false},
+		{".5", 50, false},
+		{"12.345", 0, true},
+		{"12a", 0, true},
+		{"", 0, true},
+		{"-", 0, true},
+		{"1-2", 0, true},
+	}
+	for _, test := range tests {
+		money, err := ParseMoney(test.txt)
+		assert.Equal(t, test.err, err != nil, test.txt)
+		assert.Equal(t, test.money, money, test.txt)
+	}
+}
+
+func TestParseUnits(t *testing.T) {
+	tests := []struct {
+		txt   string
+		money Money
+		err   bool
+	}{
+		{"150", 15000, false},
+		{"-150", -15000, false},
+		{"1.234,56", 123456, false},
+		{"1,234.56", 123456, false},
+		{"1.234.567,8", 123456780, false},
+		{"1.234", 123400, false},
+		{"1,234,567", 123456700, false},
+		{"12,5", 1250, false},
+		{" 0,05 ", 5, false},
+		{"R$ 10", 0, true},
+		{"1,2345", 0, true},
+		{"1.234,56.7", 0, true},
+		{"", 0, true},
+		{"-", 0, true},
+	}
+	for _, test := range tests {
+		money, err := ParseUnits(test.txt)
+		assert.Equal(t, test.err, err != nil, test.txt)
+		assert.Equal(t, test.money, money, test.txt)
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(struct{ Amount Money }{-123405})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"Amount":-1234.05}`, string(data))
+	var amounts []Money
+	assert.Nil(t, json.Unmarshal([]byte(`[12.5, 3, -0.01]`), &amounts))
+	assert.Equal(t, []Money{1250, 300, -1}, amounts)
+	assert.Equal(t, "0.00", Money(0).String())
+}
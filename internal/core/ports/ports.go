@@ -1,18 +1,40 @@
 package ports
 
 import (
+	"context"
+	"io"
 	"io/fs"
 	"time"
 )
 
 type StringParserInterface interface {
 	Parse(interface{}, string) error
+	Inspect(interface{}, string) []FieldInspection
+}
+
+// ColumnParserInterface is a csv parser that reads the fields from the named columns of a header line
+type ColumnParserInterface interface {
+	StringParserInterface
+	SetColumns(string, map[string]string) error
+	SetDateFormat(string) error
 }
 
 type FileManagerInterface interface {
+	SetEncoding(string) error
 	GetFiles(string) ([]fs.FileInfo, error)
 	GetFirstLine(string, fs.FileInfo) (string, error)
+	GetLastLine(string, fs.FileInfo) (string, error)
+	OpenReader(string, fs.FileInfo) (io.ReadCloser, error)
+	CountLines(string, fs.FileInfo) (int, error)
 	RenameFile(string, string, string) error
+	MoveFile(string, string, string) error
+	GetFileHash(string, fs.FileInfo) (string, error)
+	WriteFile(string, string, []byte) error
+	RemoveFile(string, string) error
+}
+
+type FileWatcherInterface interface {
+	Watch(context.Context, string, func(fs.FileInfo)) error
 }
 
 type LoggerInterface interface {
@@ -22,27 +44,194 @@ type LoggerInterface interface {
 
 type HeaderInterface interface {
 	Parse(string) error
+	Inspect(string) []FieldInspection
 	IsValid() bool
+	Validate() error
 	GetData() HeaderDataInterface
 }
 
+type TrailerInterface interface {
+	Parse(string) error
+	Validate(int) error
+	GetData() TrailerDataInterface
+}
+
+type TrailerDataInterface interface {
+	GetRecordCount() int
+	Validate() error
+}
+
 type HeaderDataInterface interface {
 	GetHeadquarter() int64
 	GetProcessingDate() time.Time
 	GetPeriodInit() time.Time
 	GetPeriodEnd() time.Time
+	GetSequence() int
 	GetStatementId() string
 	GetLayoutVersion() int8
 	GetAcquirer() string
+	GetLayoutName() string
 	IsReprocessed() bool
 	GetPeriodDates() ([]time.Time, error)
 	IsValid() bool
+	Validate() error
+}
+
+// RecordInterface is a detail record of a statement file, like a sale or a payment
+type RecordInterface interface {
+	GetRecordType() string
+}
+
+// SettlementInterface is a detail record that moves money on the bank account of an establishment (EC)
+// on a payment date. Credits are positive and debits negative
+type SettlementInterface interface {
+	RecordInterface
+	GetEstablishment() int64
+	GetPaymentDate() time.Time
+	GetBankAccount() BankAccount
+	GetSettledAmount() Money
+}
+
+// AnticipationInterface is a detail record of an anticipation operation: receivables paid on a credit date
+// before their original dates for a fee
+type AnticipationInterface interface {
+	RecordInterface
+	GetOperation() int64
+	GetEstablishment() int64
+	GetCreditDate() time.Time
+	GetGrossAmount() Money
+	GetNetAmount() Money
+}
+
+// AnticipatedInterface is a detail record of the receivables of an anticipation operation with the date
+// they would be paid without it
+type AnticipatedInterface interface {
+	RecordInterface
+	GetOperation() int64
+	GetOriginalDate() time.Time
+	GetGrossAmount() Money
+}
+
+// AssignmentInterface is a detail record of a receivables assignment operation: receivables sold to an assignee
+// (a bank or fund) on an assignment date. They are not anticipations, as the acquirer does not pay them in advance
+type AssignmentInterface interface {
+	RecordInterface
+	GetOperation() int64
+	GetEstablishment() int64
+	GetAssignee() string
+	GetAssignmentDate() time.Time
+	GetGrossAmount() Money
+	GetNetAmount() Money
+}
+
+// ReceivableInterface is a detail record of the open balance of the receivables of an establishment (EC) and card
+// brand due on a date
+type ReceivableInterface interface {
+	RecordInterface
+	GetEstablishment() int64
+	GetBrand() string
+	GetDueDate() time.Time
+	GetReceivableCount() int
+	GetGrossAmount() Money
+	GetNetAmount() Money
+}
+
+// SalesSummaryInterface is a detail record that totals the sales of a date and card brand, like a sales summary (RV)
+type SalesSummaryInterface interface {
+	RecordInterface
+	GetSalesDate() time.Time
+	GetBrand() string
+	GetSaleCount() int
+	GetGrossAmount() Money
+	GetDiscountAmount() Money
+	GetNetAmount() Money
+}
+
+// ScheduledPaymentInterface is a detail record of a sales statement with the net amount to be paid to an establishment
+// (EC) on a date for an operation summary (RO/RV) installment. Single payment summaries have installment zero
+type ScheduledPaymentInterface interface {
+	RecordInterface
+	GetEstablishment() int64
+	GetSummaryNumber() int64
+	GetInstallment() int
+	GetScheduledDate() time.Time
+	GetNetAmount() Money
+}
+
+// TransactionInterface is a detail record that maps to a transaction of the canonical schema. The acquirer
+// of the transaction is given by the header of the file
+type TransactionInterface interface {
+	RecordInterface
+	GetTransaction() Transaction
+}
+
+// ErpSaleInterface is a record of the csv sales export of the ERP or POS of a client
+type ErpSaleInterface interface {
+	RecordInterface
+	GetErpSale() ErpSale
+}
+
+// BankEntryInterface is a record of a bank statement, like the CNAB return files
+type BankEntryInterface interface {
+	RecordInterface
+	GetBankEntry() BankEntry
+}
+
+// DetailInterface parses the detail records of a statement with the layout version of the file
+// and checks the totals declared on its trailer records
+type DetailInterface interface {
+	Parse(string, int8) (RecordInterface, error)
+	Validate([]RecordInterface) error
+}
+
+type CalendarInterface interface {
+	IsExpected(time.Time) bool
 }
 
 type ServiceInterface interface {
-	FormatNames(string) ([]string, error)
-	GetGapGrouped(string, time.Time, time.Time) ([]string, error)
-	GetPeriodGrouped(string) ([]string, error)
+	SetNameTemplate(string) error
+	SetCalendar(CalendarInterface)
+	SetTrailer(TrailerInterface)
+	SetVerifyTrailer(bool)
+	SetEncoding(string) error
+	SetDetail(DetailInterface)
+	GetRecords(string, fs.FileInfo) ([]RecordInterface, error)
+	GetDailySettlements(string) ([]DailySettlement, error)
+	GetSalesTotals(string) ([]SalesTotal, error)
+	GetAnticipationCosts(string) ([]AnticipationCost, error)
+	GetOutstandingReceivables(string) ([]OutstandingReceivable, error)
+	GetTransactions(string) ([]Transaction, error)
+	GetScheduledPayments(string) ([]ScheduledPayment, error)
+	ValidateFiles(string) ([]FileValidation, error)
+	FormatNames(string) ([]RenameResult, error)
+	FormatNamesQuarantine(string, string) ([]RenameResult, error)
+	FormatFile(string, fs.FileInfo, string) RenameResult
+	IsFormattedName(string) bool
+	Inspect(string, fs.FileInfo) (LayoutInspection, error)
+	Requeue(string, string) ([]RenameResult, error)
+	GetGapGrouped(string, time.Time, time.Time) ([]DateRange, error)
+	GetPeriodGrouped(string) ([]DateRange, error)
+	GetHeadquarterGapGrouped(string, int64, time.Time, time.Time) ([]DateRange, error)
+	GetHeadquarterPeriodGrouped(string, int64) ([]DateRange, error)
+	GetGapSummary(string, []int64, time.Time, time.Time) ([]GapSummary, error)
+	GetInventory(string) ([]FileInventory, error)
+	QuarantineDuplicates(string, string, bool) ([]DuplicateGroup, error)
+}
+
+// ErpReaderInterface reads the sales of the csv exports of the ERP or POS of a client
+type ErpReaderInterface interface {
+	GetSales(string) ([]ErpSale, error)
+}
+
+// BankDetailInterface parses the records of the bank statements and recognizes the header record of their files
+type BankDetailInterface interface {
+	DetailInterface
+	IsHeader(string) bool
+}
+
+// BankReaderInterface reads the entries of the bank statements of a client
+type BankReaderInterface interface {
+	GetEntries(string) ([]BankEntry, error)
 }
 
 type CommandLineInterface interface {
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+// BankReader reads the entries of the bank statements of a client, like the CNAB return files
+type BankReader struct {
+	fileManager ports.FileManagerInterface
+	detail      ports.BankDetailInterface
+}
+
+// NewBankReader creates a reader of bank statements whose lines are parsed by detail
+func NewBankReader(fileManager ports.FileManagerInterface, detail ports.BankDetailInterface) *BankReader {
+	return &BankReader{fileManager: fileManager, detail: detail}
+}
+
+// GetEntries returns the entries of the files of a path, in the order of the files and lines. Files that do not
+// start with a header record are not bank statements and are skipped. The records of each file are checked
+// against the totals of its trailers
+func (r BankReader) GetEntries(path string) ([]ports.BankEntry, error) {
+	files, err := r.fileManager.GetFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]ports.BankEntry, 0)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		fileEntries, err := r.getFileEntries(path, file)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	return entries, nil
+}
+
+// getFileEntries reads the entries of a file and validates its records. Files whose first line is not a header
+// return no entries
+func (r BankReader) getFileEntries(path string, file fs.FileInfo) ([]ports.BankEntry, error) {
+	reader, err := r.fileManager.OpenReader(path, file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, scanBuffer), maxLineSize)
+	records := make([]ports.RecordInterface, 0)
+	entries := make([]ports.BankEntry, 0)
+	header := false
+	for line := 1; scanner.Scan(); line++ {
+		txt := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(txt) == "" {
+			continue
+		}
+		if !header {
+			if !r.detail.IsHeader(txt) {
+				return nil, nil
+			}
+			header = true
+		}
+		record, err := r.detail.Parse(txt, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", file.Name(), line, err)
+		}
+		if record == nil {
+			continue
+		}
+		records = append(records, record)
+		if e, ok := record.(ports.BankEntryInterface); ok {
+			entry := e.GetBankEntry()
+			entry.File, entry.Line = file.Name(), line
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.detail.Validate(records); err != nil {
+		return nil, fmt.Errorf("%s: %v", file.Name(), err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBankReader(t *testing.T) {
+	fi := []fs.FileInfo{NewFileInfoMock("bank.ret", false), NewFileInfoMock("dir", true)}
+	fm := NewFileManagerHashMock(fi, nil)
+	fm.content = "header\r\nK:2021-04-09:145.50\r\n\r\nD1\nK:2021-04-10:-10.00\n"
+	reader := NewBankReader(fm, &DetailMock{declared: 3})
+	entries, err := reader.GetEntries("path")
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+	date, _ := time.Parse("2006-01-02", "2021-04-09")
+	assert.Equal(t, ports.BankEntry{File: "bank.ret", Line: 2, Date: date,
+		Account: ports.BankAccount{Bank: 341, Branch: 1, Account: 10}, Amount: 14550}, entries[0])
+	assert.Equal(t, 5, entries[1].Line)
+	// totals errors
+	reader = NewBankReader(fm, &DetailMock{declared: 2})
+	_, err = reader.GetEntries("path")
+	assert.NotNil(t, err)
+	assert.Equal(t, "bank.ret: trailer record count 2 does not match the 3 read", err.Error())
+	// files without a header record are skipped
+	fm.content = "K:2021-04-09:145.50\nheader\nX\n"
+	entries, err = reader.GetEntries("path")
+	assert.Nil(t, err)
+	assert.Len(t, entries, 0)
+	// detail errors
+	fm.content = "\nheader\nK:2021-04-09:145.50\nX\n"
+	_, err = reader.GetEntries("path")
+	assert.NotNil(t, err)
+	assert.Equal(t, "bank.ret line 4: record X: Parse Error", err.Error())
+}
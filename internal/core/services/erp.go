@@ -0,0 +1,100 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+const (
+	// erpExtension is the extension of the ERP sales exports read from a path
+	erpExtension = ".csv"
+)
+
+// ErpReader reads the sales of the csv exports of the ERP or POS of a client with the layout of the client.
+// The first line of each file is the header with the names of the columns
+type ErpReader struct {
+	fileManager ports.FileManagerInterface
+	parser      ports.ColumnParserInterface
+	detail      ports.DetailInterface
+	layout      ports.ErpLayout
+}
+
+// NewErpReader creates a reader of ERP sales exports. detail parses the lines with parser, that is set with the
+// columns of the header of each file
+func NewErpReader(fileManager ports.FileManagerInterface, parser ports.ColumnParserInterface, detail ports.DetailInterface,
+	layout ports.ErpLayout) *ErpReader {
+	return &ErpReader{fileManager: fileManager, parser: parser, detail: detail, layout: layout}
+}
+
+// GetSales returns the sales of the csv files of a path, in the order of the files and lines
+func (r ErpReader) GetSales(path string) ([]ports.ErpSale, error) {
+	for _, field := range []string{"date", "amount"} {
+		if r.layout.Columns[field] == "" {
+			return nil, fmt.Errorf("erp layout has no %s column", field)
+		}
+	}
+	if err := r.parser.SetDateFormat(r.layout.DateFormat); err != nil {
+		return nil, err
+	}
+	files, err := r.fileManager.GetFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	sales := make([]ports.ErpSale, 0)
+	for _, file := range files {
+		if file.IsDir() || !strings.EqualFold(filepath.Ext(file.Name()), erpExtension) {
+			continue
+		}
+		fileSales, err := r.getFileSales(path, file)
+		if err != nil {
+			return nil, err
+		}
+		sales = append(sales, fileSales...)
+	}
+	return sales, nil
+}
+
+// getFileSales reads the sales of a file with the columns of its header
+func (r ErpReader) getFileSales(path string, file fs.FileInfo) ([]ports.ErpSale, error) {
+	reader, err := r.fileManager.OpenReader(path, file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, scanBuffer), maxLineSize)
+	sales := make([]ports.ErpSale, 0)
+	if !scanner.Scan() {
+		return sales, scanner.Err()
+	}
+	header := strings.TrimPrefix(strings.TrimRight(scanner.Text(), "\r"), "\ufeff")
+	if err := r.parser.SetColumns(header, r.layout.Columns); err != nil {
+		return nil, fmt.Errorf("%s: %v", file.Name(), err)
+	}
+	for line := 2; scanner.Scan(); line++ {
+		txt := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(txt) == "" {
+			continue
+		}
+		record, err := r.detail.Parse(txt, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", file.Name(), line, err)
+		}
+		e, ok := record.(ports.ErpSaleInterface)
+		if !ok {
+			continue
+		}
+		sale := e.GetErpSale()
+		sale.File, sale.Line = file.Name(), line
+		sales = append(sales, sale)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sales, nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErpReader(t *testing.T) {
+	fi := []fs.FileInfo{NewFileInfoMock("sales.csv", false), NewFileInfoMock("sales.txt", false), NewFileInfoMock("dir.csv", true)}
+	fm := NewFileManagerHashMock(fi, nil)
+	fm.content = "\ufeffdata;valor;nsu\r\nE:2021-03-10:12.30:123\r\n\r\nD1\nE:2021-03-11:5.00:0\n"
+	parser := &ColumnParserMock{}
+	layout := ports.ErpLayout{Columns: map[string]string{"date": "data", "amount": "valor"}, DateFormat: "dd/mm/yyyy"}
+	reader := NewErpReader(fm, parser, &DetailMock{}, layout)
+	sales, err := reader.GetSales("path")
+	assert.Nil(t, err)
+	assert.Equal(t, "data;valor;nsu", parser.header)
+	assert.Equal(t, "dd/mm/yyyy", parser.dateFormat)
+	assert.Len(t, sales, 2)
+	date, _ := time.Parse("2006-01-02", "2021-03-10")
+	assert.Equal(t, ports.ErpSale{File: "sales.csv", Line: 2, Date: date, Amount: 1230, Nsu: 123}, sales[0])
+	assert.Equal(t, 5, sales[1].Line)
+	// detail errors
+	fm.content = "data;valor\nX\n"
+	_, err = reader.GetSales("path")
+	assert.NotNil(t, err)
+	assert.Equal(t, "sales.csv line 2: record X: Parse Error", err.Error())
+	// layout errors
+	fm.content = "date;amount\n"
+	_, err = reader.GetSales("path")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "sales.csv: column ")
+	reader = NewErpReader(fm, parser, &DetailMock{}, ports.ErpLayout{Columns: map[string]string{"date": "data"}})
+	_, err = reader.GetSales("path")
+	assert.NotNil(t, err)
+	assert.Equal(t, "erp layout has no amount column", err.Error())
+}
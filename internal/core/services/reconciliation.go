@@ -0,0 +1,232 @@
+package services
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+// Reconcile matches each scheduled payment to the settlements that paid it by acquirer, establishment, operation
+// summary (RO/RV) and installment, in the order of the scheduled payments. Settlements without installment number
+// pay the installments of their summary one by one, in date order. A scheduled payment is divergent when the amount
+// paid is not the expected, late when paid after its date and open when it has no settlement
+func Reconcile(scheduled []ports.ScheduledPayment, settlements []ports.Transaction) []ports.Reconciliation {
+	type paymentKey struct {
+		acquirer      string
+		establishment int64
+		summary       int64
+		installment   int
+	}
+	paid := make(map[paymentKey][]ports.Transaction)
+	for _, t := range settlements {
+		if t.Kind != ports.SettlementTransaction {
+			continue
+		}
+		key := paymentKey{acquirer: t.Acquirer, establishment: t.Establishment, summary: t.Summary, installment: t.Installment}
+		paid[key] = append(paid[key], t)
+	}
+	for _, p := range paid {
+		sort.SliceStable(p, func(i, j int) bool { return p[i].PaymentDate.Before(p[j].PaymentDate) })
+	}
+	reconciliations := make([]ports.Reconciliation, 0, len(scheduled))
+	for _, s := range scheduled {
+		r := ports.Reconciliation{Acquirer: s.Acquirer, Establishment: s.Establishment, Summary: s.Summary,
+			Installment: s.Installment, ScheduledDate: s.Date, Expected: s.Net, Status: ports.OpenStatus}
+		key := paymentKey{acquirer: s.Acquirer, establishment: s.Establishment, summary: s.Summary, installment: s.Installment}
+		matched := paid[key]
+		delete(paid, key)
+		if len(matched) == 0 && s.Installment > 0 {
+			key.installment = 0
+			if p := paid[key]; len(p) > 0 {
+				matched, paid[key] = p[:1], p[1:]
+			}
+		}
+		for _, t := range matched {
+			r.Paid += t.Net
+			r.PaymentDate = t.PaymentDate
+			r.Settlements++
+		}
+		switch {
+		case r.Settlements == 0:
+		case r.Paid != r.Expected:
+			r.Status = ports.DivergentStatus
+		case r.PaymentDate.After(r.ScheduledDate):
+			r.Status = ports.LateStatus
+		default:
+			r.Status = ports.OnTimeStatus
+		}
+		reconciliations = append(reconciliations, r)
+	}
+	return reconciliations
+}
+
+// ReconcileSales matches the sales of an ERP export to the acquirer sales dated up to the layout day tolerance apart.
+// Sales are matched first by NSU or authorization code, then by the last four card digits and amount, and sales
+// without NSU, authorization and card are matched by amount. Amounts match up to the layout amount tolerance apart.
+// It returns the ERP sales not found at the acquirer and the sales matched with different amounts, in the ERP order,
+// followed by the acquirer sales not found on the ERP. Acquirer sales out of the period of the ERP sales are ignored
+func ReconcileSales(erp []ports.ErpSale, sales []ports.Transaction, layout ports.ErpLayout) []ports.SaleReconciliation {
+	reconciliations := make([]ports.SaleReconciliation, 0)
+	if len(erp) == 0 {
+		return reconciliations
+	}
+	tolerance := time.Duration(layout.DayTolerance) * 24 * time.Hour
+	first, last := erp[0].Date, erp[0].Date
+	for _, e := range erp {
+		if e.Date.Before(first) {
+			first = e.Date
+		}
+		if e.Date.After(last) {
+			last = e.Date
+		}
+	}
+	acquirer := make([]ports.Transaction, 0)
+	for _, t := range sales {
+		if t.Kind == ports.SaleTransaction && !t.SaleDate.Before(first.Add(-tolerance)) && !t.SaleDate.After(last.Add(tolerance)) {
+			acquirer = append(acquirer, t)
+		}
+	}
+	amountMatch := func(e ports.ErpSale, t ports.Transaction) bool {
+		return absMoney(e.Amount-t.Gross) <= layout.AmountTolerance
+	}
+	rules := []func(ports.ErpSale, ports.Transaction) bool{
+		func(e ports.ErpSale, t ports.Transaction) bool {
+			return e.Nsu != 0 && e.Nsu == t.Nsu ||
+				e.Authorization != "" && e.Authorization == strings.ToUpper(strings.TrimSpace(t.Authorization))
+		},
+		func(e ports.ErpSale, t ports.Transaction) bool {
+			digits := lastDigits(e.Card)
+			return digits != "" && digits == lastDigits(t.Card) && amountMatch(e, t)
+		},
+		func(e ports.ErpSale, t ports.Transaction) bool {
+			return e.Nsu == 0 && e.Authorization == "" && e.Card == "" && amountMatch(e, t)
+		},
+	}
+	matches := make([]int, len(erp))
+	for i := range matches {
+		matches[i] = -1
+	}
+	used := make([]bool, len(acquirer))
+	for _, rule := range rules {
+		for i, e := range erp {
+			if matches[i] >= 0 {
+				continue
+			}
+			for j, t := range acquirer {
+				if !used[j] && absDuration(e.Date.Sub(t.SaleDate)) <= tolerance && rule(e, t) {
+					matches[i], used[j] = j, true
+					break
+				}
+			}
+		}
+	}
+	for i, e := range erp {
+		r := ports.SaleReconciliation{Status: ports.ErpOnlyStatus, File: e.File, Line: e.Line, Date: e.Date, Nsu: e.Nsu,
+			Authorization: e.Authorization, Card: e.Card, ErpAmount: e.Amount}
+		if matches[i] >= 0 {
+			t := acquirer[matches[i]]
+			if amountMatch(e, t) {
+				continue
+			}
+			r.Status, r.Acquirer, r.Establishment, r.AcquirerAmount = ports.DivergentStatus, t.Acquirer, t.Establishment, t.Gross
+		}
+		reconciliations = append(reconciliations, r)
+	}
+	for j, t := range acquirer {
+		if used[j] {
+			continue
+		}
+		reconciliations = append(reconciliations, ports.SaleReconciliation{Status: ports.AcquirerOnlyStatus, Acquirer: t.Acquirer,
+			Establishment: t.Establishment, Date: t.SaleDate, Nsu: t.Nsu, Authorization: t.Authorization, Card: t.Card,
+			AcquirerAmount: t.Gross})
+	}
+	return reconciliations
+}
+
+// ReconcileDeposits matches the credits of the daily settlements of each acquirer to the credit entries of the bank
+// statements with the same amount and account up to dayTolerance days apart, the closest date first. Each entry
+// deposits a single credit. Credits without an entry are missing. Deposits are sorted by date and acquirer
+func ReconcileDeposits(settlements map[string][]ports.DailySettlement, entries []ports.BankEntry,
+	dayTolerance int) []ports.Deposit {
+	tolerance := time.Duration(dayTolerance) * 24 * time.Hour
+	acquirers := make([]string, 0, len(settlements))
+	for acquirer := range settlements {
+		acquirers = append(acquirers, acquirer)
+	}
+	sort.Strings(acquirers)
+	used := make([]bool, len(entries))
+	deposits := make([]ports.Deposit, 0)
+	for _, acquirer := range acquirers {
+		for _, s := range settlements[acquirer] {
+			if s.Net <= 0 {
+				continue
+			}
+			d := ports.Deposit{Acquirer: acquirer, Date: s.Date, Establishment: s.Establishment, Account: s.Account,
+				Expected: s.Net, Status: ports.MissingStatus}
+			found := -1
+			for i, e := range entries {
+				diff := absDuration(e.Date.Sub(s.Date))
+				if used[i] || e.Amount != s.Net || diff > tolerance || !accountMatch(s.Account, e) {
+					continue
+				}
+				if found < 0 || diff < absDuration(entries[found].Date.Sub(s.Date)) {
+					found = i
+				}
+			}
+			if found >= 0 {
+				e := entries[found]
+				used[found] = true
+				d.File, d.Line, d.History, d.Document, d.Status = e.File, e.Line, e.History, e.Document, ports.DepositedStatus
+			}
+			deposits = append(deposits, d)
+		}
+	}
+	sort.SliceStable(deposits, func(i, j int) bool { return deposits[i].Date.Before(deposits[j].Date) })
+	return deposits
+}
+
+// accountMatch tells if a bank entry is on an account of a settlement. Acquirers may inform the account number with
+// its check digit
+func accountMatch(account ports.BankAccount, entry ports.BankEntry) bool {
+	if account.Bank != entry.Account.Bank || account.Branch != entry.Account.Branch {
+		return false
+	}
+	if account.Account == entry.Account.Account {
+		return true
+	}
+	digit, err := strconv.ParseInt(entry.AccountDigit, 10, 64)
+	return err == nil && account.Account == entry.Account.Account*10+digit
+}
+
+// lastDigits returns the last four digits of a card number, masked (ex 5067******1234) or not. It is empty
+// when the card does not end with four digits
+func lastDigits(card string) string {
+	card = strings.TrimSpace(card)
+	if len(card) < 4 {
+		return ""
+	}
+	digits := card[len(card)-4:]
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return ""
+		}
+	}
+	return digits
+}
+
+func absMoney(m ports.Money) ports.Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
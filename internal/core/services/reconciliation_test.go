@@ -0,0 +1,139 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcile(t *testing.T) {
+	date := func(txt string) time.Time {
+		d, _ := time.Parse("2006-01-02", txt)
+		return d
+	}
+	scheduled := []ports.ScheduledPayment{
+		{Acquirer: "CIELO", Establishment: 1, Summary: 101, Date: date("2021-04-09"), Net: 14550},
+		{Acquirer: "CIELO", Establishment: 1, Summary: 102, Date: date("2021-04-09"), Net: 10000},
+		{Acquirer: "CIELO", Establishment: 1, Summary: 103, Installment: 1, Date: date("2021-04-09"), Net: 5000},
+		{Acquirer: "CIELO", Establishment: 1, Summary: 104, Date: date("2021-04-09"), Net: 2000},
+		{Acquirer: "REDECARD", Establishment: 2, Summary: 201, Installment: 1, Date: date("2021-04-11"), Net: 9700},
+		{Acquirer: "REDECARD", Establishment: 2, Summary: 201, Installment: 2, Date: date("2021-05-11"), Net: 9700},
+		{Acquirer: "REDECARD", Establishment: 2, Summary: 201, Installment: 3, Date: date("2021-06-11"), Net: 9700},
+	}
+	settlements := []ports.Transaction{
+		{Kind: ports.SettlementTransaction, Acquirer: "CIELO", Establishment: 1, Summary: 101, Net: 10000, PaymentDate: date("2021-04-09")},
+		{Kind: ports.SettlementTransaction, Acquirer: "CIELO", Establishment: 1, Summary: 101, Net: 4550, PaymentDate: date("2021-04-09")},
+		{Kind: ports.SettlementTransaction, Acquirer: "CIELO", Establishment: 1, Summary: 102, Net: 10000, PaymentDate: date("2021-04-12")},
+		{Kind: ports.SettlementTransaction, Acquirer: "CIELO", Establishment: 1, Summary: 103, Installment: 1, Net: 4900, PaymentDate: date("2021-04-09")},
+		{Kind: ports.AdjustmentTransaction, Acquirer: "CIELO", Establishment: 1, Summary: 104, Net: 2000, PaymentDate: date("2021-04-09")},
+		{Kind: ports.SettlementTransaction, Acquirer: "CIELO", Establishment: 2, Summary: 104, Net: 2000, PaymentDate: date("2021-04-09")},
+		{Kind: ports.SettlementTransaction, Acquirer: "REDECARD", Establishment: 2, Summary: 201, Net: 9700, PaymentDate: date("2021-05-12")},
+		{Kind: ports.SettlementTransaction, Acquirer: "REDECARD", Establishment: 2, Summary: 201, Net: 9700, PaymentDate: date("2021-04-11")},
+	}
+	reconciliations := Reconcile(scheduled, settlements)
+	assert.Len(t, reconciliations, 7)
+	assert.Equal(t, ports.Reconciliation{Acquirer: "CIELO", Establishment: 1, Summary: 101, ScheduledDate: date("2021-04-09"),
+		Expected: 14550, PaymentDate: date("2021-04-09"), Paid: 14550, Settlements: 2, Status: ports.OnTimeStatus}, reconciliations[0])
+	assert.Equal(t, ports.LateStatus, reconciliations[1].Status)
+	assert.Equal(t, ports.DivergentStatus, reconciliations[2].Status)
+	assert.Equal(t, ports.Money(4900), reconciliations[2].Paid)
+	assert.Equal(t, ports.Reconciliation{Acquirer: "CIELO", Establishment: 1, Summary: 104, ScheduledDate: date("2021-04-09"),
+		Expected: 2000, Status: ports.OpenStatus}, reconciliations[3])
+	assert.Equal(t, ports.OnTimeStatus, reconciliations[4].Status)
+	assert.Equal(t, date("2021-04-11"), reconciliations[4].PaymentDate)
+	assert.Equal(t, ports.LateStatus, reconciliations[5].Status)
+	assert.Equal(t, date("2021-05-12"), reconciliations[5].PaymentDate)
+	assert.Equal(t, ports.OpenStatus, reconciliations[6].Status)
+	assert.Len(t, Reconcile(nil, settlements), 0)
+}
+
+func TestReconcileSales(t *testing.T) {
+	date := func(txt string) time.Time {
+		d, _ := time.Parse("2006-01-02", txt)
+		return d
+	}
+	erp := []ports.ErpSale{
+		{Line: 2, Date: date("2021-03-10"), Amount: 10000, Nsu: 11},
+		{Line: 3, Date: date("2021-03-10"), Amount: 5000, Authorization: "A1B2C3"},
+		{Line: 4, Date: date("2021-03-11"), Amount: 2002, Card: "1234"},
+		{Line: 5, Date: date("2021-03-11"), Amount: 3000},
+		{Line: 6, Date: date("2021-03-11"), Amount: 7000, Nsu: 99},
+	}
+	sales := []ports.Transaction{
+		{Kind: ports.SaleTransaction, Acquirer: "CIELO", Establishment: 1, Nsu: 11, Gross: 10000, SaleDate: date("2021-03-10")},
+		{Kind: ports.SaleTransaction, Acquirer: "CIELO", Establishment: 1, Nsu: 12, Authorization: "a1b2c3", Gross: 4000,
+			SaleDate: date("2021-03-10")},
+		{Kind: ports.SaleTransaction, Acquirer: "CIELO", Establishment: 1, Nsu: 13, Card: "5067******1234", Gross: 2000,
+			SaleDate: date("2021-03-12")},
+		{Kind: ports.SaleTransaction, Acquirer: "REDECARD", Establishment: 2, Nsu: 14, Gross: 3000, SaleDate: date("2021-03-11")},
+		{Kind: ports.SaleTransaction, Acquirer: "REDECARD", Establishment: 2, Nsu: 15, Gross: 8000, SaleDate: date("2021-03-11")},
+		{Kind: ports.SaleTransaction, Acquirer: "REDECARD", Establishment: 2, Nsu: 16, Gross: 8000, SaleDate: date("2021-03-20")},
+		{Kind: ports.SettlementTransaction, Acquirer: "REDECARD", Establishment: 2, Gross: 8000, SaleDate: date("2021-03-11")},
+	}
+	layout := ports.ErpLayout{AmountTolerance: 2, DayTolerance: 1}
+	reconciliations := ReconcileSales(erp, sales, layout)
+	assert.Len(t, reconciliations, 3)
+	assert.Equal(t, ports.SaleReconciliation{Status: ports.DivergentStatus, Line: 3, Acquirer: "CIELO", Establishment: 1,
+		Date: date("2021-03-10"), Authorization: "A1B2C3", ErpAmount: 5000, AcquirerAmount: 4000}, reconciliations[0])
+	assert.Equal(t, ports.SaleReconciliation{Status: ports.ErpOnlyStatus, Line: 6, Date: date("2021-03-11"), Nsu: 99,
+		ErpAmount: 7000}, reconciliations[1])
+	assert.Equal(t, ports.SaleReconciliation{Status: ports.AcquirerOnlyStatus, Acquirer: "REDECARD", Establishment: 2,
+		Date: date("2021-03-11"), Nsu: 15, AcquirerAmount: 8000}, reconciliations[2])
+	// without day tolerance the card sale is a day apart
+	layout.DayTolerance = 0
+	reconciliations = ReconcileSales(erp, sales, layout)
+	assert.Len(t, reconciliations, 4)
+	assert.Equal(t, ports.ErpOnlyStatus, reconciliations[1].Status)
+	assert.Equal(t, 4, reconciliations[1].Line)
+	assert.Len(t, ReconcileSales(nil, sales, layout), 0)
+}
+
+func TestReconcileDeposits(t *testing.T) {
+	date := func(txt string) time.Time {
+		d, _ := time.Parse("2006-01-02", txt)
+		return d
+	}
+	account := ports.BankAccount{Bank: 341, Branch: 1234, Account: 1234567}
+	settlements := map[string][]ports.DailySettlement{
+		"redefinanceiro": {
+			{Date: date("2021-04-09"), Establishment: 2, Account: account, Net: 9700},
+		},
+		"cielofinanceiro": {
+			{Date: date("2021-04-09"), Establishment: 1, Account: account, Net: 14550},
+			{Date: date("2021-04-09"), Establishment: 1, Account: account, Net: 14550},
+			{Date: date("2021-04-10"), Establishment: 1, Account: account, Net: -500},
+			{Date: date("2021-04-12"), Establishment: 1, Account: account, Net: 2000},
+		},
+	}
+	entries := []ports.BankEntry{
+		{File: "a.ret", Line: 2, Date: date("2021-04-09"), Account: ports.BankAccount{Bank: 341, Branch: 1234, Account: 123456},
+			AccountDigit: "7", Amount: 14550, History: "CIELO SA"},
+		{File: "a.ret", Line: 3, Date: date("2021-04-09"), Account: account, Amount: 9700},
+		{File: "a.ret", Line: 4, Date: date("2021-04-10"), Account: account, Amount: 14550},
+		{File: "a.ret", Line: 5, Date: date("2021-04-12"), Account: ports.BankAccount{Bank: 237, Branch: 1234, Account: 1234567},
+			Amount: 2000},
+	}
+	deposits := ReconcileDeposits(settlements, entries, 0)
+	assert.Len(t, deposits, 4)
+	assert.Equal(t, ports.Deposit{Acquirer: "cielofinanceiro", Date: date("2021-04-09"), Establishment: 1, Account: account,
+		Expected: 14550, File: "a.ret", Line: 2, History: "CIELO SA", Status: ports.DepositedStatus}, deposits[0])
+	assert.Equal(t, ports.MissingStatus, deposits[1].Status)
+	assert.Equal(t, "redefinanceiro", deposits[2].Acquirer)
+	assert.Equal(t, ports.DepositedStatus, deposits[2].Status)
+	assert.Equal(t, 3, deposits[2].Line)
+	assert.Equal(t, ports.Deposit{Acquirer: "cielofinanceiro", Date: date("2021-04-12"), Establishment: 1, Account: account,
+		Expected: 2000, Status: ports.MissingStatus}, deposits[3])
+	assert.Len(t, ReconcileDeposits(nil, entries, 0), 0)
+	// entries up to a day apart, the closest one first
+	deposits = ReconcileDeposits(settlements, entries, 1)
+	assert.Equal(t, ports.DepositedStatus, deposits[1].Status)
+	assert.Equal(t, 4, deposits[1].Line)
+	deposits = ReconcileDeposits(map[string][]ports.DailySettlement{"redefinanceiro": {
+		{Date: date("2021-04-10"), Establishment: 2, Account: account, Net: 14550}}}, entries, 1)
+	assert.Equal(t, 4, deposits[0].Line)
+	deposits = ReconcileDeposits(map[string][]ports.DailySettlement{"redefinanceiro": {
+		{Date: date("2021-04-12"), Establishment: 2, Account: account, Net: 14550}}}, entries, 1)
+	assert.Equal(t, ports.MissingStatus, deposits[0].Status)
+}
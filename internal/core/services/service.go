@@ -1,78 +1,513 @@
 package services
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
+	"math"
+	"path/filepath"
+	"regexp"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/lavinas/cielo-edi/internal/core/ports"
 )
 
 const (
-	nameFormat      string = "%s-%010d-%s-%s-%s-%s-%s-L%03d.txt"
+	nameTemplate    string = "{acquirer}-{headquarter}-{statement}-{periodInit}-{periodEnd}-{action}-{processingDate}-L{layoutVersion}.txt"
+	identityFormat  string = "%s-%010d-%s-%s-%s-%07d"
 	printDateFormat string = "2006_01_02"
+	exactKind       string = "Exact"
+	logicalKind     string = "Logical"
+	reasonExtension string = ".reason.json"
+	// scanBuffer is the initial line buffer of the detail records scanner, that grows up to maxLineSize
+	scanBuffer  = 64 * 1024
+	maxLineSize = 16 * 1024 * 1024
 )
 
+// Stages where a file is rejected, reported on HeaderError, FileValidation and the quarantine reasons
+const (
+	ReadStage     string = "read"
+	ParseStage    string = "parse"
+	ValidateStage string = "validate"
+	TrailerStage  string = "trailer"
+	RecordStage   string = "record"
+	TotalsStage   string = "totals"
+)
+
+var (
+	// namePlaceholder matches the placeholders of a name template, like {headquarter}
+	namePlaceholder = regexp.MustCompile(`\{(\w+)\}`)
+	// nameFields has the value of each placeholder of a name template and the expression it matches
+	nameFields = map[string]nameField{
+		"acquirer":       {`[A-Z]+`, func(h ports.HeaderDataInterface) string { return h.GetAcquirer() }},
+		"headquarter":    {`\d{10}`, func(h ports.HeaderDataInterface) string { return fmt.Sprintf("%010d", h.GetHeadquarter()) }},
+		"statement":      {`\w+`, func(h ports.HeaderDataInterface) string { return h.GetStatementId() }},
+		"periodInit":     {`\d{4}_\d{2}_\d{2}`, func(h ports.HeaderDataInterface) string { return h.GetPeriodInit().Format(printDateFormat) }},
+		"periodEnd":      {`\d{4}_\d{2}_\d{2}`, func(h ports.HeaderDataInterface) string { return h.GetPeriodEnd().Format(printDateFormat) }},
+		"processingDate": {`\d{4}_\d{2}_\d{2}`, func(h ports.HeaderDataInterface) string { return h.GetProcessingDate().Format(printDateFormat) }},
+		"action":         {`[NR]`, getAction},
+		"sequence":       {`\d{7}`, func(h ports.HeaderDataInterface) string { return fmt.Sprintf("%07d", h.GetSequence()) }},
+		"layoutVersion":  {`\d{3}`, func(h ports.HeaderDataInterface) string { return fmt.Sprintf("%03d", h.GetLayoutVersion()) }},
+	}
+)
+
+// nameField is a placeholder of a name template
+type nameField struct {
+	pattern string
+	value   func(ports.HeaderDataInterface) string
+}
+
+func getAction(h ports.HeaderDataInterface) string {
+	if h.IsReprocessed() {
+		return "R"
+	}
+	return "N"
+}
+
+// compileNameTemplate returns the expression that matches the names produced with a name template
+func compileNameTemplate(template string) (*regexp.Regexp, error) {
+	if !namePlaceholder.MatchString(template) {
+		return nil, fmt.Errorf("name template %s has no placeholder", template)
+	}
+	expr := "^"
+	last := 0
+	for _, match := range namePlaceholder.FindAllStringSubmatchIndex(template, -1) {
+		field, ok := nameFields[template[match[2]:match[3]]]
+		if !ok {
+			return nil, fmt.Errorf("name template placeholder %s not found", template[match[0]:match[1]])
+		}
+		expr += regexp.QuoteMeta(template[last:match[0]]) + field.pattern
+		last = match[1]
+	}
+	expr += regexp.QuoteMeta(template[last:]) + "$"
+	return regexp.Compile(expr)
+}
+
+// HeaderError describes the stage (read, parse, validate, trailer, record or totals) where a file was rejected.
+// Field has the header field that could not be parsed, if there is one
+type HeaderError struct {
+	Stage string
+	Field string
+	Err   error
+}
+
+// Error returns the stage of the failure. The stages of the trailer, records and totals are followed by the
+// detail (missing trailer, count or totals mismatch), as they are read on complete files whose header was valid
+func (e HeaderError) Error() string {
+	switch e.Stage {
+	case ParseStage:
+		return "error parsing"
+	case ValidateStage:
+		return "invalid file"
+	case TrailerStage:
+		return e.withDetail("truncated file")
+	case RecordStage:
+		return e.withDetail("invalid record")
+	case TotalsStage:
+		return e.withDetail("totals mismatch")
+	}
+	return e.Detail()
+}
+
+// withDetail appends the detail to the message of a stage, if there is one
+func (e HeaderError) withDetail(message string) string {
+	if detail := e.Detail(); detail != "" {
+		return message + ": " + detail
+	}
+	return message
+}
+
+// Detail returns the original error message
+func (e HeaderError) Detail() string {
+	if e.Err == nil {
+		return ""
+	}
+	return e.Err.Error()
+}
+
+// QuarantineReason is the content of the .reason.json sidecar written next to a quarantined file
+type QuarantineReason struct {
+	File    string    `json:"file"`
+	Stage   string    `json:"stage,omitempty"`
+	Field   string    `json:"field,omitempty"`
+	Error   string    `json:"error"`
+	Detail  string    `json:"detail,omitempty"`
+	Layouts []string  `json:"layouts"`
+	Date    time.Time `json:"date"`
+}
+
 type Service struct {
-	fileManager ports.FileManagerInterface
-	header      ports.HeaderInterface
+	fileManager   ports.FileManagerInterface
+	header        ports.HeaderInterface
+	trailer       ports.TrailerInterface
+	detail        ports.DetailInterface
+	verifyTrailer bool
+	nameTemplate  string
+	nameRegexp    *regexp.Regexp
+	calendar      ports.CalendarInterface
 }
 
 func NewService(fileManager ports.FileManagerInterface, header ports.HeaderInterface) *Service {
-	return &Service{fileManager: fileManager, header: header}
+	nameRegexp, _ := compileNameTemplate(nameTemplate)
+	return &Service{fileManager: fileManager, header: header, verifyTrailer: true, nameTemplate: nameTemplate, nameRegexp: nameRegexp}
+}
+
+// SetNameTemplate sets the template of the names given by FormatNames. Placeholders are
+// {acquirer}, {headquarter}, {statement}, {periodInit}, {periodEnd}, {processingDate},
+// {action}, {sequence} and {layoutVersion}
+func (s *Service) SetNameTemplate(template string) error {
+	nameRegexp, err := compileNameTemplate(template)
+	if err != nil {
+		return err
+	}
+	s.nameTemplate = template
+	s.nameRegexp = nameRegexp
+	return nil
+}
+
+// SetCalendar sets the days files are expected. Days out of the calendar are never gaps
+func (s *Service) SetCalendar(calendar ports.CalendarInterface) {
+	s.calendar = calendar
+}
+
+// SetTrailer sets the trailer layout used to check that files are complete
+func (s *Service) SetTrailer(trailer ports.TrailerInterface) {
+	s.trailer = trailer
+}
+
+// SetVerifyTrailer sets whether GetHeaderData rejects truncated files, so they are neither renamed nor counted
+// on periods and gaps. It is on by default; turning it off reads only the header line of each file
+func (s *Service) SetVerifyTrailer(verify bool) {
+	s.verifyTrailer = verify
+}
+
+// SetDetail sets the layout of the detail records of the files
+func (s *Service) SetDetail(detail ports.DetailInterface) {
+	s.detail = detail
+}
+
+// SetEncoding sets the encoding the files are decoded from before their lines are parsed
+func (s *Service) SetEncoding(encoding string) error {
+	return s.fileManager.SetEncoding(encoding)
 }
 
 func (s Service) GetHeaderData(path string, file fs.FileInfo) (ports.HeaderDataInterface, error) {
-	date, err := s.fileManager.GetFirstLine(path, file)
+	d, err := s.getHeaderData(path, file)
 	if err != nil {
 		return nil, err
 	}
+	if s.verifyTrailer && s.trailer != nil {
+		if _, _, err := s.validateTrailer(path, file); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func (s Service) getHeaderData(path string, file fs.FileInfo) (ports.HeaderDataInterface, error) {
+	date, err := s.fileManager.GetFirstLine(path, file)
+	if err != nil {
+		return nil, &HeaderError{Stage: ReadStage, Err: err}
+	}
 	if err := s.header.Parse(date); err != nil {
-		return nil, fmt.Errorf("error parsing")
+		herr := &HeaderError{Stage: ParseStage, Err: err}
+		var ferr *ports.FieldError
+		if errors.As(err, &ferr) {
+			herr.Field = ferr.Field
+		}
+		return nil, herr
 	}
-	if !s.header.IsValid() {
-		return nil, fmt.Errorf("invalid file")
+	if err := s.header.Validate(); err != nil {
+		return nil, &HeaderError{Stage: ValidateStage, Err: err}
 	}
 	d := s.header.GetData()
 	return d, nil
 }
 
-func (s Service) FormatNames(path string) ([]string, error) {
-	logger := make([]string, 0)
+// validateTrailer reads a file to the end and checks that its last record is a trailer that declares
+// the number of records read
+//
+// returns the number of records read and declared and a HeaderError if the file is truncated
+func (s Service) validateTrailer(path string, file fs.FileInfo) (int, int, error) {
+	last, err := s.fileManager.GetLastLine(path, file)
+	if err != nil {
+		return 0, 0, &HeaderError{Stage: ReadStage, Err: err}
+	}
+	if err := s.trailer.Parse(last); err != nil {
+		return 0, 0, &HeaderError{Stage: TrailerStage, Err: fmt.Errorf("trailer not found (%v)", err)}
+	}
+	if err := s.trailer.GetData().Validate(); err != nil {
+		return 0, 0, &HeaderError{Stage: TrailerStage, Err: err}
+	}
+	records, err := s.fileManager.CountLines(path, file)
+	if err != nil {
+		return 0, 0, &HeaderError{Stage: ReadStage, Err: err}
+	}
+	declared := s.trailer.GetData().GetRecordCount()
+	if err := s.trailer.Validate(records); err != nil {
+		return records, declared, &HeaderError{Stage: TrailerStage, Err: err}
+	}
+	return records, declared, nil
+}
+
+// ValidateFiles checks that the files of the path accepted by the header layout are complete
+func (s Service) ValidateFiles(path string) ([]ports.FileValidation, error) {
+	validations := make([]ports.FileValidation, 0)
+	if s.trailer == nil {
+		return validations, fmt.Errorf("layout %s has no trailer", strings.Join(s.layoutNames(), ","))
+	}
 	files, err := s.fileManager.GetFiles(path)
 	if err != nil {
-		return []string{}, err
+		return validations, err
 	}
 	for _, file := range files {
-		h, err := s.GetHeaderData(path, file)
-		if err != nil {
-			logger = append(logger, fmt.Sprintf("No: %s - %v", file.Name(), err))
+		if file.IsDir() {
+			continue
+		}
+		validation := ports.FileValidation{File: file.Name()}
+		var herr *HeaderError
+		if _, err := s.getHeaderData(path, file); err != nil {
+			if errors.As(err, &herr) {
+				validation.Stage = herr.Stage
+			}
+			validation.Error = err.Error()
+			validations = append(validations, validation)
 			continue
 		}
-		act := "N"
-		if h.IsReprocessed() {
-			act = "R"
+		validation.Records, validation.DeclaredRecords, err = s.validateTrailer(path, file)
+		if err == nil && s.detail != nil {
+			err = s.validateRecords(path, file)
 		}
-		newName := fmt.Sprintf(nameFormat, h.GetAcquirer(), h.GetHeadquarter(), h.GetStatementId(),
-			h.GetPeriodInit().Format(printDateFormat), h.GetPeriodEnd().Format(printDateFormat), act,
-			h.GetProcessingDate().Format(printDateFormat), h.GetLayoutVersion())
-		err = s.fileManager.RenameFile(path, file.Name(), newName)
+		switch {
+		case err == nil:
+			validation.Valid = true
+		case errors.As(err, &herr):
+			validation.Stage, validation.Error = herr.Stage, herr.Detail()
+		default:
+			validation.Error = err.Error()
+		}
+		validations = append(validations, validation)
+	}
+	return validations, nil
+}
+
+// validateRecords parses the detail records of a file and checks them against the totals of its trailer
+func (s Service) validateRecords(path string, file fs.FileInfo) error {
+	records, err := s.GetRecords(path, file)
+	if err != nil {
+		var herr *HeaderError
+		if errors.As(err, &herr) {
+			return herr
+		}
+		return &HeaderError{Stage: RecordStage, Err: err}
+	}
+	if err := s.detail.Validate(records); err != nil {
+		return &HeaderError{Stage: TotalsStage, Err: err}
+	}
+	return nil
+}
+
+// GetRecords parses the detail records of a file with the layout version of its header. Lines of
+// record types the layout does not describe, like the header, are skipped
+func (s Service) GetRecords(path string, file fs.FileInfo) ([]ports.RecordInterface, error) {
+	if s.detail == nil {
+		return nil, fmt.Errorf("layout %s has no detail records", strings.Join(s.layoutNames(), ","))
+	}
+	data, err := s.getHeaderData(path, file)
+	if err != nil {
+		return nil, err
+	}
+	version := data.GetLayoutVersion()
+	reader, err := s.fileManager.OpenReader(path, file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, scanBuffer), maxLineSize)
+	records := make([]ports.RecordInterface, 0)
+	for line := 1; scanner.Scan(); line++ {
+		txt := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(txt) == "" {
+			continue
+		}
+		record, err := s.detail.Parse(txt, version)
 		if err != nil {
-			logger = append(logger, fmt.Sprintf("No: %s - %v", file.Name(), err))
+			return nil, fmt.Errorf("%s line %d: %v", file.Name(), line, err)
+		}
+		if record != nil {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Inspect parses the header of a file field by field with the layout of the service and tells
+// the stage and the reason the layout rejects it
+func (s Service) Inspect(path string, file fs.FileInfo) (ports.LayoutInspection, error) {
+	inspection := ports.LayoutInspection{Layout: strings.Join(s.layoutNames(), ","), Fields: []ports.FieldInspection{}}
+	line, err := s.fileManager.GetFirstLine(path, file)
+	if err != nil {
+		return inspection, err
+	}
+	inspection.Fields = s.header.Inspect(line)
+	if err := s.header.Parse(line); err != nil {
+		inspection.Stage, inspection.Error = ParseStage, err.Error()
+		return inspection, nil
+	}
+	if err := s.header.Validate(); err != nil {
+		inspection.Stage, inspection.Error = ValidateStage, err.Error()
+		return inspection, nil
+	}
+	inspection.Valid = true
+	return inspection, nil
+}
+
+func (s Service) FormatNames(path string) ([]ports.RenameResult, error) {
+	return s.FormatNamesQuarantine(path, "")
+}
+
+// FormatNamesQuarantine renames all valid files of the path and, when quarantine is not empty,
+// moves the files that fail GetHeaderData to quarantine with a .reason.json sidecar
+func (s Service) FormatNamesQuarantine(path string, quarantine string) ([]ports.RenameResult, error) {
+	results := make([]ports.RenameResult, 0)
+	files, err := s.fileManager.GetFiles(path)
+	if err != nil {
+		return results, err
+	}
+	for _, file := range files {
+		results = append(results, s.FormatFile(path, file, quarantine))
+	}
+	return results, nil
+}
+
+// FormatFile renames a single file of the path (see FormatNamesQuarantine)
+func (s Service) FormatFile(path string, file fs.FileInfo, quarantine string) ports.RenameResult {
+	h, err := s.GetHeaderData(path, file)
+	if err != nil {
+		if quarantine != "" && !file.IsDir() {
+			return s.quarantineFile(path, file.Name(), quarantine, err)
+		}
+		return ports.RenameResult{File: file.Name(), Status: ports.FailedStatus, Reason: err.Error()}
+	}
+	newName := s.formatName(h)
+	err = s.fileManager.RenameFile(path, file.Name(), newName)
+	if err != nil {
+		return ports.RenameResult{File: file.Name(), Status: ports.FailedStatus, Reason: err.Error()}
+	}
+	return ports.RenameResult{File: file.Name(), NewName: newName, Status: ports.RenamedStatus}
+}
+
+// IsFormattedName checks if a file name was already given by FormatNames
+func (s Service) IsFormattedName(name string) bool {
+	return s.nameRegexp.MatchString(name)
+}
+
+func (s Service) formatName(h ports.HeaderDataInterface) string {
+	return namePlaceholder.ReplaceAllStringFunc(s.nameTemplate, func(placeholder string) string {
+		return nameFields[placeholder[1:len(placeholder)-1]].value(h)
+	})
+}
+
+func (s Service) quarantineFile(path string, name string, quarantine string, cause error) ports.RenameResult {
+	result := ports.RenameResult{File: name, Status: ports.FailedStatus}
+	reason := QuarantineReason{File: name, Error: cause.Error(), Layouts: s.layoutNames(), Date: time.Now()}
+	if herr, ok := cause.(*HeaderError); ok {
+		reason.Stage = herr.Stage
+		reason.Field = herr.Field
+		reason.Detail = herr.Detail()
+	}
+	data, err := json.MarshalIndent(reason, "", "  ")
+	if err != nil {
+		result.Reason = fmt.Sprintf("%v (quarantine: %v)", cause, err)
+		return result
+	}
+	if err := s.fileManager.MoveFile(path, name, quarantine); err != nil {
+		result.Reason = fmt.Sprintf("%v (quarantine: %v)", cause, err)
+		return result
+	}
+	if err := s.fileManager.WriteFile(quarantine, name+reasonExtension, data); err != nil {
+		result.Reason = fmt.Sprintf("%v (quarantine: %v)", cause, err)
+		return result
+	}
+	return ports.RenameResult{File: name, NewName: filepath.Join(quarantine, name), Status: ports.QuarantinedStatus, Reason: cause.Error()}
+}
+
+func (s Service) layoutNames() []string {
+	layouts := make([]string, 0)
+	if d := s.header.GetData(); d != nil {
+		layouts = append(layouts, d.GetLayoutName())
+	}
+	return layouts
+}
+
+// Requeue moves back to path the quarantined files that are now accepted by the header layout
+// and removes their .reason.json sidecars. Files that still fail are kept in quarantine.
+// A sidecar that could not be removed is reported on the reason of the requeued file
+func (s Service) Requeue(path string, quarantine string) ([]ports.RenameResult, error) {
+	results := make([]ports.RenameResult, 0)
+	files, err := s.fileManager.GetFiles(quarantine)
+	if err != nil {
+		return results, err
+	}
+	for _, file := range files {
+		if file.IsDir() || strings.HasSuffix(file.Name(), reasonExtension) {
+			continue
+		}
+		if _, err := s.GetHeaderData(quarantine, file); err != nil {
+			results = append(results, ports.RenameResult{File: file.Name(), Status: ports.FailedStatus, Reason: err.Error()})
 			continue
 		}
-		logger = append(logger, fmt.Sprintf("Yes: %s - %s", file.Name(), newName))
+		if err := s.fileManager.MoveFile(quarantine, file.Name(), path); err != nil {
+			results = append(results, ports.RenameResult{File: file.Name(), Status: ports.FailedStatus, Reason: err.Error()})
+			continue
+		}
+		result := ports.RenameResult{File: file.Name(), NewName: filepath.Join(path, file.Name()), Status: ports.RequeuedStatus}
+		if err := s.fileManager.RemoveFile(quarantine, file.Name()+reasonExtension); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			result.Reason = fmt.Sprintf("reason file not removed: %v", err)
+		}
+		results = append(results, result)
 	}
-	return logger, nil
+	return results, nil
 }
 
 func (s Service) GetPeriodMap(path string) (map[time.Time]int, error) {
+	return s.GetHeadquarterPeriodMap(path, 0)
+}
+
+// GetHeadquarterPeriodMap counts the files of each date of the path that belongs to a headquarter (EC).
+// A zero headquarter counts the files of all headquarters
+func (s Service) GetHeadquarterPeriodMap(path string, headquarter int64) (map[time.Time]int, error) {
 	dMap := make(map[time.Time]int)
-	files, err := s.fileManager.GetFiles(path)
+	hqMap, err := s.getHeadquarterPeriodMaps(path)
 	if err != nil {
 		return dMap, err
 	}
+	for hq, hqDates := range hqMap {
+		if headquarter != 0 && hq != headquarter {
+			continue
+		}
+		for d, count := range hqDates {
+			dMap[d] += count
+		}
+	}
+	return dMap, nil
+}
+
+// getHeadquarterPeriodMaps counts the files of each date of the path by headquarter
+func (s Service) getHeadquarterPeriodMaps(path string) (map[int64]map[time.Time]int, error) {
+	hqMap := make(map[int64]map[time.Time]int)
+	files, err := s.fileManager.GetFiles(path)
+	if err != nil {
+		return hqMap, err
+	}
 	for _, f := range files {
 		hData, err := s.GetHeaderData(path, f)
 		if err != nil {
@@ -82,19 +517,24 @@ func (s Service) GetPeriodMap(path string) (map[time.Time]int, error) {
 		if err != nil {
 			continue
 		}
+		dMap, ok := hqMap[hData.GetHeadquarter()]
+		if !ok {
+			dMap = make(map[time.Time]int)
+			hqMap[hData.GetHeadquarter()] = dMap
+		}
 		for _, d := range ds {
-			if val, ok := dMap[d]; ok {
-				dMap[d] = val + 1
-			} else {
-				dMap[d] = 1
-			}
+			dMap[d]++
 		}
 	}
-	return dMap, err
+	return hqMap, nil
 }
 
 func (s Service) GetPeriod(path string) ([]time.Time, error) {
-	dMap, err := s.GetPeriodMap(path)
+	return s.GetHeadquarterPeriod(path, 0)
+}
+
+func (s Service) GetHeadquarterPeriod(path string, headquarter int64) ([]time.Time, error) {
+	dMap, err := s.GetHeadquarterPeriodMap(path, headquarter)
 	if err != nil {
 		return make([]time.Time, 0), err
 	}
@@ -107,6 +547,23 @@ func (s Service) GetPeriod(path string) ([]time.Time, error) {
 }
 
 func (s Service) GetGap(path string, initDate time.Time, endDate time.Time) ([]time.Time, error) {
+	return s.GetHeadquarterGap(path, 0, initDate, endDate)
+}
+
+func (s Service) GetHeadquarterGap(path string, headquarter int64, initDate time.Time, endDate time.Time) ([]time.Time, error) {
+	searchPeriod, err := s.getSearchPeriod(initDate, endDate)
+	if err != nil {
+		return searchPeriod, err
+	}
+	mdMap, err := s.GetHeadquarterPeriodMap(path, headquarter)
+	if err != nil {
+		return make([]time.Time, 0), err
+	}
+	return getMissingDates(searchPeriod, mdMap), nil
+}
+
+// getSearchPeriod returns the days between initDate and endDate that files are expected on the calendar
+func (s Service) getSearchPeriod(initDate time.Time, endDate time.Time) ([]time.Time, error) {
 	searchPeriod := make([]time.Time, 0)
 	if initDate.Equal(time.Time{}) || endDate.Equal(time.Time{}) {
 		return searchPeriod, fmt.Errorf("period is empty")
@@ -115,33 +572,35 @@ func (s Service) GetGap(path string, initDate time.Time, endDate time.Time) ([]t
 		return searchPeriod, fmt.Errorf("initDate after endDate")
 	}
 	for t := initDate; !t.After(endDate); t = t.Add(24 * time.Hour) {
+		if s.calendar != nil && !s.calendar.IsExpected(t) {
+			continue
+		}
 		searchPeriod = append(searchPeriod, t)
 	}
-	mdMap, err := s.GetPeriodMap(path)
-	if err != nil {
-		return make([]time.Time, 0), err
-	}
+	return searchPeriod, nil
+}
+
+func getMissingDates(searchPeriod []time.Time, dMap map[time.Time]int) []time.Time {
 	gaps := make([]time.Time, 0)
 	for _, d := range searchPeriod {
-		if _, ok := mdMap[d]; !ok {
+		if _, ok := dMap[d]; !ok {
 			gaps = append(gaps, d)
 		}
 	}
-	return gaps, nil
+	return gaps
 }
 
-func (s Service) GetGrouped(dates []time.Time) []string {
+func (s Service) GetGrouped(dates []time.Time) []ports.DateRange {
 	pInit := time.Time{}
 	pEnd := time.Time{}
-	ret := make([]string, 0)
+	ret := make([]ports.DateRange, 0)
 	for _, date := range dates {
 		if pInit.Equal(time.Time{}) {
 			pInit = date
 			pEnd = date
 		}
 		if date.After(pEnd.Add(24 * time.Hour)) {
-			strRet := fmt.Sprintf("%s - %s", pInit.Format("02/01/2006"), pEnd.Format("02/01/2006"))
-			ret = append(ret, strRet)
+			ret = append(ret, ports.DateRange{Start: pInit, End: pEnd})
 			pInit = date
 			pEnd = date
 		} else {
@@ -149,24 +608,538 @@ func (s Service) GetGrouped(dates []time.Time) []string {
 		}
 	}
 	if !pInit.Equal(time.Time{}) {
-		strRet := fmt.Sprintf("%s - %s", pInit.Format("02/01/2006"), pEnd.Format("02/01/2006"))
-		ret = append(ret, strRet)
+		ret = append(ret, ports.DateRange{Start: pInit, End: pEnd})
 	}
 	return ret
 }
 
-func (s Service) GetGapGrouped(path string, initDate time.Time, endDate time.Time) ([]string, error) {
-	dates, err := s.GetGap(path, initDate, endDate)
+func (s Service) GetGapGrouped(path string, initDate time.Time, endDate time.Time) ([]ports.DateRange, error) {
+	return s.GetHeadquarterGapGrouped(path, 0, initDate, endDate)
+}
+
+func (s Service) GetHeadquarterGapGrouped(path string, headquarter int64, initDate time.Time, endDate time.Time) ([]ports.DateRange, error) {
+	dates, err := s.GetHeadquarterGap(path, headquarter, initDate, endDate)
 	if err != nil {
-		return []string{}, err
+		return []ports.DateRange{}, err
 	}
 	return s.GetGrouped(dates), nil
 }
 
-func (s Service) GetPeriodGrouped(path string) ([]string, error) {
-	dates, err := s.GetPeriod(path)
+// GetGapSummary returns the missing days between initDate and endDate of each headquarter (EC).
+// Without headquarters, the headquarters of the files of the path are summarized
+func (s Service) GetGapSummary(path string, headquarters []int64, initDate time.Time, endDate time.Time) ([]ports.GapSummary, error) {
+	summaries := make([]ports.GapSummary, 0)
+	searchPeriod, err := s.getSearchPeriod(initDate, endDate)
+	if err != nil {
+		return summaries, err
+	}
+	hqMap, err := s.getHeadquarterPeriodMaps(path)
 	if err != nil {
-		return []string{}, err
+		return summaries, err
+	}
+	if len(headquarters) == 0 {
+		for hq := range hqMap {
+			headquarters = append(headquarters, hq)
+		}
+		sort.Slice(headquarters, func(i, j int) bool { return headquarters[i] < headquarters[j] })
+	}
+	if len(headquarters) == 0 {
+		headquarters = []int64{0}
+	}
+	statement := strings.Join(s.layoutNames(), ",")
+	for _, hq := range headquarters {
+		missing := getMissingDates(searchPeriod, hqMap[hq])
+		gaps := s.GetGrouped(missing)
+		summary := ports.GapSummary{Statement: statement, Headquarter: hq, MissingDays: len(missing), Gaps: gaps}
+		if len(gaps) > 0 {
+			summary.OldestGap = &gaps[0]
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+func (s Service) GetPeriodGrouped(path string) ([]ports.DateRange, error) {
+	return s.GetHeadquarterPeriodGrouped(path, 0)
+}
+
+func (s Service) GetHeadquarterPeriodGrouped(path string, headquarter int64) ([]ports.DateRange, error) {
+	dates, err := s.GetHeadquarterPeriod(path, headquarter)
+	if err != nil {
+		return []ports.DateRange{}, err
 	}
 	return s.GetGrouped(dates), nil
 }
+
+// getPathRecords returns the detail records of the files of a path accepted by the header layout.
+// Files of other layouts are skipped and records that cannot be parsed are errors
+func (s Service) getPathRecords(path string) ([]ports.RecordInterface, error) {
+	records := make([]ports.RecordInterface, 0)
+	files, err := s.fileManager.GetFiles(path)
+	if err != nil {
+		return records, err
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		if _, err := s.GetHeaderData(path, file); err != nil {
+			continue
+		}
+		r, err := s.GetRecords(path, file)
+		if err != nil {
+			return records, err
+		}
+		records = append(records, r...)
+	}
+	return records, nil
+}
+
+// GetDailySettlements returns the net amount settled on each date, establishment and bank account by the
+// files of a path, sorted by date, establishment and account, so it can be matched against bank deposits
+func (s Service) GetDailySettlements(path string) ([]ports.DailySettlement, error) {
+	records, err := s.getPathRecords(path)
+	if err != nil {
+		return nil, err
+	}
+	type settlementKey struct {
+		date          time.Time
+		establishment int64
+		account       ports.BankAccount
+	}
+	settlementMap := make(map[settlementKey]*ports.DailySettlement)
+	for _, record := range records {
+		r, ok := record.(ports.SettlementInterface)
+		if !ok {
+			continue
+		}
+		key := settlementKey{date: r.GetPaymentDate(), establishment: r.GetEstablishment(), account: r.GetBankAccount()}
+		settlement, ok := settlementMap[key]
+		if !ok {
+			settlement = &ports.DailySettlement{Date: key.date, Establishment: key.establishment, Account: key.account}
+			settlementMap[key] = settlement
+		}
+		if amount := r.GetSettledAmount(); amount < 0 {
+			settlement.Debits -= amount
+		} else {
+			settlement.Credits += amount
+		}
+		settlement.Net += r.GetSettledAmount()
+		settlement.Records++
+	}
+	settlements := make([]ports.DailySettlement, 0, len(settlementMap))
+	for _, settlement := range settlementMap {
+		settlement.Negative = settlement.Net < 0
+		settlements = append(settlements, *settlement)
+	}
+	sort.Slice(settlements, func(i, j int) bool {
+		a, b := settlements[i], settlements[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		if a.Establishment != b.Establishment {
+			return a.Establishment < b.Establishment
+		}
+		return a.Account.String() < b.Account.String()
+	})
+	return settlements, nil
+}
+
+// GetSalesTotals returns the sales of each date and card brand of the files of a path, sorted by date and brand,
+// so sales can be reconciled whatever the statement (credit or debit)
+func (s Service) GetSalesTotals(path string) ([]ports.SalesTotal, error) {
+	records, err := s.getPathRecords(path)
+	if err != nil {
+		return nil, err
+	}
+	type totalKey struct {
+		date  time.Time
+		brand string
+	}
+	totalMap := make(map[totalKey]*ports.SalesTotal)
+	for _, record := range records {
+		r, ok := record.(ports.SalesSummaryInterface)
+		if !ok {
+			continue
+		}
+		key := totalKey{date: r.GetSalesDate(), brand: strings.TrimSpace(r.GetBrand())}
+		total, ok := totalMap[key]
+		if !ok {
+			total = &ports.SalesTotal{Date: key.date, Brand: key.brand}
+			totalMap[key] = total
+		}
+		total.Sales += r.GetSaleCount()
+		total.Gross += r.GetGrossAmount()
+		total.Discount += r.GetDiscountAmount()
+		total.Net += r.GetNetAmount()
+	}
+	totals := make([]ports.SalesTotal, 0, len(totalMap))
+	for _, total := range totalMap {
+		totals = append(totals, *total)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if !totals[i].Date.Equal(totals[j].Date) {
+			return totals[i].Date.Before(totals[j].Date)
+		}
+		return totals[i].Brand < totals[j].Brand
+	})
+	return totals, nil
+}
+
+// GetAnticipationCosts returns the effective rate of each anticipation operation of the files of a path, sorted
+// by date and operation. The monthly rate is the compound rate that discounts the gross amount to the net amount
+// in the average days anticipated (30 days months) and the annual rate is its 12 months compound
+func (s Service) GetAnticipationCosts(path string) ([]ports.AnticipationCost, error) {
+	records, err := s.getPathRecords(path)
+	if err != nil {
+		return nil, err
+	}
+	operations := make([]ports.AnticipationInterface, 0)
+	anticipated := make(map[int64][]ports.AnticipatedInterface)
+	for _, record := range records {
+		switch r := record.(type) {
+		case ports.AnticipationInterface:
+			operations = append(operations, r)
+		case ports.AnticipatedInterface:
+			anticipated[r.GetOperation()] = append(anticipated[r.GetOperation()], r)
+		}
+	}
+	costs := make([]ports.AnticipationCost, 0, len(operations))
+	for _, o := range operations {
+		cost := ports.AnticipationCost{Operation: o.GetOperation(), Establishment: o.GetEstablishment(), Date: o.GetCreditDate(),
+			Gross: o.GetGrossAmount(), Net: o.GetNetAmount(), Fee: o.GetGrossAmount() - o.GetNetAmount()}
+		var weighted float64
+		var gross ports.Money
+		for _, a := range anticipated[o.GetOperation()] {
+			weighted += float64(a.GetGrossAmount()) * a.GetOriginalDate().Sub(cost.Date).Hours() / 24
+			gross += a.GetGrossAmount()
+		}
+		if gross > 0 {
+			cost.Days = weighted / float64(gross)
+		}
+		if cost.Days > 0 && cost.Net > 0 && cost.Gross > 0 {
+			cost.MonthlyRate = math.Pow(float64(cost.Gross)/float64(cost.Net), 30/cost.Days) - 1
+			cost.AnnualRate = math.Pow(1+cost.MonthlyRate, 12) - 1
+		}
+		costs = append(costs, cost)
+	}
+	sort.Slice(costs, func(i, j int) bool {
+		if !costs[i].Date.Equal(costs[j].Date) {
+			return costs[i].Date.Before(costs[j].Date)
+		}
+		return costs[i].Operation < costs[j].Operation
+	})
+	return costs, nil
+}
+
+// GetOutstandingReceivables returns the receivables still to be paid per establishment, card brand and due date,
+// sorted by establishment, brand and date. Each open balance file has the whole balance of its headquarter, so only
+// the latest file of each headquarter is read
+func (s Service) GetOutstandingReceivables(path string) ([]ports.OutstandingReceivable, error) {
+	files, err := s.fileManager.GetFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[int64]fs.FileInfo)
+	dates := make(map[int64]time.Time)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		data, err := s.GetHeaderData(path, file)
+		if err != nil {
+			continue
+		}
+		headquarter := data.GetHeadquarter()
+		if date, ok := dates[headquarter]; !ok || data.GetPeriodEnd().After(date) {
+			latest[headquarter], dates[headquarter] = file, data.GetPeriodEnd()
+		}
+	}
+	type receivableKey struct {
+		establishment int64
+		brand         string
+		date          time.Time
+	}
+	receivableMap := make(map[receivableKey]*ports.OutstandingReceivable)
+	for _, file := range latest {
+		records, err := s.GetRecords(path, file)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			r, ok := record.(ports.ReceivableInterface)
+			if !ok {
+				continue
+			}
+			key := receivableKey{establishment: r.GetEstablishment(), brand: strings.TrimSpace(r.GetBrand()), date: r.GetDueDate()}
+			receivable, ok := receivableMap[key]
+			if !ok {
+				receivable = &ports.OutstandingReceivable{Establishment: key.establishment, Brand: key.brand, DueDate: key.date}
+				receivableMap[key] = receivable
+			}
+			receivable.Receivables += r.GetReceivableCount()
+			receivable.Gross += r.GetGrossAmount()
+			receivable.Net += r.GetNetAmount()
+		}
+	}
+	receivables := make([]ports.OutstandingReceivable, 0, len(receivableMap))
+	for _, receivable := range receivableMap {
+		receivables = append(receivables, *receivable)
+	}
+	sort.Slice(receivables, func(i, j int) bool {
+		a, b := receivables[i], receivables[j]
+		if a.Establishment != b.Establishment {
+			return a.Establishment < b.Establishment
+		}
+		if a.Brand != b.Brand {
+			return a.Brand < b.Brand
+		}
+		return a.DueDate.Before(b.DueDate)
+	})
+	return receivables, nil
+}
+
+// GetTransactions returns the detail records of the files of a path mapped to the canonical transaction schema, in
+// the order of the files and records. The acquirer of each transaction is given by the header of its file and records
+// that are not transactions, like summaries and trailers, are skipped
+func (s Service) GetTransactions(path string) ([]ports.Transaction, error) {
+	transactions := make([]ports.Transaction, 0)
+	files, err := s.fileManager.GetFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		data, err := s.GetHeaderData(path, file)
+		if err != nil {
+			continue
+		}
+		records, err := s.GetRecords(path, file)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			r, ok := record.(ports.TransactionInterface)
+			if !ok {
+				continue
+			}
+			transaction := r.GetTransaction()
+			transaction.Acquirer = data.GetAcquirer()
+			transactions = append(transactions, transaction)
+		}
+	}
+	return transactions, nil
+}
+
+// GetScheduledPayments returns the payments scheduled by the sales statements of a path, sorted by date, establishment,
+// summary and installment. Summaries split in installments are scheduled by their installments
+func (s Service) GetScheduledPayments(path string) ([]ports.ScheduledPayment, error) {
+	payments := make([]ports.ScheduledPayment, 0)
+	files, err := s.fileManager.GetFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	type summaryKey struct {
+		establishment int64
+		summary       int64
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		data, err := s.GetHeaderData(path, file)
+		if err != nil {
+			continue
+		}
+		records, err := s.GetRecords(path, file)
+		if err != nil {
+			return nil, err
+		}
+		scheduled := make([]ports.ScheduledPaymentInterface, 0)
+		installments := make(map[summaryKey]bool)
+		for _, record := range records {
+			r, ok := record.(ports.ScheduledPaymentInterface)
+			if !ok {
+				continue
+			}
+			scheduled = append(scheduled, r)
+			if r.GetInstallment() > 0 {
+				installments[summaryKey{establishment: r.GetEstablishment(), summary: r.GetSummaryNumber()}] = true
+			}
+		}
+		for _, r := range scheduled {
+			if r.GetInstallment() == 0 && installments[summaryKey{establishment: r.GetEstablishment(), summary: r.GetSummaryNumber()}] {
+				continue
+			}
+			payments = append(payments, ports.ScheduledPayment{Acquirer: data.GetAcquirer(), Establishment: r.GetEstablishment(),
+				Summary: r.GetSummaryNumber(), Installment: r.GetInstallment(), Date: r.GetScheduledDate(), Net: r.GetNetAmount()})
+		}
+	}
+	sort.SliceStable(payments, func(i, j int) bool {
+		a, b := payments[i], payments[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		if a.Establishment != b.Establishment {
+			return a.Establishment < b.Establishment
+		}
+		if a.Summary != b.Summary {
+			return a.Summary < b.Summary
+		}
+		return a.Installment < b.Installment
+	})
+	return payments, nil
+}
+
+// GetInventory lists the files of the path with the header data of the ones that are valid
+func (s Service) GetInventory(path string) ([]ports.FileInventory, error) {
+	inventory := make([]ports.FileInventory, 0)
+	files, err := s.fileManager.GetFiles(path)
+	if err != nil {
+		return inventory, err
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		item := ports.FileInventory{Name: file.Name(), Size: file.Size()}
+		h, err := s.GetHeaderData(path, file)
+		if err != nil {
+			item.Error = err.Error()
+			inventory = append(inventory, item)
+			continue
+		}
+		item.Valid = true
+		item.Acquirer = h.GetAcquirer()
+		item.Headquarter = h.GetHeadquarter()
+		item.StatementId = h.GetStatementId()
+		item.PeriodInit = h.GetPeriodInit()
+		item.PeriodEnd = h.GetPeriodEnd()
+		item.ProcessingDate = h.GetProcessingDate()
+		item.Sequence = h.GetSequence()
+		item.LayoutVersion = h.GetLayoutVersion()
+		item.Reprocessed = h.IsReprocessed()
+		inventory = append(inventory, item)
+	}
+	return inventory, nil
+}
+
+// fileVersion holds the header data that tells which copy of a statement is the latest
+type fileVersion struct {
+	processingDate time.Time
+	reprocessed    bool
+}
+
+// newerThan returns true when the version was processed after the other one or is its reprocessing
+func (v fileVersion) newerThan(other fileVersion) bool {
+	if !v.processingDate.Equal(other.processingDate) {
+		return v.processingDate.After(other.processingDate)
+	}
+	return v.reprocessed && !other.reprocessed
+}
+
+// GetDuplicates groups the files of the path with the same content (Exact) and the files of the same statement
+// with different contents (Logical). A logical group lists one file of each content, latest processed first
+func (s Service) GetDuplicates(path string) ([]ports.DuplicateGroup, error) {
+	groups, _, err := s.getDuplicates(path)
+	return groups, err
+}
+
+// getDuplicates returns the duplicate groups of the path and the version of each file read
+func (s Service) getDuplicates(path string) ([]ports.DuplicateGroup, map[string]fileVersion, error) {
+	groups := make([]ports.DuplicateGroup, 0)
+	versions := make(map[string]fileVersion)
+	files, err := s.fileManager.GetFiles(path)
+	if err != nil {
+		return groups, versions, err
+	}
+	hashOrder := make([]string, 0)
+	hashMap := make(map[string][]string)
+	keyOrder := make([]string, 0)
+	keyMap := make(map[string][]string)
+	keyHashes := make(map[string]map[string]bool)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		hash, err := s.fileManager.GetFileHash(path, file)
+		if err != nil {
+			continue
+		}
+		if _, ok := hashMap[hash]; !ok {
+			hashOrder = append(hashOrder, hash)
+		}
+		hashMap[hash] = append(hashMap[hash], file.Name())
+		h, err := s.GetHeaderData(path, file)
+		if err != nil {
+			continue
+		}
+		// the header data is reused by the next parse, so the version keeps its values
+		versions[file.Name()] = fileVersion{processingDate: h.GetProcessingDate(), reprocessed: h.IsReprocessed()}
+		key := fmt.Sprintf(identityFormat, h.GetAcquirer(), h.GetHeadquarter(), h.GetStatementId(),
+			h.GetPeriodInit().Format(printDateFormat), h.GetPeriodEnd().Format(printDateFormat), h.GetSequence())
+		if _, ok := keyMap[key]; !ok {
+			keyOrder = append(keyOrder, key)
+			keyHashes[key] = make(map[string]bool)
+		}
+		// exact copies are already in their hash group, so the logical group keeps one file of each content
+		if keyHashes[key][hash] {
+			continue
+		}
+		keyMap[key] = append(keyMap[key], file.Name())
+		keyHashes[key][hash] = true
+	}
+	for _, hash := range hashOrder {
+		if len(hashMap[hash]) > 1 {
+			groups = append(groups, ports.DuplicateGroup{Kind: exactKind, Key: hash, Files: hashMap[hash]})
+		}
+	}
+	for _, key := range keyOrder {
+		if len(keyHashes[key]) > 1 {
+			files := keyMap[key]
+			sort.SliceStable(files, func(i, j int) bool {
+				return versions[files[i]].newerThan(versions[files[j]])
+			})
+			groups = append(groups, ports.DuplicateGroup{Kind: logicalKind, Key: key, Files: files})
+		}
+	}
+	return groups, versions, nil
+}
+
+// QuarantineDuplicates finds the duplicated files of the path and, when quarantine is not empty,
+// moves the redundant copies of exact duplicates to it keeping the first one. When logical is true
+// it also moves the older copies of logical duplicates keeping the latest processed; a copy processed
+// on the same date as the latest one can not be told apart, so it is kept and reported as failed
+func (s Service) QuarantineDuplicates(path string, quarantine string, logical bool) ([]ports.DuplicateGroup, error) {
+	groups, versions, err := s.getDuplicates(path)
+	if err != nil {
+		return groups, err
+	}
+	if quarantine == "" {
+		return groups, nil
+	}
+	for i, group := range groups {
+		if group.Kind == logicalKind && !logical {
+			continue
+		}
+		for _, name := range group.Files[1:] {
+			reason := "duplicate of " + group.Files[0]
+			if group.Kind == logicalKind {
+				if !versions[group.Files[0]].newerThan(versions[name]) {
+					result := ports.RenameResult{File: name, Status: ports.FailedStatus, Reason: "not older than " + group.Files[0]}
+					groups[i].Quarantine = append(groups[i].Quarantine, result)
+					continue
+				}
+				reason = "older copy of " + group.Files[0]
+			}
+			result := ports.RenameResult{File: name, NewName: filepath.Join(quarantine, name), Status: ports.QuarantinedStatus, Reason: reason}
+			if err := s.fileManager.MoveFile(path, name, quarantine); err != nil {
+				result = ports.RenameResult{File: name, Status: ports.FailedStatus, Reason: err.Error()}
+			}
+			groups[i].Quarantine = append(groups[i].Quarantine, result)
+		}
+	}
+	return groups, nil
+}
@@ -1,9 +1,13 @@
 package services
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -46,11 +50,30 @@ func (i FileInfoMock) Sys() interface{} {
 
 // Mock of filemanager
 type FileManagerMock struct {
-	files []fs.FileInfo
+	files    []fs.FileInfo
+	hashes   map[string]string
+	moved    *[]string
+	written  map[string][]byte
+	removed  *[]string
+	remove   error
+	records  int
+	last     string
+	content  string
+	encoding string
 }
 
 func NewFileManagerMock(files []fs.FileInfo) ports.FileManagerInterface {
-	return &FileManagerMock{files: files}
+	return &FileManagerMock{files: files, moved: &[]string{}, written: map[string][]byte{}, removed: &[]string{}}
+}
+func NewFileManagerHashMock(files []fs.FileInfo, hashes map[string]string) *FileManagerMock {
+	return &FileManagerMock{files: files, hashes: hashes, moved: &[]string{}, written: map[string][]byte{}, removed: &[]string{}}
+}
+func (f *FileManagerMock) SetEncoding(encoding string) error {
+	if encoding != "utf-8" && encoding != "iso-8859-1" {
+		return fmt.Errorf("encoding %s not found", encoding)
+	}
+	f.encoding = encoding
+	return nil
 }
 func (f FileManagerMock) GetFiles(string) ([]fs.FileInfo, error) {
 	return f.files, nil
@@ -58,9 +81,66 @@ func (f FileManagerMock) GetFiles(string) ([]fs.FileInfo, error) {
 func (f FileManagerMock) GetFirstLine(str string, info fs.FileInfo) (string, error) {
 	return str, nil
 }
+func (f FileManagerMock) GetLastLine(string, fs.FileInfo) (string, error) {
+	return f.last, nil
+}
+func (f FileManagerMock) OpenReader(string, fs.FileInfo) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(f.content)), nil
+}
+func (f FileManagerMock) CountLines(string, fs.FileInfo) (int, error) {
+	return f.records, nil
+}
 func (f FileManagerMock) RenameFile(string, string, string) error {
 	return nil
 }
+func (f FileManagerMock) MoveFile(pathFrom string, name string, pathTo string) error {
+	*f.moved = append(*f.moved, name)
+	return nil
+}
+func (f FileManagerMock) GetFileHash(path string, info fs.FileInfo) (string, error) {
+	return f.hashes[info.Name()], nil
+}
+func (f FileManagerMock) WriteFile(path string, name string, data []byte) error {
+	f.written[name] = data
+	return nil
+}
+func (f FileManagerMock) RemoveFile(path string, name string) error {
+	*f.removed = append(*f.removed, name)
+	return f.remove
+}
+
+// Trailer mock declaring a number of records
+type TrailerMock struct {
+	declared int
+}
+
+func (t TrailerMock) Parse(txt string) error {
+	if txt == "" {
+		return errors.New("Parse Error")
+	}
+	return nil
+}
+func (t TrailerMock) Validate(records int) error {
+	if records != t.declared {
+		return fmt.Errorf("trailer declares %d records but %d were read", t.declared, records)
+	}
+	return nil
+}
+func (t TrailerMock) GetData() ports.TrailerDataInterface {
+	return TrailerDataMock{declared: t.declared}
+}
+
+// Trailer Data mock
+type TrailerDataMock struct {
+	declared int
+}
+
+func (d TrailerDataMock) GetRecordCount() int {
+	return d.declared
+}
+func (d TrailerDataMock) Validate() error {
+	return nil
+}
 
 // Header Data Mock
 type HeaderDataMock struct {
@@ -105,6 +185,9 @@ func (d *HeaderDataMock) IsReprocessed() bool {
 func (d *HeaderDataMock) GetAcquirer() string {
 	return "CIELO"
 }
+func (d *HeaderDataMock) GetLayoutName() string {
+	return "cielovendas"
+}
 func (d HeaderDataMock) GetPeriodDates() ([]time.Time, error) {
 	times := make([]time.Time, 0)
 	if d.periodInit.Equal(time.Time{}) || d.periodEnd.Equal(time.Time{}) {
@@ -121,6 +204,9 @@ func (d HeaderDataMock) GetPeriodDates() ([]time.Time, error) {
 func (d HeaderDataMock) IsValid() bool {
 	return true
 }
+func (d HeaderDataMock) Validate() error {
+	return nil
+}
 
 // Header mock
 type HeaderMock struct {
@@ -135,11 +221,20 @@ func (h HeaderMock) Parse(string) error {
 	if h.loaded {
 		return nil
 	}
-	return errors.New("Parse Error")
+	return &ports.FieldError{Field: "RegisterType", Err: errors.New("Parse Error")}
 }
 func (h HeaderMock) IsValid() bool {
 	return h.loaded
 }
+func (h HeaderMock) Validate() error {
+	if h.loaded {
+		return nil
+	}
+	return errors.New("Validate Error")
+}
+func (h HeaderMock) Inspect(string) []ports.FieldInspection {
+	return []ports.FieldInspection{{Name: "RegisterType", Length: 1}}
+}
 func (h HeaderMock) GetData() ports.HeaderDataInterface {
 	return h.headerData
 }
@@ -175,9 +270,10 @@ func TestFormatNames(t *testing.T) {
 	he := NewHeaderMock(hd, true)
 	// get service
 	service := NewService(fm, he)
-	logger, err := service.FormatNames(path)
+	results, err := service.FormatNames(path)
 	assert.Nil(t, err)
-	assert.Len(t, logger, 1)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "renamed", results[0].Status)
 }
 
 func TestGetPeriodMap(t *testing.T) {
@@ -258,20 +354,20 @@ func TestGetGapStr(t *testing.T) {
 	dates, err = service.GetGapGrouped(path, initDate, endDate)
 	assert.Nil(t, err)
 	assert.Len(t, dates, 1)
-	assert.Equal(t, "31/12/2020 - 31/12/2020", dates[0])
+	assert.Equal(t, "31/12/2020 - 31/12/2020", dates[0].String())
 	initDate, _ = time.Parse(printDateFormat, "2021_02_01")
 	endDate, _ = time.Parse(printDateFormat, "2021_02_10")
 	dates, err = service.GetGapGrouped(path, initDate, endDate)
 	assert.Nil(t, err)
 	assert.Len(t, dates, 1)
-	assert.Equal(t, "01/02/2021 - 10/02/2021", dates[0])
+	assert.Equal(t, "01/02/2021 - 10/02/2021", dates[0].String())
 	initDate, _ = time.Parse(printDateFormat, "2020_12_31")
 	endDate, _ = time.Parse(printDateFormat, "2021_02_12")
 	dates, err = service.GetGapGrouped(path, initDate, endDate)
 	assert.Nil(t, err)
 	assert.Len(t, dates, 2)
-	assert.Equal(t, "31/12/2020 - 31/12/2020", dates[0])
-	assert.Equal(t, "11/01/2021 - 12/02/2021", dates[1])
+	assert.Equal(t, "31/12/2020 - 31/12/2020", dates[0].String())
+	assert.Equal(t, "11/01/2021 - 12/02/2021", dates[1].String())
 }
 
 func TestGetPeriodtr(t *testing.T) {
@@ -291,5 +387,846 @@ func TestGetPeriodtr(t *testing.T) {
 	dates, err := service.GetPeriodGrouped(path)
 	assert.Nil(t, err)
 	assert.Len(t, dates, 1)
-	assert.Equal(t, "01/01/2021 - 10/01/2021", dates[0])
+	assert.Equal(t, "01/01/2021 - 10/01/2021", dates[0].String())
+}
+
+func TestGetDuplicates(t *testing.T) {
+	// Load FileManager
+	fi := make([]fs.FileInfo, 0)
+	fi = append(fi, NewFileInfoMock("file1.txt", false))
+	fi = append(fi, NewFileInfoMock("file2.txt", false))
+	fi = append(fi, NewFileInfoMock("file3.txt", false))
+	fi = append(fi, NewFileInfoMock("dir", true))
+	fm := NewFileManagerHashMock(fi, map[string]string{"file1.txt": "aaa", "file2.txt": "bbb", "file3.txt": "aaa"})
+	// Load Header
+	initDate, _ := time.Parse(printDateFormat, "2021_01_01")
+	endDate, _ := time.Parse(printDateFormat, "2021_01_10")
+	procDate, _ := time.Parse(printDateFormat, "2021_01_10")
+	hd := NewHeaderDataMock(int64(123445), procDate, initDate, endDate, 123, "04", int8(14), false)
+	he := NewHeaderMock(hd, true)
+	// get service
+	service := NewService(fm, he)
+	groups, err := service.GetDuplicates(path)
+	assert.Nil(t, err)
+	assert.Len(t, groups, 2)
+	assert.Equal(t, "Exact", groups[0].Kind)
+	assert.Equal(t, []string{"file1.txt", "file3.txt"}, groups[0].Files)
+	assert.Equal(t, "Logical", groups[1].Kind)
+	assert.Equal(t, "CIELO-0000123445-04-2021_01_01-2021_01_10-0000123", groups[1].Key)
+	assert.Equal(t, []string{"file1.txt", "file2.txt"}, groups[1].Files)
+	// quarantining the exact copies leaves the logical group as it is
+	groups, err = service.QuarantineDuplicates(path, "quarantine", false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"file3.txt"}, *fm.moved)
+	assert.Equal(t, []string{"file1.txt", "file2.txt"}, groups[1].Files)
+	assert.Len(t, groups[1].Quarantine, 0)
+	// copies processed on the same date can not be told apart, so none is moved
+	groups, err = service.QuarantineDuplicates(path, "quarantine", true)
+	assert.Nil(t, err)
+	assert.Equal(t, []ports.RenameResult{{File: "file2.txt", Status: "failed", Reason: "not older than file1.txt"}}, groups[1].Quarantine)
+	assert.NotContains(t, *fm.moved, "file2.txt")
+}
+
+func TestQuarantineDuplicates(t *testing.T) {
+	// Load FileManager
+	fi := make([]fs.FileInfo, 0)
+	fi = append(fi, NewFileInfoMock("file1.txt", false))
+	fi = append(fi, NewFileInfoMock("file2.txt", false))
+	fm := NewFileManagerHashMock(fi, map[string]string{"file1.txt": "aaa", "file2.txt": "aaa"})
+	// Load Header
+	he := NewHeaderMock(nil, false)
+	// get service
+	service := NewService(fm, he)
+	groups, err := service.QuarantineDuplicates(path, "", false)
+	assert.Nil(t, err)
+	assert.Len(t, groups, 1)
+	assert.Equal(t, "aaa", groups[0].Key)
+	assert.Len(t, groups[0].Quarantine, 0)
+	assert.Len(t, *fm.moved, 0)
+	groups, err = service.QuarantineDuplicates(path, "quarantine", false)
+	assert.Nil(t, err)
+	assert.Len(t, groups, 1)
+	assert.Equal(t, []ports.RenameResult{{File: "file2.txt", NewName: "quarantine/file2.txt", Status: "quarantined", Reason: "duplicate of file1.txt"}}, groups[0].Quarantine)
+	assert.Equal(t, []string{"file2.txt"}, *fm.moved)
+}
+
+func TestFormatNamesQuarantine(t *testing.T) {
+	// Load FileManager
+	fi := make([]fs.FileInfo, 0)
+	fi = append(fi, NewFileInfoMock("file1.txt", false))
+	fi = append(fi, NewFileInfoMock("dir", true))
+	fm := NewFileManagerHashMock(fi, nil)
+	// Load Header
+	hd := NewHeaderDataMock(int64(123445), time.Time{}, time.Time{}, time.Time{}, 123, "04", int8(14), false)
+	he := NewHeaderMock(hd, false)
+	// get service
+	service := NewService(fm, he)
+	results, err := service.FormatNamesQuarantine(path, "quarantine")
+	assert.Nil(t, err)
+	assert.Equal(t, []ports.RenameResult{
+		{File: "file1.txt", NewName: "quarantine/file1.txt", Status: "quarantined", Reason: "error parsing"},
+		{File: "dir", Status: "failed", Reason: "error parsing"},
+	}, results)
+	assert.Equal(t, []string{"file1.txt"}, *fm.moved)
+	reason := QuarantineReason{}
+	err = json.Unmarshal(fm.written["file1.txt.reason.json"], &reason)
+	assert.Nil(t, err)
+	assert.Equal(t, "file1.txt", reason.File)
+	assert.Equal(t, "parse", reason.Stage)
+	assert.Equal(t, "error parsing", reason.Error)
+	assert.Equal(t, "RegisterType", reason.Field)
+	assert.Equal(t, "RegisterType: Parse Error", reason.Detail)
+	assert.Equal(t, []string{"cielovendas"}, reason.Layouts)
+}
+
+func TestRequeue(t *testing.T) {
+	// Load FileManager
+	fi := make([]fs.FileInfo, 0)
+	fi = append(fi, NewFileInfoMock("file1.txt", false))
+	fi = append(fi, NewFileInfoMock("file1.txt.reason.json", false))
+	fm := NewFileManagerHashMock(fi, nil)
+	// Load Header
+	hd := NewHeaderDataMock(int64(123445), time.Time{}, time.Time{}, time.Time{}, 123, "04", int8(14), false)
+	// get service
+	service := NewService(fm, NewHeaderMock(hd, false))
+	results, err := service.Requeue(path, "./quarantine")
+	assert.Nil(t, err)
+	assert.Equal(t, []ports.RenameResult{{File: "file1.txt", Status: "failed", Reason: "error parsing"}}, results)
+	assert.Len(t, *fm.moved, 0)
+	service = NewService(fm, NewHeaderMock(hd, true))
+	results, err = service.Requeue(path, "./quarantine")
+	assert.Nil(t, err)
+	assert.Equal(t, []ports.RenameResult{{File: "file1.txt", NewName: "tmp/file1.txt", Status: "requeued"}}, results)
+	assert.Equal(t, []string{"file1.txt"}, *fm.moved)
+	assert.Equal(t, []string{"file1.txt.reason.json"}, *fm.removed)
+	// a missing sidecar is not reported
+	fm.remove = fs.ErrNotExist
+	results, err = service.Requeue(path, "./quarantine")
+	assert.Nil(t, err)
+	assert.Equal(t, []ports.RenameResult{{File: "file1.txt", NewName: "tmp/file1.txt", Status: "requeued"}}, results)
+	// a sidecar that could not be removed is reported on the requeued file
+	fm.remove = errors.New("permission denied")
+	results, err = service.Requeue(path, "./quarantine")
+	assert.Nil(t, err)
+	assert.Equal(t, []ports.RenameResult{{File: "file1.txt", NewName: "tmp/file1.txt", Status: "requeued",
+		Reason: "reason file not removed: permission denied"}}, results)
+}
+
+func TestHeaderError(t *testing.T) {
+	err := HeaderError{Stage: "parse", Err: errors.New("RegisterType: unexpected end of txt for parsing this field")}
+	assert.Equal(t, "error parsing", err.Error())
+	err = HeaderError{Stage: "validate"}
+	assert.Equal(t, "invalid file", err.Error())
+	err = HeaderError{Stage: "read", Err: errors.New("error scanning file.txt")}
+	assert.Equal(t, "error scanning file.txt", err.Error())
+	err = HeaderError{Stage: "trailer", Err: errors.New("trailer declares 12 records but 10 were read")}
+	assert.Equal(t, "truncated file: trailer declares 12 records but 10 were read", err.Error())
+	err = HeaderError{Stage: "totals"}
+	assert.Equal(t, "totals mismatch", err.Error())
+}
+
+func TestIsFormattedName(t *testing.T) {
+	service := NewService(NewFileManagerMock(nil), NewHeaderMock(nil, false))
+	assert.True(t, service.IsFormattedName("CIELO-1023863232-03-2021_03_10-2021_03_10-N-2021_03_10-L013.txt"))
+	assert.True(t, service.IsFormattedName("REDECARD-0021644942-EEVC-2021_02_07-2021_02_07-R-2021_02_07-L002.txt"))
+	assert.False(t, service.IsFormattedName("test1.txt"))
+	assert.False(t, service.IsFormattedName("CIELO-1023863232-03-2021_03_10-2021_03_10-N-2021_03_10-L013.txt.reason.json"))
+}
+
+func TestGetHeadquarterGap(t *testing.T) {
+	// Load FileManager
+	fi := make([]fs.FileInfo, 0)
+	fi = append(fi, NewFileInfoMock(files[0], false))
+	fm := NewFileManagerMock(fi)
+	// Load Header
+	initDate, _ := time.Parse(printDateFormat, "2021_01_01")
+	endDate, _ := time.Parse(printDateFormat, "2021_01_10")
+	procDate, _ := time.Parse(printDateFormat, "2021_01_10")
+	hd := NewHeaderDataMock(int64(123445), procDate, initDate, endDate, 123, "4", int8(14), true)
+	he := NewHeaderMock(hd, true)
+	// get service
+	service := NewService(fm, he)
+	dates, err := service.GetHeadquarterGap(path, int64(123445), initDate, endDate)
+	assert.Nil(t, err)
+	assert.Len(t, dates, 0)
+	dates, err = service.GetHeadquarterGap(path, int64(999), initDate, endDate)
+	assert.Nil(t, err)
+	assert.Len(t, dates, 10)
+	periods, err := service.GetHeadquarterPeriodGrouped(path, int64(999))
+	assert.Nil(t, err)
+	assert.Len(t, periods, 0)
+	periods, err = service.GetHeadquarterPeriodGrouped(path, int64(123445))
+	assert.Nil(t, err)
+	assert.Equal(t, "01/01/2021 - 10/01/2021", periods[0].String())
+}
+
+func TestGetInventory(t *testing.T) {
+	// Load FileManager
+	fi := make([]fs.FileInfo, 0)
+	fi = append(fi, NewFileInfoMock(files[0], false))
+	fi = append(fi, NewFileInfoMock("dir", true))
+	fm := NewFileManagerMock(fi)
+	// Load Header
+	initDate, _ := time.Parse(printDateFormat, "2021_01_01")
+	endDate, _ := time.Parse(printDateFormat, "2021_01_10")
+	procDate, _ := time.Parse(printDateFormat, "2021_01_10")
+	hd := NewHeaderDataMock(int64(123445), procDate, initDate, endDate, 123, "04", int8(14), false)
+	// get service
+	service := NewService(fm, NewHeaderMock(hd, true))
+	inventory, err := service.GetInventory(path)
+	assert.Nil(t, err)
+	assert.Len(t, inventory, 1)
+	assert.True(t, inventory[0].Valid)
+	assert.Equal(t, files[0], inventory[0].Name)
+	assert.Equal(t, int64(123445), inventory[0].Headquarter)
+	assert.Equal(t, "04", inventory[0].StatementId)
+	assert.Equal(t, initDate, inventory[0].PeriodInit)
+	service = NewService(fm, NewHeaderMock(hd, false))
+	inventory, err = service.GetInventory(path)
+	assert.Nil(t, err)
+	assert.Len(t, inventory, 1)
+	assert.False(t, inventory[0].Valid)
+	assert.Equal(t, "error parsing", inventory[0].Error)
+}
+
+// Detail mock parsing lines starting with D as records and failing on lines starting with X.
+// Validate expects a number of records
+type DetailMock struct {
+	declared int
+}
+
+type RecordMock struct {
+	txt     string
+	version int8
+}
+
+func (r RecordMock) GetRecordType() string {
+	return r.txt[:1]
+}
+
+// Settlement record mock parsed from lines S:establishment:yyyy-mm-dd:cents
+type SettlementMock struct {
+	establishment int64
+	date          time.Time
+	amount        ports.Money
+}
+
+func (r SettlementMock) GetRecordType() string {
+	return "S"
+}
+func (r SettlementMock) GetEstablishment() int64 {
+	return r.establishment
+}
+func (r SettlementMock) GetPaymentDate() time.Time {
+	return r.date
+}
+func (r SettlementMock) GetBankAccount() ports.BankAccount {
+	return ports.BankAccount{Bank: 341, Branch: 1, Account: r.establishment * 10}
+}
+func (r SettlementMock) GetSettledAmount() ports.Money {
+	return r.amount
+}
+
+// SummaryMock is a sales summary of one sale with a discount of 1%
+type SummaryMock struct {
+	brand string
+	date  time.Time
+	gross ports.Money
+}
+
+func (r SummaryMock) GetRecordType() string {
+	return "V"
+}
+func (r SummaryMock) GetSalesDate() time.Time {
+	return r.date
+}
+func (r SummaryMock) GetBrand() string {
+	return r.brand
+}
+func (r SummaryMock) GetSaleCount() int {
+	return 1
+}
+func (r SummaryMock) GetGrossAmount() ports.Money {
+	return r.gross
+}
+func (r SummaryMock) GetDiscountAmount() ports.Money {
+	return r.gross / 100
+}
+func (r SummaryMock) GetNetAmount() ports.Money {
+	return r.gross - r.gross/100
+}
+
+// AnticipationMock is an anticipation operation of establishment 1
+type AnticipationMock struct {
+	operation  int64
+	date       time.Time
+	gross, net ports.Money
+}
+
+func (r AnticipationMock) GetRecordType() string {
+	return "A"
+}
+func (r AnticipationMock) GetOperation() int64 {
+	return r.operation
+}
+func (r AnticipationMock) GetEstablishment() int64 {
+	return 1
+}
+func (r AnticipationMock) GetCreditDate() time.Time {
+	return r.date
+}
+func (r AnticipationMock) GetGrossAmount() ports.Money {
+	return r.gross
+}
+func (r AnticipationMock) GetNetAmount() ports.Money {
+	return r.net
+}
+
+// AnticipatedMock is a receivable of an anticipation operation
+type AnticipatedMock struct {
+	operation int64
+	date      time.Time
+	gross     ports.Money
+}
+
+func (r AnticipatedMock) GetRecordType() string {
+	return "R"
+}
+func (r AnticipatedMock) GetOperation() int64 {
+	return r.operation
+}
+func (r AnticipatedMock) GetOriginalDate() time.Time {
+	return r.date
+}
+func (r AnticipatedMock) GetGrossAmount() ports.Money {
+	return r.gross
+}
+
+// ReceivableMock is an open balance of one receivable with a discount of 1%
+type ReceivableMock struct {
+	establishment int64
+	brand         string
+	date          time.Time
+	gross         ports.Money
+}
+
+func (r ReceivableMock) GetRecordType() string {
+	return "B"
+}
+func (r ReceivableMock) GetEstablishment() int64 {
+	return r.establishment
+}
+func (r ReceivableMock) GetBrand() string {
+	return r.brand
+}
+func (r ReceivableMock) GetDueDate() time.Time {
+	return r.date
+}
+func (r ReceivableMock) GetReceivableCount() int {
+	return 1
+}
+func (r ReceivableMock) GetGrossAmount() ports.Money {
+	return r.gross
+}
+func (r ReceivableMock) GetNetAmount() ports.Money {
+	return r.gross - r.gross/100
+}
+
+// ScheduledMock is a payment scheduled for an operation summary installment
+type ScheduledMock struct {
+	summary     int64
+	installment int
+	date        time.Time
+	net         ports.Money
+}
+
+func (r ScheduledMock) GetRecordType() string {
+	return "P"
+}
+func (r ScheduledMock) GetEstablishment() int64 {
+	return 1
+}
+func (r ScheduledMock) GetSummaryNumber() int64 {
+	return r.summary
+}
+func (r ScheduledMock) GetInstallment() int {
+	return r.installment
+}
+func (r ScheduledMock) GetScheduledDate() time.Time {
+	return r.date
+}
+func (r ScheduledMock) GetNetAmount() ports.Money {
+	return r.net
+}
+
+// TransactionMock is a record of the canonical schema with a fee of 1%
+type TransactionMock struct {
+	kind  string
+	gross ports.Money
+}
+
+func (r TransactionMock) GetRecordType() string {
+	return "T"
+}
+func (r TransactionMock) GetTransaction() ports.Transaction {
+	return ports.Transaction{Kind: r.kind, Establishment: 1, Gross: r.gross, Fee: r.gross / 100, Net: r.gross - r.gross/100}
+}
+
+// ErpSaleMock is a sale of an ERP export parsed from lines E:yyyy-mm-dd:amount:nsu
+type ErpSaleMock struct {
+	date   time.Time
+	amount ports.Money
+	nsu    int64
+}
+
+func (r ErpSaleMock) GetRecordType() string {
+	return "E"
+}
+func (r ErpSaleMock) GetErpSale() ports.ErpSale {
+	return ports.ErpSale{Date: r.date, Amount: r.amount, Nsu: r.nsu}
+}
+
+// BankEntryMock is a bank entry on the account 341/1/10 parsed from lines K:yyyy-mm-dd:amount
+type BankEntryMock struct {
+	date   time.Time
+	amount ports.Money
+}
+
+func (r BankEntryMock) GetRecordType() string {
+	return "K"
+}
+func (r BankEntryMock) GetBankEntry() ports.BankEntry {
+	return ports.BankEntry{Date: r.date, Account: ports.BankAccount{Bank: 341, Branch: 1, Account: 10}, Amount: r.amount}
+}
+
+// ColumnParserMock keeps the header and the date format it is set with
+type ColumnParserMock struct {
+	header     string
+	dateFormat string
+}
+
+func (p *ColumnParserMock) Parse(interface{}, string) error {
+	return nil
+}
+func (p *ColumnParserMock) Inspect(interface{}, string) []ports.FieldInspection {
+	return nil
+}
+func (p *ColumnParserMock) SetColumns(header string, columns map[string]string) error {
+	for _, column := range columns {
+		if !strings.Contains(header, column) {
+			return fmt.Errorf("column %s not found on the csv header", column)
+		}
+	}
+	p.header = header
+	return nil
+}
+func (p *ColumnParserMock) SetDateFormat(format string) error {
+	p.dateFormat = format
+	return nil
+}
+
+func (d DetailMock) Parse(txt string, version int8) (ports.RecordInterface, error) {
+	switch txt[0] {
+	case 'K':
+		fields := strings.Split(txt, ":")
+		date, _ := time.Parse("2006-01-02", fields[1])
+		amount, _ := ports.ParseMoney(fields[2])
+		return BankEntryMock{date: date, amount: amount}, nil
+	case 'E':
+		fields := strings.Split(txt, ":")
+		date, _ := time.Parse("2006-01-02", fields[1])
+		amount, _ := ports.ParseMoney(fields[2])
+		nsu, _ := strconv.ParseInt(fields[3], 10, 64)
+		return ErpSaleMock{date: date, amount: amount, nsu: nsu}, nil
+	case 'P':
+		fields := strings.Split(txt, ":")
+		summary, _ := strconv.ParseInt(fields[1], 10, 64)
+		installment, _ := strconv.Atoi(fields[2])
+		date, _ := time.Parse("2006-01-02", fields[3])
+		net, _ := ports.ParseMoney(fields[4])
+		return ScheduledMock{summary: summary, installment: installment, date: date, net: net}, nil
+	case 'T':
+		fields := strings.Split(txt, ":")
+		gross, _ := ports.ParseMoney(fields[2])
+		return TransactionMock{kind: fields[1], gross: gross}, nil
+	case 'B':
+		fields := strings.Split(txt, ":")
+		establishment, _ := strconv.ParseInt(fields[1], 10, 64)
+		date, _ := time.Parse("2006-01-02", fields[3])
+		gross, _ := ports.ParseMoney(fields[4])
+		return ReceivableMock{establishment: establishment, brand: fields[2], date: date, gross: gross}, nil
+	case 'A', 'R':
+		fields := strings.Split(txt, ":")
+		operation, _ := strconv.ParseInt(fields[1], 10, 64)
+		date, _ := time.Parse("2006-01-02", fields[2])
+		gross, _ := ports.ParseMoney(fields[3])
+		if txt[0] == 'R' {
+			return AnticipatedMock{operation: operation, date: date, gross: gross}, nil
+		}
+		net, _ := ports.ParseMoney(fields[4])
+		return AnticipationMock{operation: operation, date: date, gross: gross, net: net}, nil
+	case 'V':
+		fields := strings.Split(txt, ":")
+		date, _ := time.Parse("2006-01-02", fields[2])
+		gross, _ := ports.ParseMoney(fields[3])
+		return SummaryMock{brand: fields[1], date: date, gross: gross}, nil
+	case 'S':
+		fields := strings.Split(txt, ":")
+		establishment, _ := strconv.ParseInt(fields[1], 10, 64)
+		date, _ := time.Parse("2006-01-02", fields[2])
+		amount, _ := ports.ParseMoney(fields[3])
+		return SettlementMock{establishment: establishment, date: date, amount: amount}, nil
+	case 'D':
+		return RecordMock{txt: txt, version: version}, nil
+	case 'X':
+		return nil, errors.New("record X: Parse Error")
+	}
+	return nil, nil
+}
+func (d DetailMock) IsHeader(txt string) bool {
+	return txt == "header"
+}
+func (d DetailMock) Validate(records []ports.RecordInterface) error {
+	if len(records) != d.declared {
+		return fmt.Errorf("trailer record count %d does not match the %d read", d.declared, len(records))
+	}
+	return nil
+}
+
+// Calendar mock expecting files only on weekdays
+type CalendarMock struct{}
+
+func (c CalendarMock) IsExpected(date time.Time) bool {
+	return date.Weekday() != time.Saturday && date.Weekday() != time.Sunday
+}
+
+func TestSetNameTemplate(t *testing.T) {
+	fi := make([]fs.FileInfo, 0)
+	fi = append(fi, NewFileInfoMock(files[0], false))
+	fm := NewFileManagerMock(fi)
+	initDate, _ := time.Parse(printDateFormat, "2021_01_01")
+	endDate, _ := time.Parse(printDateFormat, "2021_01_10")
+	hd := NewHeaderDataMock(int64(123445), endDate, initDate, endDate, 123, "4", int8(14), true)
+	service := NewService(fm, NewHeaderMock(hd, true))
+	results, err := service.FormatNames(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "CIELO-0000123445-4-2021_01_01-2021_01_10-R-2021_01_10-L014.txt", results[0].NewName)
+	assert.True(t, service.IsFormattedName(results[0].NewName))
+	err = service.SetNameTemplate("{headquarter}_{acquirer}_{periodInit}_{sequence}.txt")
+	assert.Nil(t, err)
+	results, err = service.FormatNames(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "0000123445_CIELO_2021_01_01_0000123.txt", results[0].NewName)
+	assert.True(t, service.IsFormattedName(results[0].NewName))
+	assert.False(t, service.IsFormattedName("CIELO-0000123445-4-2021_01_01-2021_01_10-R-2021_01_10-L014.txt"))
+	err = service.SetNameTemplate("{headquarter}-{unknown}.txt")
+	assert.NotNil(t, err)
+	assert.Equal(t, "name template placeholder {unknown} not found", err.Error())
+	err = service.SetNameTemplate("fixed.txt")
+	assert.NotNil(t, err)
+	assert.True(t, service.IsFormattedName("0000123445_CIELO_2021_01_01_0000123.txt"))
+}
+
+func TestGetGapCalendar(t *testing.T) {
+	fi := make([]fs.FileInfo, 0)
+	fi = append(fi, NewFileInfoMock(files[0], false))
+	fm := NewFileManagerMock(fi)
+	initDate, _ := time.Parse(printDateFormat, "2021_01_04")
+	endDate, _ := time.Parse(printDateFormat, "2021_01_08")
+	hd := NewHeaderDataMock(int64(123445), endDate, initDate, endDate, 123, "4", int8(14), false)
+	service := NewService(fm, NewHeaderMock(hd, true))
+	from, _ := time.Parse(printDateFormat, "2021_01_01")
+	to, _ := time.Parse(printDateFormat, "2021_01_11")
+	dates, err := service.GetGapGrouped(path, from, to)
+	assert.Nil(t, err)
+	assert.Len(t, dates, 2)
+	assert.Equal(t, "01/01/2021 - 03/01/2021", dates[0].String())
+	assert.Equal(t, "09/01/2021 - 11/01/2021", dates[1].String())
+	service.SetCalendar(CalendarMock{})
+	dates, err = service.GetGapGrouped(path, from, to)
+	assert.Nil(t, err)
+	assert.Len(t, dates, 2)
+	assert.Equal(t, "01/01/2021 - 01/01/2021", dates[0].String())
+	assert.Equal(t, "11/01/2021 - 11/01/2021", dates[1].String())
+}
+
+func TestGetGapSummary(t *testing.T) {
+	fi := make([]fs.FileInfo, 0)
+	fi = append(fi, NewFileInfoMock(files[0], false))
+	fm := NewFileManagerMock(fi)
+	initDate, _ := time.Parse(printDateFormat, "2021_01_04")
+	endDate, _ := time.Parse(printDateFormat, "2021_01_08")
+	hd := NewHeaderDataMock(int64(123445), endDate, initDate, endDate, 123, "4", int8(14), false)
+	service := NewService(fm, NewHeaderMock(hd, true))
+	from, _ := time.Parse(printDateFormat, "2021_01_01")
+	to, _ := time.Parse(printDateFormat, "2021_01_11")
+	summaries, err := service.GetGapSummary(path, nil, from, to)
+	assert.Nil(t, err)
+	assert.Len(t, summaries, 1)
+	assert.Equal(t, "cielovendas", summaries[0].Statement)
+	assert.Equal(t, int64(123445), summaries[0].Headquarter)
+	assert.Equal(t, 6, summaries[0].MissingDays)
+	assert.Equal(t, "01/01/2021 - 03/01/2021", summaries[0].OldestGap.String())
+	assert.Len(t, summaries[0].Gaps, 2)
+	summaries, err = service.GetGapSummary(path, []int64{123445, 999}, initDate, endDate)
+	assert.Nil(t, err)
+	assert.Len(t, summaries, 2)
+	assert.Equal(t, 0, summaries[0].MissingDays)
+	assert.Nil(t, summaries[0].OldestGap)
+	assert.Equal(t, int64(999), summaries[1].Headquarter)
+	assert.Equal(t, 5, summaries[1].MissingDays)
+	service = NewService(NewFileManagerMock(nil), NewHeaderMock(hd, true))
+	summaries, err = service.GetGapSummary(path, nil, initDate, endDate)
+	assert.Nil(t, err)
+	assert.Len(t, summaries, 1)
+	assert.Equal(t, int64(0), summaries[0].Headquarter)
+	assert.Equal(t, 5, summaries[0].MissingDays)
+	_, err = service.GetGapSummary(path, nil, endDate, initDate)
+	assert.NotNil(t, err)
+}
+
+func TestValidateFiles(t *testing.T) {
+	fi := make([]fs.FileInfo, 0)
+	fi = append(fi, NewFileInfoMock(files[0], false))
+	fm := NewFileManagerHashMock(fi, nil)
+	fm.records, fm.last = 10, "trailer"
+	initDate, _ := time.Parse(printDateFormat, "2021_01_01")
+	hd := NewHeaderDataMock(int64(123445), initDate, initDate, initDate, 123, "4", int8(14), false)
+	service := NewService(fm, NewHeaderMock(hd, true))
+	_, err := service.ValidateFiles(path)
+	assert.NotNil(t, err)
+	assert.Equal(t, "layout cielovendas has no trailer", err.Error())
+	service.SetTrailer(TrailerMock{declared: 10})
+	validations, err := service.ValidateFiles(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []ports.FileValidation{{File: files[0], Valid: true, Records: 10, DeclaredRecords: 10}}, validations)
+	service.SetTrailer(TrailerMock{declared: 12})
+	validations, err = service.ValidateFiles(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []ports.FileValidation{{File: files[0], Records: 10, DeclaredRecords: 12, Stage: "trailer",
+		Error: "trailer declares 12 records but 10 were read"}}, validations)
+	// truncated files are not counted unless the check is turned off
+	dates, err := service.GetPeriod(path)
+	assert.Nil(t, err)
+	assert.Len(t, dates, 0)
+	results, err := service.FormatNames(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "truncated file: trailer declares 12 records but 10 were read", results[0].Reason)
+	service.SetVerifyTrailer(false)
+	dates, err = service.GetPeriod(path)
+	assert.Nil(t, err)
+	assert.Len(t, dates, 1)
+	service.SetVerifyTrailer(true)
+	fm.last = ""
+	validations, err = service.ValidateFiles(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "trailer not found (Parse Error)", validations[0].Error)
+	service = NewService(fm, NewHeaderMock(hd, false))
+	service.SetTrailer(TrailerMock{declared: 10})
+	validations, err = service.ValidateFiles(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "parse", validations[0].Stage)
+}
+
+func TestSetEncoding(t *testing.T) {
+	fm := NewFileManagerHashMock(nil, nil)
+	service := NewService(fm, NewHeaderMock(nil, true))
+	assert.Nil(t, service.SetEncoding("iso-8859-1"))
+	assert.Equal(t, "iso-8859-1", fm.encoding)
+	assert.NotNil(t, service.SetEncoding("ebcdic"))
+}
+
+func TestGetRecords(t *testing.T) {
+	fi := []fs.FileInfo{NewFileInfoMock(files[0], false)}
+	fm := NewFileManagerHashMock(fi, nil)
+	fm.records, fm.last, fm.content = 4, "trailer", "header\r\nD1\r\n\r\nD2\r\ntrailer\r\n"
+	initDate, _ := time.Parse(printDateFormat, "2021_01_01")
+	hd := NewHeaderDataMock(int64(123445), initDate, initDate, initDate, 123, "4", int8(14), false)
+	service := NewService(fm, NewHeaderMock(hd, true))
+	_, err := service.GetRecords(path, fi[0])
+	assert.NotNil(t, err)
+	assert.Equal(t, "layout cielovendas has no detail records", err.Error())
+	service.SetDetail(DetailMock{declared: 2})
+	records, err := service.GetRecords(path, fi[0])
+	assert.Nil(t, err)
+	assert.Equal(t, []ports.RecordInterface{RecordMock{txt: "D1", version: 14}, RecordMock{txt: "D2", version: 14}}, records)
+	service.SetTrailer(TrailerMock{declared: 4})
+	validations, err := service.ValidateFiles(path)
+	assert.Nil(t, err)
+	assert.True(t, validations[0].Valid)
+	service.SetDetail(DetailMock{declared: 3})
+	validations, _ = service.ValidateFiles(path)
+	assert.Equal(t, ports.FileValidation{File: files[0], Records: 4, DeclaredRecords: 4, Stage: "totals",
+		Error: "trailer record count 3 does not match the 2 read"}, validations[0])
+	fm.content = "header\nD1\nX2\ntrailer"
+	_, err = service.GetRecords(path, fi[0])
+	assert.NotNil(t, err)
+	assert.Equal(t, files[0]+" line 3: record X: Parse Error", err.Error())
+	validations, _ = service.ValidateFiles(path)
+	assert.Equal(t, "record", validations[0].Stage)
+	service = NewService(fm, NewHeaderMock(hd, false))
+	service.SetDetail(DetailMock{})
+	_, err = service.GetRecords(path, fi[0])
+	assert.NotNil(t, err)
+}
+
+func TestGetDailySettlements(t *testing.T) {
+	fi := []fs.FileInfo{NewFileInfoMock(files[0], false), NewFileInfoMock(files[1], false)}
+	fm := NewFileManagerHashMock(fi, nil)
+	fm.content = "header\nS:2:2021-03-10:1000\nD1\nS:1:2021-03-10:5000\nS:1:2021-03-10:-1500\nS:1:2021-03-09:700"
+	initDate, _ := time.Parse(printDateFormat, "2021_01_01")
+	hd := NewHeaderDataMock(int64(123445), initDate, initDate, initDate, 123, "4", int8(14), false)
+	service := NewService(fm, NewHeaderMock(hd, true))
+	_, err := service.GetDailySettlements(path)
+	assert.NotNil(t, err)
+	service.SetDetail(DetailMock{})
+	settlements, err := service.GetDailySettlements(path)
+	assert.Nil(t, err)
+	assert.Len(t, settlements, 3)
+	march9, _ := time.Parse("2006-01-02", "2021-03-09")
+	march10, _ := time.Parse("2006-01-02", "2021-03-10")
+	assert.Equal(t, ports.DailySettlement{Date: march9, Establishment: 1, Account: ports.BankAccount{Bank: 341, Branch: 1, Account: 10},
+		Credits: 1400, Net: 1400, Records: 2}, settlements[0])
+	assert.Equal(t, ports.DailySettlement{Date: march10, Establishment: 1, Account: ports.BankAccount{Bank: 341, Branch: 1, Account: 10},
+		Credits: 10000, Debits: 3000, Net: 7000, Records: 4}, settlements[1])
+	assert.Equal(t, int64(2), settlements[2].Establishment)
+	assert.Equal(t, ports.Money(2000), settlements[2].Net)
+	assert.False(t, settlements[2].Negative)
+	fm.content = "header\nS:1:2021-03-10:5000\nS:1:2021-03-10:-7500"
+	settlements, err = service.GetDailySettlements(path)
+	assert.Nil(t, err)
+	assert.Equal(t, ports.DailySettlement{Date: march10, Establishment: 1, Account: ports.BankAccount{Bank: 341, Branch: 1, Account: 10},
+		Credits: 10000, Debits: 15000, Net: -5000, Records: 4, Negative: true}, settlements[0])
+	service = NewService(fm, NewHeaderMock(hd, false))
+	service.SetDetail(DetailMock{})
+	settlements, err = service.GetDailySettlements(path)
+	assert.Nil(t, err)
+	assert.Len(t, settlements, 0)
+}
+
+func TestGetSalesTotals(t *testing.T) {
+	fi := []fs.FileInfo{NewFileInfoMock(files[0], false)}
+	fm := NewFileManagerHashMock(fi, nil)
+	fm.content = "header\nV:VISA:2021-03-10:10000\nS:1:2021-03-10:5000\nV:ELO :2021-03-10:2000\nV:VISA:2021-03-10:5000\nV:ELO:2021-03-09:100"
+	initDate, _ := time.Parse(printDateFormat, "2021_01_01")
+	hd := NewHeaderDataMock(int64(123445), initDate, initDate, initDate, 123, "4", int8(14), false)
+	service := NewService(fm, NewHeaderMock(hd, true))
+	_, err := service.GetSalesTotals(path)
+	assert.NotNil(t, err)
+	service.SetDetail(DetailMock{})
+	totals, err := service.GetSalesTotals(path)
+	assert.Nil(t, err)
+	assert.Len(t, totals, 3)
+	march9, _ := time.Parse("2006-01-02", "2021-03-09")
+	march10, _ := time.Parse("2006-01-02", "2021-03-10")
+	assert.Equal(t, ports.SalesTotal{Date: march9, Brand: "ELO", Sales: 1, Gross: 100, Discount: 1, Net: 99}, totals[0])
+	assert.Equal(t, ports.SalesTotal{Date: march10, Brand: "ELO", Sales: 1, Gross: 2000, Discount: 20, Net: 1980}, totals[1])
+	assert.Equal(t, ports.SalesTotal{Date: march10, Brand: "VISA", Sales: 2, Gross: 15000, Discount: 150, Net: 14850}, totals[2])
+}
+
+func TestGetAnticipationCosts(t *testing.T) {
+	fi := []fs.FileInfo{NewFileInfoMock(files[0], false)}
+	fm := NewFileManagerHashMock(fi, nil)
+	fm.content = "header\nA:2:2021-03-10:10000:9800\nR:2:2021-04-09:10000\nA:1:2021-03-10:20000:19000\nR:1:2021-04-09:10000\n" +
+		"R:1:2021-05-09:10000\nA:3:2021-03-01:5000:5000\nD1"
+	initDate, _ := time.Parse(printDateFormat, "2021_01_01")
+	hd := NewHeaderDataMock(int64(123445), initDate, initDate, initDate, 123, "4", int8(14), false)
+	service := NewService(fm, NewHeaderMock(hd, true))
+	_, err := service.GetAnticipationCosts(path)
+	assert.NotNil(t, err)
+	service.SetDetail(DetailMock{})
+	costs, err := service.GetAnticipationCosts(path)
+	assert.Nil(t, err)
+	assert.Len(t, costs, 3)
+	assert.Equal(t, int64(3), costs[0].Operation)
+	assert.Equal(t, 0.0, costs[0].Days)
+	assert.Equal(t, 0.0, costs[0].MonthlyRate)
+	assert.Equal(t, int64(1), costs[1].Operation)
+	assert.Equal(t, ports.Money(1000), costs[1].Fee)
+	assert.Equal(t, 45.0, costs[1].Days)
+	assert.InDelta(t, 0.0348, costs[1].MonthlyRate, 0.0001)
+	assert.Equal(t, int64(2), costs[2].Operation)
+	assert.Equal(t, 30.0, costs[2].Days)
+	assert.InDelta(t, 0.020408, costs[2].MonthlyRate, 0.000001)
+	assert.InDelta(t, 0.274345, costs[2].AnnualRate, 0.000001)
+}
+
+func TestGetOutstandingReceivables(t *testing.T) {
+	fi := []fs.FileInfo{NewFileInfoMock(files[0], false), NewFileInfoMock(files[1], false), NewFileInfoMock("dir", true)}
+	fm := NewFileManagerHashMock(fi, nil)
+	fm.content = "header\nB:2:VIS:2021-08-10:1000\nB:1:VIS:2021-08-10:5000\nB:1:MC :2021-08-10:2000\nB:1:VIS:2021-08-10:3000\n" +
+		"B:1:VIS:2021-07-10:700\nD1"
+	initDate, _ := time.Parse(printDateFormat, "2021_01_01")
+	hd := NewHeaderDataMock(int64(123445), initDate, initDate, initDate, 123, "4", int8(14), false)
+	service := NewService(fm, NewHeaderMock(hd, true))
+	_, err := service.GetOutstandingReceivables(path)
+	assert.NotNil(t, err)
+	service.SetDetail(DetailMock{})
+	receivables, err := service.GetOutstandingReceivables(path)
+	assert.Nil(t, err)
+	assert.Len(t, receivables, 4)
+	july10, _ := time.Parse("2006-01-02", "2021-07-10")
+	august10, _ := time.Parse("2006-01-02", "2021-08-10")
+	assert.Equal(t, ports.OutstandingReceivable{Establishment: 1, Brand: "MC", DueDate: august10, Receivables: 1, Gross: 2000, Net: 1980},
+		receivables[0])
+	assert.Equal(t, ports.OutstandingReceivable{Establishment: 1, Brand: "VIS", DueDate: july10, Receivables: 1, Gross: 700, Net: 693},
+		receivables[1])
+	assert.Equal(t, ports.OutstandingReceivable{Establishment: 1, Brand: "VIS", DueDate: august10, Receivables: 2, Gross: 8000, Net: 7920},
+		receivables[2])
+	assert.Equal(t, int64(2), receivables[3].Establishment)
+	service = NewService(fm, NewHeaderMock(hd, false))
+	service.SetDetail(DetailMock{})
+	receivables, err = service.GetOutstandingReceivables(path)
+	assert.Nil(t, err)
+	assert.Len(t, receivables, 0)
+}
+
+func TestGetTransactions(t *testing.T) {
+	fi := []fs.FileInfo{NewFileInfoMock(files[0], false), NewFileInfoMock("dir", true)}
+	fm := NewFileManagerHashMock(fi, nil)
+	fm.content = "header\nT:sale:10000\nS:1:2021-08-10:500\nT:settlement:9900\nD1"
+	initDate, _ := time.Parse(printDateFormat, "2021_01_01")
+	hd := NewHeaderDataMock(int64(123445), initDate, initDate, initDate, 123, "4", int8(14), false)
+	service := NewService(fm, NewHeaderMock(hd, true))
+	_, err := service.GetTransactions(path)
+	assert.NotNil(t, err)
+	service.SetDetail(DetailMock{})
+	transactions, err := service.GetTransactions(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []ports.Transaction{
+		{Kind: ports.SaleTransaction, Acquirer: "CIELO", Establishment: 1, Gross: 10000, Fee: 100, Net: 9900},
+		{Kind: ports.SettlementTransaction, Acquirer: "CIELO", Establishment: 1, Gross: 9900, Fee: 99, Net: 9801},
+	}, transactions)
+	service = NewService(fm, NewHeaderMock(hd, false))
+	service.SetDetail(DetailMock{})
+	transactions, err = service.GetTransactions(path)
+	assert.Nil(t, err)
+	assert.Len(t, transactions, 0)
+}
+
+func TestGetScheduledPayments(t *testing.T) {
+	fi := []fs.FileInfo{NewFileInfoMock(files[0], false), NewFileInfoMock("dir", true)}
+	fm := NewFileManagerHashMock(fi, nil)
+	fm.content = "header\nP:102:0:2021-04-11:30000\nP:102:2:2021-05-11:10000\nP:102:1:2021-04-11:10000\n" +
+		"P:101:0:2021-04-11:14700\nT:sale:10000\nD1"
+	initDate, _ := time.Parse(printDateFormat, "2021_01_01")
+	hd := NewHeaderDataMock(int64(123445), initDate, initDate, initDate, 123, "4", int8(14), false)
+	service := NewService(fm, NewHeaderMock(hd, true))
+	_, err := service.GetScheduledPayments(path)
+	assert.NotNil(t, err)
+	service.SetDetail(DetailMock{})
+	payments, err := service.GetScheduledPayments(path)
+	assert.Nil(t, err)
+	april11, _ := time.Parse("2006-01-02", "2021-04-11")
+	may11, _ := time.Parse("2006-01-02", "2021-05-11")
+	assert.Equal(t, []ports.ScheduledPayment{
+		{Acquirer: "CIELO", Establishment: 1, Summary: 101, Date: april11, Net: 14700},
+		{Acquirer: "CIELO", Establishment: 1, Summary: 102, Installment: 1, Date: april11, Net: 10000},
+		{Acquirer: "CIELO", Establishment: 1, Summary: 102, Installment: 2, Date: may11, Net: 10000},
+	}, payments)
+	service = NewService(fm, NewHeaderMock(hd, false))
+	service.SetDetail(DetailMock{})
+	payments, err = service.GetScheduledPayments(path)
+	assert.Nil(t, err)
+	assert.Len(t, payments, 0)
 }
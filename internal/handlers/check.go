@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+const (
+	okStatus      = "OK"
+	warningStatus = "WARNING"
+	metricPrefix  = "cielo_edi_"
+)
+
+// checkResult is the json output of the check command
+type checkResult struct {
+	Status      string             `json:"status"`
+	MissingDays int                `json:"missingDays"`
+	OldestGap   *ports.DateRange   `json:"oldestGap,omitempty"`
+	Summaries   []ports.GapSummary `json:"summaries"`
+}
+
+func getMissingDays(summaries []ports.GapSummary) int {
+	missing := 0
+	for _, s := range summaries {
+		missing += s.MissingDays
+	}
+	return missing
+}
+
+func getOldestGap(summaries []ports.GapSummary) *ports.DateRange {
+	var oldest *ports.DateRange
+	for _, s := range summaries {
+		if s.OldestGap != nil && (oldest == nil || s.OldestGap.Start.Before(oldest.Start)) {
+			oldest = s.OldestGap
+		}
+	}
+	return oldest
+}
+
+func summaryName(s ports.GapSummary) string {
+	if s.Headquarter == 0 {
+		return s.Statement
+	}
+	return fmt.Sprintf("%s %d", s.Statement, s.Headquarter)
+}
+
+// writeGapSummaries writes the check summary. The first table line is a status line with performance data
+// (Nagios plugin format) followed by a line for each statement and headquarter
+func writeGapSummaries(o *output, summaries []ports.GapSummary) error {
+	result := checkResult{Status: okStatus, MissingDays: getMissingDays(summaries), OldestGap: getOldestGap(summaries), Summaries: summaries}
+	status := fmt.Sprintf("%s - %d statements fully covered | missing_days=0", okStatus, len(summaries))
+	if result.MissingDays > 0 {
+		result.Status = warningStatus
+		withGaps := 0
+		for _, s := range summaries {
+			if s.MissingDays > 0 {
+				withGaps++
+			}
+		}
+		status = fmt.Sprintf("%s - %d missing days in %d of %d statements, oldest gap %s | missing_days=%d",
+			warningStatus, result.MissingDays, withGaps, len(summaries), result.OldestGap.Start.Format("02/01/2006"), result.MissingDays)
+	}
+	lines := []string{status}
+	records := make([][]string, 0, len(summaries))
+	for _, s := range summaries {
+		if s.OldestGap == nil {
+			lines = append(lines, fmt.Sprintf("%s: covered", summaryName(s)))
+			records = append(records, []string{s.Statement, fmt.Sprint(s.Headquarter), "0", "", ""})
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %d missing days, oldest gap %s", summaryName(s), s.MissingDays, s.OldestGap.String()))
+		records = append(records, []string{s.Statement, fmt.Sprint(s.Headquarter), fmt.Sprint(s.MissingDays),
+			s.OldestGap.Start.Format(ports.DateFormat), s.OldestGap.End.Format(ports.DateFormat)})
+	}
+	header := []string{"statement", "headquarter", "missingDays", "oldestGapStart", "oldestGapEnd"}
+	return o.write(result, lines, header, records)
+}
+
+// writeTextfile writes the check metrics in the Prometheus textfile collector format. The file is written
+// to a temporary file and renamed, so the collector never reads it partially written
+func writeTextfile(file string, summaries []ports.GapSummary, success bool) error {
+	b := &strings.Builder{}
+	ok := 0
+	if success {
+		ok = 1
+	}
+	fmt.Fprintf(b, "# HELP %scheck_success Whether the last check ran without errors.\n", metricPrefix)
+	fmt.Fprintf(b, "# TYPE %scheck_success gauge\n", metricPrefix)
+	fmt.Fprintf(b, "%scheck_success %d\n", metricPrefix, ok)
+	if len(summaries) > 0 {
+		fmt.Fprintf(b, "# HELP %smissing_days Days without statement files in the checked period.\n", metricPrefix)
+		fmt.Fprintf(b, "# TYPE %smissing_days gauge\n", metricPrefix)
+		for _, s := range summaries {
+			fmt.Fprintf(b, "%smissing_days{statement=%q,headquarter=\"%d\"} %d\n", metricPrefix, s.Statement, s.Headquarter, s.MissingDays)
+		}
+		fmt.Fprintf(b, "# HELP %soldest_gap_start_seconds Unix time of the first day of the oldest gap.\n", metricPrefix)
+		fmt.Fprintf(b, "# TYPE %soldest_gap_start_seconds gauge\n", metricPrefix)
+		for _, s := range summaries {
+			if s.OldestGap != nil {
+				fmt.Fprintf(b, "%soldest_gap_start_seconds{statement=%q,headquarter=\"%d\"} %d\n", metricPrefix, s.Statement, s.Headquarter, s.OldestGap.Start.Unix())
+			}
+		}
+	}
+	temp := file + ".tmp"
+	if err := os.WriteFile(temp, []byte(b.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp, file)
+}
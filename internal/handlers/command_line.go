@@ -1,206 +1,1143 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"math"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/lavinas/cielo-edi/internal/core/domain"
 	"github.com/lavinas/cielo-edi/internal/core/ports"
 	"github.com/lavinas/cielo-edi/internal/core/services"
+	"github.com/lavinas/cielo-edi/internal/utils/config"
 	"github.com/lavinas/cielo-edi/internal/utils/file_manager"
+	"github.com/lavinas/cielo-edi/internal/utils/file_watcher"
 	"github.com/lavinas/cielo-edi/internal/utils/string_parser"
 )
 
+const (
+	ExitOk      = 0
+	ExitFailure = 1
+	ExitUsage   = 2
+	// ExitGaps and ExitCheckError are the exit codes of the check command (as Nagios warning and critical)
+	ExitGaps       = 1
+	ExitCheckError = 2
+)
+
 var (
-	funcMap = map[string]interface{}{
-		"rename":  rename,
-		"gaps":    gaps,
-		"periods": periods,
+	// Version is the program version, set on build with -ldflags "-X github.com/lavinas/cielo-edi/internal/handlers.Version=x.y.z"
+	Version = "dev"
+	// commands has the subcommands of the command line in the order they are shown on help (see init)
+	commands []command
+	// flagUsage has the description of each flag
+	flagUsage = map[string]string{
+		"acquirer":   "acquirer statement layout (%s)",
+		"path":       "directory of the files",
+		"from":       "initial date (dd/mm/yyyy or yyyy-mm-dd)",
+		"to":         "final date (dd/mm/yyyy or yyyy-mm-dd)",
+		"quarantine": "directory where rejected or redundant files are moved",
+		"stable":     "seconds a file size must be stable before it is renamed",
+		"address":    "address the server listens on",
+		"output":     "output format (table, json or csv)",
+		"shell":      "shell of the completion script (bash, zsh or fish)",
+		"command":    "command to be described",
+		"profile":    "client profile of the config file (acquirers, paths, headquarters, name template and calendar)",
+		"config":     "config file (yaml or json) with the profiles",
+		"file":       "statement file to be inspected",
+		"trailer":    "reject truncated files (trailer missing or not matching the records read); --trailer=false skips the check, that reads each file to the end",
+		"textfile":   "file where the check metrics are written in the Prometheus textfile collector format",
+		"encoding":   "encoding of the files (%s)",
+		"rate":       "contracted monthly anticipation rate in percent (operations above it are flagged)",
+		"erp":        "directory of the csv sales exports of the ERP or POS, reconciled with the acquirer sales",
+		"bank":       "directory of the bank statement return files (CNAB 240 or 400)",
+		"logical":    "also move to quarantine the older copies of logical duplicates (same statement, different contents), keeping the latest processed",
 	}
 	acquirerMap = map[string]ports.HeaderDataInterface{
 		"cielovendas":       &domain.HeaderCielo{Statement: "vendas"},
 		"cielofinanceiro":   &domain.HeaderCielo{Statement: "financeiro"},
 		"cieloantecipacoes": &domain.HeaderCielo{Statement: "antecipacoes"},
-		"cieloalelo":        &domain.HeaderCielo{Statement: "alelo"}, 
+		"cieloalelo":        &domain.HeaderCielo{Statement: "alelo"},
+		"cielocessoes":      &domain.HeaderCielo{Statement: "cessoes"},
+		"cielosaldo":        &domain.HeaderCielo{Statement: "saldo"},
 		"redecredito":       &domain.HeaderRedeCredit{Statement: "credito"},
 		"rededebito":        &domain.HeaderRedeDebt{Statement: "debito"},
 		"redefinanceiro":    &domain.HeaderRedeFin{Statement: "financeiro"},
 		"getnet":            &domain.HeaderGetnet{},
 	}
+	// trailerMap has the trailer layouts of the acquirers whose files can be checked for completeness
+	trailerMap = map[string]ports.TrailerDataInterface{
+		"cielovendas":       &domain.TrailerCielo{},
+		"cielofinanceiro":   &domain.TrailerCielo{},
+		"cieloantecipacoes": &domain.TrailerCielo{},
+		"cieloalelo":        &domain.TrailerCielo{},
+		"cielocessoes":      &domain.TrailerCielo{},
+		"cielosaldo":        &domain.TrailerCielo{},
+		"redecredito":       &domain.TrailerRede{Statement: "credito"},
+		"rededebito":        &domain.TrailerRedeDebt{},
+		"redefinanceiro":    &domain.TrailerRede{Statement: "financeiro"},
+		"getnet":            &domain.TrailerGetnet{},
+	}
+	// detailMap has the detail record layouts of the acquirers whose records can be read and checked against
+	// the totals of the trailer
+	detailMap = map[string]func(ports.StringParserInterface) *domain.Detail{
+		"cieloalelo":        domain.NewCieloAleloDetail,
+		"cielofinanceiro":   domain.NewCieloFinDetail,
+		"cieloantecipacoes": domain.NewCieloAnticipationDetail,
+		"cielocessoes":      domain.NewCieloAssignmentDetail,
+		"cielosaldo":        domain.NewCieloOpenBalanceDetail,
+		"cielovendas":       domain.NewCieloSalesDetail,
+		"redecredito":       domain.NewRedeCreditDetail,
+		"rededebito":        domain.NewRedeDebtDetail,
+		"redefinanceiro":    domain.NewRedeFinDetail,
+		"getnet":            domain.NewGetnetDetail,
+	}
+	// settlementMap has the financial statement that settles the payments scheduled by each sales statement
+	settlementMap = map[string]string{
+		"cielovendas": "cielofinanceiro",
+		"redecredito": "redefinanceiro",
+		"rededebito":  "redefinanceiro",
+	}
+	serveAddress  = ":8080"
+	watchInterval = 2 * time.Second
+	watchStable   = 10
 	parserTypeMap = map[string]string{
 		"cielovendas":       "position",
 		"cielofinanceiro":   "position",
 		"cieloantecipacoes": "position",
 		"cieloalelo":        "position",
+		"cielocessoes":      "position",
+		"cielosaldo":        "position",
 		"redecredito":       "position",
 		"rededebito":        "csv",
 		"redefinanceiro":    "position",
 		"getnet":            "position",
 	}
+	// erpColumns has the columns of the ERP sales exports of clients without an ERP layout on their profile
+	erpColumns = map[string]string{
+		"date":          "date",
+		"amount":        "amount",
+		"nsu":           "nsu",
+		"authorization": "authorization",
+		"card":          "card",
+	}
+	inputDateFormats = []string{"02/01/2006", "2006-01-02"}
+	configFile       = "cielo-edi.yaml"
 )
 
+func init() {
+	commands = []command{
+		{name: "rename", description: "rename the files of a path with their header data",
+			flags: []string{"acquirer", "path", "quarantine", "profile", "config", "trailer", "encoding", "output"}, positional: []string{"acquirer", "path", "quarantine"}, run: rename},
+		{name: "gaps", description: "list the days of a period without files",
+			flags: []string{"acquirer", "path", "from", "to", "profile", "config", "trailer", "encoding", "output"}, positional: []string{"acquirer", "path", "from", "to"}, run: gaps},
+		{name: "check", description: "check the gaps of a period and exit 0 when covered, 1 with gaps and 2 on errors",
+			flags: []string{"acquirer", "path", "from", "to", "profile", "config", "textfile", "trailer", "encoding", "output"}, positional: []string{"acquirer", "path", "from", "to"}, run: check},
+		{name: "periods", description: "list the days covered by the files of a path",
+			flags: []string{"acquirer", "path", "profile", "config", "trailer", "encoding", "output"}, positional: []string{"acquirer", "path"}, run: periods},
+		{name: "validate", description: "check that the files of a path are complete (trailer, record count and totals)",
+			flags: []string{"acquirer", "path", "profile", "config", "encoding", "output"}, positional: []string{"acquirer", "path"}, run: validateFiles},
+		{name: "settlements", description: "list the net amount settled per day, establishment and bank account",
+			flags: []string{"acquirer", "path", "profile", "config", "trailer", "encoding", "output"}, positional: []string{"acquirer", "path"}, run: settlements},
+		{name: "sales", description: "list the sales per day and card brand",
+			flags: []string{"acquirer", "path", "profile", "config", "trailer", "encoding", "output"}, positional: []string{"acquirer", "path"}, run: sales},
+		{name: "anticipations", description: "list the effective monthly and annual rate of each anticipation operation",
+			flags: []string{"acquirer", "path", "rate", "profile", "config", "trailer", "encoding", "output"}, positional: []string{"acquirer", "path"}, run: anticipations},
+		{name: "receivables", description: "list the outstanding receivables per establishment, card brand and due date of the latest open balance",
+			flags: []string{"acquirer", "path", "profile", "config", "trailer", "encoding", "output"}, positional: []string{"acquirer", "path"}, run: receivables},
+		{name: "transactions", description: "list the sales, installments, settlements, adjustments, chargebacks, anticipations and assignments on a schema shared by all acquirers",
+			flags: []string{"acquirer", "path", "profile", "config", "trailer", "encoding", "output"}, positional: []string{"acquirer", "path"}, run: transactions},
+		{name: "reconcile", description: "match the payments scheduled by the sales statements to the financial statements and list them as on time, late, divergent or open, or the sales to the ERP sales exports (--erp) and list the missing and divergent ones",
+			flags: []string{"acquirer", "path", "erp", "profile", "config", "trailer", "encoding", "output"}, positional: []string{"acquirer", "path"}, run: reconcile},
+		{name: "deposits", description: "match the credits of the financial statements to the entries of the bank return files (CNAB 240 or 400) by date, amount and account and list them as deposited or missing",
+			flags: []string{"acquirer", "path", "bank", "profile", "config", "trailer", "encoding", "output"}, positional: []string{"acquirer", "path", "bank"}, run: deposits},
+		{name: "duplicates", description: "list duplicated files and optionally move the redundant copies to quarantine",
+			flags: []string{"acquirer", "path", "quarantine", "logical", "profile", "config", "trailer", "encoding", "output"}, positional: []string{"acquirer", "path", "quarantine"}, run: duplicates},
+		{name: "requeue", description: "move back quarantined files that are now valid",
+			flags: []string{"acquirer", "path", "quarantine", "profile", "config", "trailer", "encoding", "output"}, positional: []string{"acquirer", "path", "quarantine"}, run: requeue},
+		{name: "watch", description: "rename new files of a path as they arrive",
+			flags: []string{"acquirer", "path", "stable", "trailer", "encoding", "output"}, positional: []string{"acquirer", "path", "stable"}, run: watch},
+		{name: "inspect", description: "show how each layout parses the header of a file and why it is rejected",
+			flags: []string{"file", "encoding", "output"}, positional: []string{"file"}, run: inspect},
+		{name: "serve", description: "serve a REST API for the files of a path",
+			flags: []string{"path", "address"}, positional: []string{"path", "address"}, run: serve},
+		{name: "version", description: "print the version", run: version},
+		{name: "completion", description: "print a shell completion script (bash, zsh or fish)",
+			flags: []string{"shell"}, positional: []string{"shell"}, run: completion},
+		{name: "help", description: "show the help of a command", positional: []string{"command"}, run: help},
+	}
+}
+
+// UsageError is a wrong command line. It makes the program exit with ExitUsage instead of ExitFailure
+type UsageError struct {
+	Err error
+}
+
+func (e UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func usageErrorf(format string, a ...interface{}) error {
+	return &UsageError{Err: fmt.Errorf(format, a...)}
+}
+
+// ExitError is an error of a command that sets its own exit code
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e ExitError) Error() string {
+	return e.Err.Error()
+}
+
+// ExitCode returns the exit code of an error returned by Run
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOk
+	}
+	var exit *ExitError
+	if errors.As(err, &exit) {
+		return exit.Code
+	}
+	var usage *UsageError
+	if errors.As(err, &usage) {
+		return ExitUsage
+	}
+	return ExitFailure
+}
+
+// command is a subcommand of the command line. flags has the names of the accepted flags and positional
+// has the flags that can also be given as positional arguments (legacy form: command acquirer path ...)
+type command struct {
+	name        string
+	description string
+	flags       []string
+	positional  []string
+	run         func(*CommandLine, *options) error
+}
+
+// options has the values of the flags of a command
+type options struct {
+	program     string
+	command     command
+	acquirer    string
+	path        string
+	from        string
+	to          string
+	quarantine  string
+	stable      int
+	address     string
+	output      string
+	shell       string
+	topic       string
+	profile     string
+	config      string
+	textfile    string
+	file        string
+	trailer     bool
+	logical     bool
+	encoding    string
+	rate        float64
+	erp         string
+	erpLayout   ports.ErpLayout
+	erpEncoding string
+	bank        string
+	bankDays    int
+	initDate    time.Time
+	endDate     time.Time
+	service     ports.ServiceInterface
+	targets     []target
+	out         *output
+}
+
+// target is an acquirer whose files are read by a command. Commands run once for each target
+// of a profile and once for the acquirer and path flags without a profile
+type target struct {
+	acquirer     string
+	path         string
+	headquarters []int64
+	service      ports.ServiceInterface
+}
+
 type CommandLine struct {
 	logger ports.LoggerInterface
+	writer io.Writer
 }
 
 func NewCommandLine(logger ports.LoggerInterface) *CommandLine {
-	return &CommandLine{logger: logger}
+	return &CommandLine{logger: logger, writer: os.Stdout}
 }
 
 func (cm CommandLine) Run(args []string) error {
-	function, headerData, parserType, path, err := getArgs(args)
+	if len(args) < 2 || args[1] == "-h" || args[1] == "--help" {
+		cm.printHelp(programName(args))
+		if len(args) < 2 {
+			return usageErrorf("command not found (should be %s)", strings.Join(getCommandNames(), ", "))
+		}
+		return nil
+	}
+	cmd, err := getCommand(args[1])
 	if err != nil {
 		return err
 	}
-	parser := string_parser.NewStringParser(parserType)
-	manager := file_manager.NewFileManager()
-	header := domain.NewHeader(headerData, parser)
-	service := services.NewService(manager, header)
-	if err := function.(func(ports.LoggerInterface, ports.ServiceInterface, string, []string) error)(cm.logger, service, path, args); err != nil {
+	opts, err := cm.parseFlags(cmd, programName(args), args[2:])
+	if errors.Is(err, flag.ErrHelp) {
+		cm.printCommandHelp(cmd, programName(args))
+		return nil
+	}
+	if err != nil {
 		return err
 	}
-	return nil
+	return cmd.run(&cm, opts)
 }
 
-func rename(log ports.LoggerInterface, service ports.ServiceInterface, path string, args []string) error {
-	logger, err := service.FormatNames(path)
-	if err != nil {
+// parseFlags parses the flags of a command. Flags and positional arguments can be mixed
+//
+// returns the options of the command, flag.ErrHelp if help was asked or a UsageError
+func (cm CommandLine) parseFlags(cmd command, program string, args []string) (*options, error) {
+	opts := &options{program: program, command: cmd, stable: watchStable, address: serveAddress, output: tableOutput}
+	fset := cm.newFlagSet(cmd, opts)
+	positional := make([]string, 0)
+	for {
+		if err := fset.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, &UsageError{Err: err}
+		}
+		if fset.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fset.Arg(0))
+		args = fset.Args()[1:]
+	}
+	if len(positional) > len(cmd.positional) {
+		return nil, usageErrorf("too many arguments for %s (see %s help %s)", cmd.name, program, cmd.name)
+	}
+	set := make(map[string]bool)
+	fset.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for i, value := range positional {
+		name := cmd.positional[i]
+		if set[name] {
+			return nil, usageErrorf("%s given as flag and as argument", name)
+		}
+		if err := fset.Set(name, value); err != nil {
+			return nil, usageErrorf("invalid value %s for %s: %v", value, name, err)
+		}
+	}
+	if err := cm.validate(opts); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+func (cm CommandLine) newFlagSet(cmd command, opts *options) *flag.FlagSet {
+	fset := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+	for _, name := range cmd.flags {
+		usage := flagUsage[name]
+		switch name {
+		case "acquirer":
+			fset.StringVar(&opts.acquirer, name, "", fmt.Sprintf(usage, strings.Join(getAcquirerNames(), ", ")))
+		case "path":
+			fset.StringVar(&opts.path, name, "", usage)
+		case "from":
+			fset.StringVar(&opts.from, name, "", usage)
+		case "to":
+			fset.StringVar(&opts.to, name, "", usage)
+		case "quarantine":
+			fset.StringVar(&opts.quarantine, name, "", usage)
+		case "stable":
+			fset.IntVar(&opts.stable, name, watchStable, usage)
+		case "address":
+			fset.StringVar(&opts.address, name, serveAddress, usage)
+		case "output":
+			fset.StringVar(&opts.output, name, tableOutput, usage)
+		case "shell":
+			fset.StringVar(&opts.shell, name, "", usage)
+		case "profile":
+			fset.StringVar(&opts.profile, name, "", usage)
+		case "config":
+			fset.StringVar(&opts.config, name, "", usage)
+		case "textfile":
+			fset.StringVar(&opts.textfile, name, "", usage)
+		case "file":
+			fset.StringVar(&opts.file, name, "", usage)
+		case "trailer":
+			fset.BoolVar(&opts.trailer, name, true, usage)
+		case "logical":
+			fset.BoolVar(&opts.logical, name, false, usage)
+		case "encoding":
+			fset.StringVar(&opts.encoding, name, "", fmt.Sprintf(usage, strings.Join(file_manager.GetEncodings(), ", ")))
+		case "rate":
+			fset.Float64Var(&opts.rate, name, 0, usage)
+		case "erp":
+			fset.StringVar(&opts.erp, name, "", usage)
+		case "bank":
+			fset.StringVar(&opts.bank, name, "", usage)
+		}
+	}
+	for _, name := range cmd.positional {
+		if fset.Lookup(name) == nil {
+			fset.StringVar(&opts.topic, name, "", flagUsage[name])
+		}
+	}
+	return fset
+}
+
+// validate checks the options of the command and creates its service and output
+func (cm CommandLine) validate(opts *options) error {
+	has := make(map[string]bool)
+	for _, name := range opts.command.flags {
+		has[name] = true
+	}
+	if has["output"] {
+		if err := validateOutput(opts.output); err != nil {
+			return err
+		}
+		opts.out = newOutput(opts.output, cm.logger, cm.writer)
+	}
+	if has["profile"] && opts.profile == "" {
+		opts.profile = os.Getenv(config.EnvPrefix + "PROFILE")
+	}
+	if opts.profile != "" {
+		if err := validateProfile(opts); err != nil {
+			return checkError(opts, err)
+		}
+	} else if err := validateAcquirer(opts, has); err != nil {
 		return err
 	}
-	for _, logLine := range logger {
-		log.Println(logLine)
+	// truncated files are rejected unless the check is turned off, as it reads every file to the end instead of
+	// its header line. validate checks the trailers of a path on its own
+	if has["trailer"] {
+		for _, t := range opts.targets {
+			t.service.SetVerifyTrailer(opts.trailer)
+		}
+	}
+	if opts.encoding != "" {
+		for _, t := range opts.targets {
+			if err := t.service.SetEncoding(opts.encoding); err != nil {
+				return &UsageError{Err: err}
+			}
+		}
+	}
+	if has["from"] {
+		var err error
+		if opts.initDate, err = parseDate("from", opts.from); err != nil {
+			return err
+		}
+		if opts.endDate, err = parseDate("to", opts.to); err != nil {
+			return err
+		}
+		if opts.initDate.After(opts.endDate) {
+			return usageErrorf("from date after to date")
+		}
+	}
+	if has["file"] {
+		if err := validateFile(opts.file); err != nil {
+			return err
+		}
+	}
+	if has["erp"] && opts.erp != "" {
+		if err := validatePath(opts.erp); err != nil {
+			return err
+		}
+	}
+	if has["bank"] {
+		if opts.bank == "" {
+			return usageErrorf("bank not found (should be --bank directory)")
+		}
+		if err := validatePath(opts.bank); err != nil {
+			return err
+		}
+	}
+	if has["stable"] && opts.stable < 0 {
+		return usageErrorf("stable interval error (should be a number of seconds)")
+	}
+	if has["rate"] && opts.rate < 0 {
+		return usageErrorf("rate error (should be a monthly percent rate)")
+	}
+	return nil
+}
+
+// validateAcquirer checks the acquirer and path flags and creates the service of the acquirer
+func validateAcquirer(opts *options, has map[string]bool) error {
+	if has["path"] {
+		if err := validatePath(opts.path); err != nil {
+			return err
+		}
+	}
+	if has["acquirer"] {
+		if opts.acquirer == "" {
+			return usageErrorf("acquirer not found (should be --acquirer %s)", strings.Join(getAcquirerNames(), "|"))
+		}
+		service, err := getAcquirerService(opts.acquirer)
+		if err != nil {
+			return err
+		}
+		opts.service = service
+		opts.targets = []target{{acquirer: opts.acquirer, path: opts.path, service: service}}
 	}
 	return nil
 }
 
-func gaps(log ports.LoggerInterface, service ports.ServiceInterface, path string, args []string) error {
-	initDate, endDate, err := gapsExtraParam(args)
+// validateProfile loads the profile of the config file and creates a target for each of its acquirers.
+// The acquirer, path and quarantine flags override the profile values
+func validateProfile(opts *options) error {
+	file := opts.config
+	if file == "" {
+		file = os.Getenv(config.EnvPrefix + "CONFIG")
+	}
+	if file == "" {
+		file = configFile
+	}
+	cfg, err := config.Load(file)
 	if err != nil {
 		return err
 	}
-	dates, err := service.GetGapGrouped(path, initDate, endDate)
+	profile, err := cfg.GetProfile(opts.profile)
 	if err != nil {
-		return err
+		return &UsageError{Err: err}
+	}
+	if opts.quarantine == "" {
+		opts.quarantine = profile.Quarantine
+	}
+	if opts.bank == "" {
+		opts.bank = profile.Bank.Path
+	}
+	if profile.Bank.DayTolerance < 0 {
+		return fmt.Errorf("profile %s bank error: day tolerance should not be negative", opts.profile)
 	}
-	for _, date := range dates {
-		log.Printf("%s", date)
+	opts.bankDays = profile.Bank.DayTolerance
+	calendar, err := domain.NewCalendar(profile.Calendar.Weekdays, profile.Calendar.Holidays)
+	if err != nil {
+		return fmt.Errorf("profile %s calendar error: %v", opts.profile, err)
+	}
+	opts.erpEncoding = profile.Encoding
+	if opts.erpLayout, err = getErpLayout(profile.Erp); err != nil {
+		return fmt.Errorf("profile %s erp error: %v", opts.profile, err)
+	}
+	acquirers := profile.Acquirers
+	if opts.acquirer != "" {
+		acquirers = []string{opts.acquirer}
 	}
+	if len(acquirers) == 0 {
+		return usageErrorf("profile %s has no acquirers", opts.profile)
+	}
+	opts.targets = make([]target, 0, len(acquirers))
+	for _, acquirer := range acquirers {
+		path := opts.path
+		if path == "" {
+			path = profile.GetPath(acquirer)
+		}
+		if err := validatePath(path); err != nil {
+			return err
+		}
+		service, err := getAcquirerService(acquirer)
+		if err != nil {
+			return err
+		}
+		if profile.NameTemplate != "" {
+			if err := service.SetNameTemplate(profile.NameTemplate); err != nil {
+				return fmt.Errorf("profile %s error: %v", opts.profile, err)
+			}
+		}
+		service.SetCalendar(calendar)
+		if encoding := profile.GetEncoding(acquirer); encoding != "" {
+			if err := service.SetEncoding(encoding); err != nil {
+				return fmt.Errorf("profile %s error: %v", opts.profile, err)
+			}
+		}
+		opts.targets = append(opts.targets, target{acquirer: acquirer, path: path, headquarters: profile.Headquarters, service: service})
+	}
+	opts.service = opts.targets[0].service
+	opts.acquirer = opts.targets[0].acquirer
+	opts.path = opts.targets[0].path
 	return nil
 }
 
-func periods(log ports.LoggerInterface, service ports.ServiceInterface, path string, args []string) error {
-	dates, err := service.GetPeriodGrouped(path)
+// getErpLayout returns the layout of the ERP sales exports of a profile. Column fields are case insensitive
+func getErpLayout(erp config.Erp) (ports.ErpLayout, error) {
+	if erp.AmountTolerance < 0 || erp.DayTolerance < 0 {
+		return ports.ErpLayout{}, fmt.Errorf("tolerances should not be negative")
+	}
+	layout := ports.ErpLayout{DateFormat: erp.DateFormat, DayTolerance: erp.DayTolerance,
+		AmountTolerance: ports.Money(math.Round(erp.AmountTolerance * 100))}
+	if len(erp.Columns) > 0 {
+		layout.Columns = make(map[string]string)
+		for field, column := range erp.Columns {
+			layout.Columns[strings.ToLower(field)] = column
+		}
+	}
+	return layout, nil
+}
+
+func validateOutput(format string) error {
+	for _, f := range outputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return usageErrorf("output format %s not found (should be %s)", format, strings.Join(outputFormats, ", "))
+}
+
+func validatePath(path string) error {
+	if path == "" {
+		return usageErrorf("path not found (should be --path directory)")
+	}
+	dir, err := os.Stat(path)
 	if err != nil {
-		return err
+		return &UsageError{Err: err}
 	}
-	for _, date := range dates {
-		log.Printf("%s", date)
+	if !dir.IsDir() {
+		return usageErrorf("dir %s do not exists", path)
 	}
 	return nil
 }
 
-func gapsExtraParam(args []string) (time.Time, time.Time, error) {
-	zeroTime := time.Time{}
-	if len(args) < 5 {
-		err := fmt.Errorf("not enouth parameters (should by ./command-line command path initialDate finalDate)")
-		return zeroTime, zeroTime, err
+func validateFile(file string) error {
+	if file == "" {
+		return usageErrorf("file not found (should be --file name)")
 	}
-	initDate := args[4]
-	finalDate := args[5]
-	dInit, err := time.Parse("02/01/2006", initDate)
+	info, err := os.Stat(file)
 	if err != nil {
-		err := fmt.Errorf("initial date error %v", err)
-		return zeroTime, zeroTime, err
+		return &UsageError{Err: err}
 	}
-	dFinal, err := time.Parse("02/01/2006", finalDate)
-	if err != nil {
-		err := fmt.Errorf("final date error %v", err)
-		return zeroTime, zeroTime, err
+	if info.IsDir() {
+		return usageErrorf("%s is a directory (should be a file)", file)
 	}
-	return dInit, dFinal, nil
+	return nil
 }
 
-func getCommand(args []string) (interface{}, error) {
-	if len(args) < 2 {
-		return nil, fmt.Errorf("command not found (should be ./command-line command acquirer path")
+func parseDate(name string, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, usageErrorf("%s date not found (should be --%s dd/mm/yyyy)", name, name)
 	}
-	command := strings.ToLower(args[1])
-	if command == "" {
-		return nil, fmt.Errorf("command not found (should be ./command-line command acquirer path")
+	for _, format := range inputDateFormats {
+		if d, err := time.Parse(format, value); err == nil {
+			return d, nil
+		}
 	}
-	if _, ok := funcMap[command]; !ok {
-		return nil, fmt.Errorf("command %s not found (should be rename, gaps or periods)", command)
+	return time.Time{}, usageErrorf("%s date error %s (should be dd/mm/yyyy or yyyy-mm-dd)", name, value)
+}
+
+func getCommand(name string) (command, error) {
+	name = strings.ToLower(name)
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, nil
+		}
 	}
-	return funcMap[command], nil
+	return command{}, usageErrorf("command %s not found (should be %s)", name, strings.Join(getCommandNames(), ", "))
 }
 
-func getAcquirer(args []string) (ports.HeaderDataInterface, error) {
-	if len(args) < 3 {
-		return nil, fmt.Errorf("command not found (should be ./command-line command acquirer path")
+func getCommandNames() []string {
+	names := make([]string, 0, len(commands))
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
 	}
-	acquirer := args[2]
-	if acquirer == "" {
-		return nil, fmt.Errorf("command not found (should be ./command-line command acquirer path")
+	return names
+}
+
+func programName(args []string) string {
+	if len(args) == 0 || args[0] == "" {
+		return "cielo-edi"
 	}
-	if _, ok := acquirerMap[acquirer]; !ok {
-		return nil, fmt.Errorf("acquirer name %s not found (should be cielovendas, cielofinanceiro, cieloantecipacoes, redecredito, redebito, redefinanceiro, getnet)", acquirer)
+	return filepath.Base(args[0])
+}
+
+func (cm CommandLine) printHelp(program string) {
+	fmt.Fprintf(cm.writer, "Usage: %s <command> [flags]\n\nCommands:\n", program)
+	for _, cmd := range commands {
+		fmt.Fprintf(cm.writer, "  %-12s %s\n", cmd.name, cmd.description)
 	}
-	return acquirerMap[acquirer], nil
+	fmt.Fprintf(cm.writer, "\nRun '%s help <command>' for the flags of a command.\n", program)
 }
 
-func getParserType(args []string) (string, error) {
-	if len(args) < 3 {
-		return "", fmt.Errorf("command not found (should be ./command-line command acquirer path")
+func (cm CommandLine) printCommandHelp(cmd command, program string) {
+	usage := fmt.Sprintf("Usage: %s %s", program, cmd.name)
+	if len(cmd.flags) > 0 {
+		usage += " [flags]"
 	}
-	acquirer := args[2]
-	if acquirer == "" {
-		return "", fmt.Errorf("command not found (should be ./command-line command acquirer path")
+	fmt.Fprintf(cm.writer, "%s\n\n%s\n", usage, cmd.description)
+	if len(cmd.positional) > 0 {
+		fmt.Fprintf(cm.writer, "\nArguments can also be given in order: %s %s %s\n", program, cmd.name, strings.Join(cmd.positional, " "))
 	}
-	if _, ok := acquirerMap[acquirer]; !ok {
-		return "", fmt.Errorf("acquirer name %s not found (should be cielovendas, cielofinanceiro, cieloantecipacoes, redecredito, redebito, redefinanceiro, getnet)", acquirer)
+	if len(cmd.flags) > 0 {
+		fmt.Fprintf(cm.writer, "\nFlags:\n")
+		fset := cm.newFlagSet(cmd, &options{})
+		fset.SetOutput(cm.writer)
+		fset.PrintDefaults()
 	}
-	return parserTypeMap[acquirer], nil
 }
 
-func getPath(args []string) (string, error) {
-	if len(args) < 4 {
-		return "", fmt.Errorf("wrong number of parameters (should be ./command-line command acquirer path")
+func newService(headerData ports.HeaderDataInterface, trailerData ports.TrailerDataInterface,
+	newDetail func(ports.StringParserInterface) *domain.Detail, parserType string) ports.ServiceInterface {
+	parser := string_parser.NewStringParser(parserType)
+	manager := file_manager.NewFileManager()
+	header := domain.NewHeader(headerData, parser)
+	service := services.NewService(manager, header)
+	if trailerData != nil {
+		service.SetTrailer(domain.NewTrailer(trailerData, parser))
 	}
-	path := args[3]
-	if path == "" {
-		return "", fmt.Errorf("command not found (should be ./command-line command acquirer path")
+	if newDetail != nil {
+		service.SetDetail(newDetail(parser))
 	}
-	dir, err := os.Stat(path)
+	return service
+}
+
+// getAcquirerService creates a service for an acquirer name with its own header, trailer and detail data,
+// so services can parse files concurrently
+func getAcquirerService(acquirer string) (ports.ServiceInterface, error) {
+	data, ok := acquirerMap[acquirer]
+	if !ok {
+		return nil, usageErrorf("acquirer name %s not found (should be %s)", acquirer, strings.Join(getAcquirerNames(), ", "))
+	}
+	var trailerData ports.TrailerDataInterface
+	if trailer, ok := trailerMap[acquirer]; ok {
+		trailerData = copyData(trailer).(ports.TrailerDataInterface)
+	}
+	return newService(copyData(data).(ports.HeaderDataInterface), trailerData, detailMap[acquirer], parserTypeMap[acquirer]), nil
+}
+
+// copyData returns a pointer to a copy of the struct pointed by data
+func copyData(data interface{}) interface{} {
+	value := reflect.New(reflect.TypeOf(data).Elem())
+	value.Elem().Set(reflect.ValueOf(data).Elem())
+	return value.Interface()
+}
+
+func getAcquirerNames() []string {
+	names := make([]string, 0, len(acquirerMap))
+	for name := range acquirerMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func rename(cm *CommandLine, opts *options) error {
+	results := make([]ports.RenameResult, 0)
+	for _, t := range opts.targets {
+		r, err := t.service.FormatNamesQuarantine(t.path, opts.quarantine)
+		if err != nil {
+			return err
+		}
+		results = append(results, r...)
+	}
+	return writeRenameResults(opts.out, results)
+}
+
+func gaps(cm *CommandLine, opts *options) error {
+	if opts.profile == "" {
+		dates, err := opts.service.GetGapGrouped(opts.path, opts.initDate, opts.endDate)
+		if err != nil {
+			return err
+		}
+		return writeDateRanges(opts.out, dates)
+	}
+	return writeCoverages(opts.out, opts.targets, func(t target, headquarter int64) ([]ports.DateRange, error) {
+		return t.service.GetHeadquarterGapGrouped(t.path, headquarter, opts.initDate, opts.endDate)
+	})
+}
+
+// check summarizes the gaps of each target and headquarter. It returns an ExitError with ExitGaps
+// when days are missing and with ExitCheckError when the check fails
+func check(cm *CommandLine, opts *options) error {
+	summaries := make([]ports.GapSummary, 0)
+	for _, t := range opts.targets {
+		s, err := t.service.GetGapSummary(t.path, t.headquarters, opts.initDate, opts.endDate)
+		if err != nil {
+			if opts.textfile != "" {
+				writeTextfile(opts.textfile, nil, false)
+			}
+			return &ExitError{Code: ExitCheckError, Err: err}
+		}
+		summaries = append(summaries, s...)
+	}
+	if opts.textfile != "" {
+		if err := writeTextfile(opts.textfile, summaries, true); err != nil {
+			return &ExitError{Code: ExitCheckError, Err: err}
+		}
+	}
+	if err := writeGapSummaries(opts.out, summaries); err != nil {
+		return &ExitError{Code: ExitCheckError, Err: err}
+	}
+	if missing := getMissingDays(summaries); missing > 0 {
+		return &ExitError{Code: ExitGaps, Err: fmt.Errorf("%d missing days", missing)}
+	}
+	return nil
+}
+
+// checkError returns the profile errors of the check command with ExitCheckError, so a check that could not
+// run is not reported as gaps (ExitFailure is ExitGaps). Usage errors keep ExitUsage
+func checkError(opts *options, err error) error {
+	var usage *UsageError
+	if opts.command.name != "check" || errors.As(err, &usage) {
+		return err
+	}
+	if opts.textfile != "" {
+		writeTextfile(opts.textfile, nil, false)
+	}
+	return &ExitError{Code: ExitCheckError, Err: err}
+}
+
+func periods(cm *CommandLine, opts *options) error {
+	if opts.profile == "" {
+		dates, err := opts.service.GetPeriodGrouped(opts.path)
+		if err != nil {
+			return err
+		}
+		return writeDateRanges(opts.out, dates)
+	}
+	return writeCoverages(opts.out, opts.targets, func(t target, headquarter int64) ([]ports.DateRange, error) {
+		return t.service.GetHeadquarterPeriodGrouped(t.path, headquarter)
+	})
+}
+
+// validateFiles checks the trailer of the files of each target. It returns an ExitError with ExitFailure
+// when files are truncated
+func validateFiles(cm *CommandLine, opts *options) error {
+	validations := make([]ports.FileValidation, 0)
+	for _, t := range opts.targets {
+		v, err := t.service.ValidateFiles(t.path)
+		if err != nil {
+			return err
+		}
+		validations = append(validations, v...)
+	}
+	if err := writeValidations(opts.out, validations); err != nil {
+		return err
+	}
+	truncated, invalid := 0, 0
+	for _, v := range validations {
+		switch v.Stage {
+		case services.TrailerStage:
+			truncated++
+		case services.RecordStage, services.TotalsStage:
+			invalid++
+		}
+	}
+	problems := make([]string, 0, 2)
+	if truncated > 0 {
+		problems = append(problems, fmt.Sprintf("%d truncated files", truncated))
+	}
+	if invalid > 0 {
+		problems = append(problems, fmt.Sprintf("%d files with invalid records or totals", invalid))
+	}
+	if len(problems) > 0 {
+		return &ExitError{Code: ExitFailure, Err: errors.New(strings.Join(problems, ", "))}
+	}
+	return nil
+}
+
+// detailTargets returns the targets with detail records. Acquirers of a profile without them are skipped,
+// while a target given by path is kept so that its error is reported
+func detailTargets(opts *options) []target {
+	targets := make([]target, 0, len(opts.targets))
+	for _, t := range opts.targets {
+		if _, ok := detailMap[t.acquirer]; !ok && opts.profile != "" {
+			continue
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// settlements lists the daily settlements of each target. Acquirers of a profile without detail
+// records are skipped
+func settlements(cm *CommandLine, opts *options) error {
+	results := make([]acquirerSettlement, 0)
+	for _, t := range detailTargets(opts) {
+		s, err := t.service.GetDailySettlements(t.path)
+		if err != nil {
+			return err
+		}
+		for _, settlement := range s {
+			results = append(results, acquirerSettlement{Acquirer: t.acquirer, DailySettlement: settlement})
+		}
+	}
+	return writeSettlements(opts.out, results)
+}
+
+// sales lists the sales totals per day and card brand of each target. Acquirers of a profile without detail
+// records are skipped
+func sales(cm *CommandLine, opts *options) error {
+	results := make([]acquirerSalesTotal, 0)
+	for _, t := range detailTargets(opts) {
+		s, err := t.service.GetSalesTotals(t.path)
+		if err != nil {
+			return err
+		}
+		for _, total := range s {
+			results = append(results, acquirerSalesTotal{Acquirer: t.acquirer, SalesTotal: total})
+		}
+	}
+	return writeSalesTotals(opts.out, results)
+}
+
+// anticipations lists the effective rate of the anticipation operations of each target. Acquirers of a profile
+// without detail records are skipped
+func anticipations(cm *CommandLine, opts *options) error {
+	results := make([]acquirerAnticipationCost, 0)
+	for _, t := range detailTargets(opts) {
+		c, err := t.service.GetAnticipationCosts(t.path)
+		if err != nil {
+			return err
+		}
+		for _, cost := range c {
+			above := opts.rate > 0 && cost.MonthlyRate*100 > opts.rate
+			results = append(results, acquirerAnticipationCost{Acquirer: t.acquirer, AnticipationCost: cost, AboveRate: above})
+		}
+	}
+	return writeAnticipationCosts(opts.out, results)
+}
+
+// receivables lists the outstanding receivables of each target. Acquirers of a profile without detail
+// records are skipped
+func receivables(cm *CommandLine, opts *options) error {
+	results := make([]acquirerReceivable, 0)
+	for _, t := range detailTargets(opts) {
+		r, err := t.service.GetOutstandingReceivables(t.path)
+		if err != nil {
+			return err
+		}
+		for _, receivable := range r {
+			results = append(results, acquirerReceivable{Acquirer: t.acquirer, OutstandingReceivable: receivable})
+		}
+	}
+	return writeReceivables(opts.out, results)
+}
+
+// reconcile matches the payments scheduled by the sales statements of the targets to the settlements of their financial
+// statements. The financial statement of a sales statement that is not a target is read from the same path
+func reconcile(cm *CommandLine, opts *options) error {
+	targets := detailTargets(opts)
+	layouts := make(map[string]bool)
+	for _, t := range targets {
+		layouts[t.acquirer] = true
+	}
+	if opts.erp != "" {
+		return reconcileSales(opts, targets)
+	}
+	for _, t := range targets {
+		acquirer, ok := settlementMap[t.acquirer]
+		if !ok || layouts[acquirer] {
+			continue
+		}
+		service, err := getAcquirerService(acquirer)
+		if err != nil {
+			return err
+		}
+		service.SetVerifyTrailer(opts.trailer)
+		if opts.encoding != "" {
+			if err := service.SetEncoding(opts.encoding); err != nil {
+				return &UsageError{Err: err}
+			}
+		}
+		targets = append(targets, target{acquirer: acquirer, path: t.path, headquarters: t.headquarters, service: service})
+		layouts[acquirer] = true
+	}
+	scheduled := make([]ports.ScheduledPayment, 0)
+	settlements := make([]ports.Transaction, 0)
+	for _, t := range targets {
+		s, err := t.service.GetScheduledPayments(t.path)
+		if err != nil {
+			return err
+		}
+		scheduled = append(scheduled, s...)
+		r, err := t.service.GetTransactions(t.path)
+		if err != nil {
+			return err
+		}
+		settlements = append(settlements, r...)
+	}
+	return writeReconciliations(opts.out, services.Reconcile(scheduled, settlements))
+}
+
+// reconcileSales matches the sales of the ERP exports to the sales of the targets with the ERP layout of the profile,
+// or the default columns without it. The exports are read with the encoding flag or the encoding of the profile
+func reconcileSales(opts *options, targets []target) error {
+	layout := opts.erpLayout
+	if len(layout.Columns) == 0 {
+		layout.Columns = erpColumns
+	}
+	manager := file_manager.NewFileManager()
+	encoding := opts.encoding
+	if encoding == "" {
+		encoding = opts.erpEncoding
+	}
+	if encoding != "" {
+		if err := manager.SetEncoding(encoding); err != nil {
+			return &UsageError{Err: err}
+		}
+	}
+	parser := string_parser.NewStringParser("csv")
+	reader := services.NewErpReader(manager, parser, domain.NewErpDetail(parser), layout)
+	erp, err := reader.GetSales(opts.erp)
 	if err != nil {
-		return "", err
+		return err
 	}
-	if !dir.IsDir() {
-		return "", fmt.Errorf("dir %s do not exists", path)
+	sales := make([]ports.Transaction, 0)
+	for _, t := range targets {
+		r, err := t.service.GetTransactions(t.path)
+		if err != nil {
+			return err
+		}
+		sales = append(sales, r...)
 	}
-	return path, nil
+	return writeSaleReconciliations(opts.out, services.ReconcileSales(erp, sales, layout))
 }
 
-func getArgs(args []string) (interface{}, ports.HeaderDataInterface, string, string, error) {
-	if len(args) < 4 {
-		return nil, nil, "", "", fmt.Errorf("wrong number of parameters (should be ./command-line command acquirer path")
+// deposits matches the credits of the daily settlements of the targets to the entries of the bank return files.
+// Acquirers of a profile without detail records are skipped
+func deposits(cm *CommandLine, opts *options) error {
+	settlements := make(map[string][]ports.DailySettlement)
+	for _, t := range detailTargets(opts) {
+		s, err := t.service.GetDailySettlements(t.path)
+		if err != nil {
+			return err
+		}
+		settlements[t.acquirer] = append(settlements[t.acquirer], s...)
 	}
-	command, err := getCommand(args)
+	reader := services.NewBankReader(file_manager.NewFileManager(), domain.NewCnabDetail(string_parser.NewStringParser("position")))
+	entries, err := reader.GetEntries(opts.bank)
 	if err != nil {
-		return nil, nil, "", "", err
+		return err
+	}
+	return writeDeposits(opts.out, services.ReconcileDeposits(settlements, entries, opts.bankDays))
+}
+
+// transactions lists the detail records of each target on the canonical transaction schema. Acquirers of a profile
+// without detail records are skipped
+func transactions(cm *CommandLine, opts *options) error {
+	results := make([]ports.Transaction, 0)
+	for _, t := range detailTargets(opts) {
+		r, err := t.service.GetTransactions(t.path)
+		if err != nil {
+			return err
+		}
+		results = append(results, r...)
+	}
+	return writeTransactions(opts.out, results)
+}
+
+func duplicates(cm *CommandLine, opts *options) error {
+	groups := make([]ports.DuplicateGroup, 0)
+	for _, t := range opts.targets {
+		g, err := t.service.QuarantineDuplicates(t.path, opts.quarantine, opts.logical)
+		if err != nil {
+			return err
+		}
+		groups = append(groups, g...)
 	}
-	acquirer, err := getAcquirer(args)
+	return writeDuplicates(opts.out, groups)
+}
+
+func requeue(cm *CommandLine, opts *options) error {
+	results := make([]ports.RenameResult, 0)
+	for _, t := range opts.targets {
+		quarantine := opts.quarantine
+		if quarantine == "" {
+			quarantine = filepath.Join(t.path, "quarantine")
+		}
+		r, err := t.service.Requeue(t.path, quarantine)
+		if err != nil {
+			return err
+		}
+		results = append(results, r...)
+	}
+	return writeRenameResults(opts.out, results)
+}
+
+func watch(cm *CommandLine, opts *options) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	watcher := file_watcher.NewFileWatcher(watchInterval, time.Duration(opts.stable)*time.Second)
+	return watchFiles(ctx, opts.out, opts.service, watcher, opts.path)
+}
+
+func watchFiles(ctx context.Context, out *output, service ports.ServiceInterface, watcher ports.FileWatcherInterface, path string) error {
+	out.log.Printf("watching %s", path)
+	err := watcher.Watch(ctx, path, func(file fs.FileInfo) {
+		if service.IsFormattedName(file.Name()) {
+			return
+		}
+		result := service.FormatFile(path, file, "")
+		if err := out.writeItem(result, renameLines(result), renameHeader, renameRecord(result)); err != nil {
+			out.log.Println(err)
+		}
+	})
+	out.log.Printf("stopped watching %s", path)
+	return err
+}
+
+// inspect parses the header of a file with the layout of each acquirer
+func inspect(cm *CommandLine, opts *options) error {
+	info, err := os.Stat(opts.file)
 	if err != nil {
-		return nil, nil, "", "", err
+		return err
 	}
-	path, err := getPath(args)
+	inspections := make([]ports.LayoutInspection, 0, len(acquirerMap))
+	for _, name := range getAcquirerNames() {
+		service, err := getAcquirerService(name)
+		if err != nil {
+			return err
+		}
+		if opts.encoding != "" {
+			if err := service.SetEncoding(opts.encoding); err != nil {
+				return &UsageError{Err: err}
+			}
+		}
+		inspection, err := service.Inspect(filepath.Dir(opts.file), info)
+		if err != nil {
+			return err
+		}
+		inspections = append(inspections, inspection)
+	}
+	return writeInspections(opts.out, inspections)
+}
+
+func serve(cm *CommandLine, opts *options) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	return NewHttpServer(cm.logger, opts.path).Run(ctx, opts.address)
+}
+
+func version(cm *CommandLine, opts *options) error {
+	fmt.Fprintf(cm.writer, "%s %s\n", opts.program, Version)
+	return nil
+}
+
+func completion(cm *CommandLine, opts *options) error {
+	script, err := completionScript(opts.shell, opts.program)
 	if err != nil {
-		return nil, nil, "", "", err
+		return err
 	}
-	parseType, err := getParserType(args)
+	fmt.Fprint(cm.writer, script)
+	return nil
+}
+
+func help(cm *CommandLine, opts *options) error {
+	if opts.topic == "" {
+		cm.printHelp(opts.program)
+		return nil
+	}
+	cmd, err := getCommand(opts.topic)
 	if err != nil {
-		return nil, nil, "", "", err
+		return err
 	}
-	return command, acquirer, parseType, path, nil
+	cm.printCommandHelp(cmd, opts.program)
+	return nil
 }
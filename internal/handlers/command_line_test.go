@@ -1,11 +1,22 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/lavinas/cielo-edi/internal/core/domain"
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/lavinas/cielo-edi/internal/core/services"
+	"github.com/lavinas/cielo-edi/internal/utils/file_manager"
+	"github.com/lavinas/cielo-edi/internal/utils/file_watcher"
+	"github.com/lavinas/cielo-edi/internal/utils/string_parser"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -34,11 +45,19 @@ const (
 	cielofinanc = "010238632322021063020210630202106300008358CIELO04I                    014"
 	cielosales  = "010238632322021031020210310202103100008246CIELO03I                    013 "
 	cieloant    = "010238632322021051120210511202105110008308CIELO06I                    013"
+	// cieloRoTail has the fields of an operation summary (RO) between the adjustment reason and the brand
+	cieloRoTail = "0000000000000" + " " + "000000000" + "+" + "0000000000000"
 	redecredit  = "00207022021REDECARDEXTRATO DE MOVIMENTO DE VENDASNESPRESSO PJM         000200021644942DIARIO         V2.01 - 09/06 - EEVC"
 	redefin     = "03029092021RedecardExtrato de Movimentacao FinanceiraNESPRESSO PJM         000434021644942DIARIO         V3.01 - 09/06 - EEFI"
 	rededebt    = "00,021644942,22092021,21092021,Movimentacao diaria - Cartoes de Debito,Redecard,NESPRESSO PJM             ,000427,DIARIO         ,V1.04 - 07/10 - EEVD"
 	getnet      = "02307202106154023072021CEADM1001447355        10440482000154GETNET S.A.         000002146GS                         "
 	cieloalelo  = "011013496782020123120201231202012310008177CIELO10I                    013"
+	// trailers of the files with a header and no detail records (2 records)
+	cieloTrailer      = "900000000002"
+	redecreditTrailer = "028" + "0001" + "000002"
+	redefinTrailer    = "052" + "0001" + "000002"
+	rededebtTrailer   = "04,0001,000002"
+	getnetTrailer     = "9" + "000000002"
 )
 
 //------------------------------------------------------------------------------------------
@@ -60,6 +79,11 @@ func endPath(path string) {
 	}
 }
 
+// statement returns the content of a complete file with a header and a trailer and no detail records
+func statement(header string, trailer string) string {
+	return header + "\r\n" + trailer
+}
+
 func createFile(path string, name string, header string) string {
 	fn := filepath.Join(path, name)
 	os.WriteFile(fn, []byte(header), 0755)
@@ -78,7 +102,7 @@ func TestPeriodsCieloSales(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f1"
 	initPath(path)
-	createFile(path, "test1.txt", cielosales)
+	createFile(path, "test1.txt", statement(cielosales, cieloTrailer))
 	args := []string{"pm", "periods", "cielovendas", path}
 	err := cm.Run(args)
 	assert.Nil(t, err)
@@ -93,7 +117,7 @@ func TestGapsCieloSales(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f2"
 	initPath(path)
-	createFile(path, "test1.txt", cielosales)
+	createFile(path, "test1.txt", statement(cielosales, cieloTrailer))
 	args := []string{"pm", "gaps", "cielovendas", path, "01/03/2021", "30/03/2021"}
 	err := cm.Run(args)
 	assert.Nil(t, err)
@@ -109,9 +133,9 @@ func TestRenameCieloSales(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f3"
 	initPath(path)
-	fn := createFile(path, "test1.txt", cielosales)
+	fn := createFile(path, "test1.txt", statement(cielosales, cieloTrailer))
 	assert.True(t, fileExists(fn))
-	fn = createFile(path, "test2.txt", cielofinanc)
+	fn = createFile(path, "test2.txt", statement(cielofinanc, cieloTrailer))
 	assert.True(t, fileExists(fn))
 	args := []string{"pm", "rename", "cielovendas", path}
 	err := cm.Run(args)
@@ -128,7 +152,7 @@ func TestPeriodsRedeSales(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f4"
 	initPath(path)
-	createFile(path, "test1.txt", redecredit)
+	createFile(path, "test1.txt", statement(redecredit, redecreditTrailer))
 	args := []string{"pm", "periods", "redecredito", path}
 	err := cm.Run(args)
 	assert.Nil(t, err)
@@ -143,7 +167,7 @@ func TestGapsRedeSales(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f5"
 	initPath(path)
-	createFile(path, "test1.txt", redecredit)
+	createFile(path, "test1.txt", statement(redecredit, redecreditTrailer))
 	args := []string{"pm", "gaps", "redecredito", path, "01/01/2021", "31/12/2021"}
 	err := cm.Run(args)
 	assert.Nil(t, err)
@@ -159,9 +183,9 @@ func TestRenameRedeSales(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f6"
 	initPath(path)
-	fn := createFile(path, "test1.txt", redecredit)
+	fn := createFile(path, "test1.txt", statement(redecredit, redecreditTrailer))
 	assert.True(t, fileExists(fn))
-	fn = createFile(path, "test2.txt", redefin)
+	fn = createFile(path, "test2.txt", statement(redefin, redefinTrailer))
 	assert.True(t, fileExists(fn))
 	args := []string{"pm", "rename", "redecredito", path}
 	err := cm.Run(args)
@@ -178,7 +202,7 @@ func TestPeriodsRedeFin(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f7"
 	initPath(path)
-	createFile(path, "test1.txt", redefin)
+	createFile(path, "test1.txt", statement(redefin, redefinTrailer))
 	args := []string{"pm", "periods", "redefinanceiro", path}
 	err := cm.Run(args)
 	assert.Nil(t, err)
@@ -193,7 +217,7 @@ func TestGapsRedeFin(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f8"
 	initPath(path)
-	createFile(path, "test1.txt", redefin)
+	createFile(path, "test1.txt", statement(redefin, redefinTrailer))
 	args := []string{"pm", "gaps", "redefinanceiro", path, "01/01/2021", "31/12/2021"}
 	err := cm.Run(args)
 	assert.Nil(t, err)
@@ -209,9 +233,9 @@ func TestRenameRedeFin(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f9"
 	initPath(path)
-	fn := createFile(path, "test1.txt", redefin)
+	fn := createFile(path, "test1.txt", statement(redefin, redefinTrailer))
 	assert.True(t, fileExists(fn))
-	fn = createFile(path, "test2.txt", redecredit)
+	fn = createFile(path, "test2.txt", statement(redecredit, redecreditTrailer))
 	assert.True(t, fileExists(fn))
 	args := []string{"pm", "rename", "redefinanceiro", path}
 	err := cm.Run(args)
@@ -228,7 +252,7 @@ func TestPeriodsRedeDebt(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f10"
 	initPath(path)
-	createFile(path, "test1.txt", rededebt)
+	createFile(path, "test1.txt", statement(rededebt, rededebtTrailer))
 	args := []string{"pm", "periods", "rededebito", path}
 	err := cm.Run(args)
 	assert.Nil(t, err)
@@ -243,7 +267,7 @@ func TestGapsRedeDebt(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f11"
 	initPath(path)
-	createFile(path, "test1.txt", rededebt)
+	createFile(path, "test1.txt", statement(rededebt, rededebtTrailer))
 	args := []string{"pm", "gaps", "rededebito", path, "01/01/2021", "31/12/2021"}
 	err := cm.Run(args)
 	assert.Nil(t, err)
@@ -259,9 +283,9 @@ func TestRenameRedeDebt(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f12"
 	initPath(path)
-	fn := createFile(path, "test1.txt", rededebt)
+	fn := createFile(path, "test1.txt", statement(rededebt, rededebtTrailer))
 	assert.True(t, fileExists(fn))
-	fn = createFile(path, "test2.txt", redecredit)
+	fn = createFile(path, "test2.txt", statement(redecredit, redecreditTrailer))
 	assert.True(t, fileExists(fn))
 	args := []string{"pm", "rename", "rededebito", path}
 	err := cm.Run(args)
@@ -278,7 +302,7 @@ func TestPeriodsGetnet(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f13"
 	initPath(path)
-	createFile(path, "test1.txt", getnet)
+	createFile(path, "test1.txt", statement(getnet, getnetTrailer))
 	args := []string{"pm", "periods", "getnet", path}
 	err := cm.Run(args)
 	assert.Nil(t, err)
@@ -293,7 +317,7 @@ func TestGapsGetnet(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f14"
 	initPath(path)
-	createFile(path, "test1.txt", getnet)
+	createFile(path, "test1.txt", statement(getnet, getnetTrailer))
 	args := []string{"pm", "gaps", "getnet", path, "01/01/2021", "31/12/2021"}
 	err := cm.Run(args)
 	assert.Nil(t, err)
@@ -309,9 +333,9 @@ func TestRenameGetnet(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f15"
 	initPath(path)
-	fn := createFile(path, "test1.txt", getnet)
+	fn := createFile(path, "test1.txt", statement(getnet, getnetTrailer))
 	assert.True(t, fileExists(fn))
-	fn = createFile(path, "test2.txt", redecredit)
+	fn = createFile(path, "test2.txt", statement(redecredit, redecreditTrailer))
 	assert.True(t, fileExists(fn))
 	args := []string{"pm", "rename", "getnet", path}
 	err := cm.Run(args)
@@ -328,7 +352,7 @@ func TestPeriodsCieloAlelo(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f1"
 	initPath(path)
-	createFile(path, "test1.txt", cieloalelo)
+	createFile(path, "test1.txt", statement(cieloalelo, cieloTrailer))
 	args := []string{"pm", "periods", "cieloalelo", path}
 	err := cm.Run(args)
 	assert.Nil(t, err)
@@ -343,7 +367,7 @@ func TestGapsCieloAlelo(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f2"
 	initPath(path)
-	createFile(path, "test1.txt", cieloalelo)
+	createFile(path, "test1.txt", statement(cieloalelo, cieloTrailer))
 	args := []string{"pm", "gaps", "cieloalelo", path, "01/12/2020", "30/03/2021"}
 	err := cm.Run(args)
 	assert.Nil(t, err)
@@ -359,9 +383,9 @@ func TestRenameCieloAlelo(t *testing.T) {
 	cm := NewCommandLine(logx)
 	path := "./f3"
 	initPath(path)
-	fn := createFile(path, "test1.txt", cieloalelo)
+	fn := createFile(path, "test1.txt", statement(cieloalelo, cieloTrailer))
 	assert.True(t, fileExists(fn))
-	fn = createFile(path, "test2.txt", cielofinanc)
+	fn = createFile(path, "test2.txt", statement(cielofinanc, cieloTrailer))
 	assert.True(t, fileExists(fn))
 	args := []string{"pm", "rename", "cieloalelo", path}
 	err := cm.Run(args)
@@ -372,3 +396,965 @@ func TestRenameCieloAlelo(t *testing.T) {
 	assert.Equal(t, "No: test2.txt - invalid file", result[1])
 	endPath(path)
 }
+
+func TestDuplicatesCieloSales(t *testing.T) {
+	logx := NewLoggerMock()
+	cm := NewCommandLine(logx)
+	path := "./f16"
+	initPath(path)
+	createFile(path, "test1.txt", statement(cielosales, cieloTrailer))
+	createFile(path, "test2.txt", statement(cielosales, cieloTrailer))
+	createFile(path, "test3.txt", statement(cielosales, cieloTrailer)+"\n")
+	createFile(path, "test4.txt", statement(cielofinanc, cieloTrailer))
+	args := []string{"pm", "duplicates", "cielovendas", path}
+	err := cm.Run(args)
+	assert.Nil(t, err)
+	result := logx.GetLines()
+	assert.Len(t, result, 2)
+	assert.True(t, strings.HasPrefix(result[0], "Exact: test1.txt, test2.txt - "))
+	assert.Equal(t, "Logical: test1.txt, test3.txt - CIELO-1023863232-03-2021_03_10-2021_03_10-0008246", result[1])
+	quarantine := filepath.Join(path, "quarantine")
+	logx = NewLoggerMock()
+	cm = NewCommandLine(logx)
+	args = []string{"pm", "duplicates", "cielovendas", path, quarantine}
+	err = cm.Run(args)
+	assert.Nil(t, err)
+	result = logx.GetLines()
+	assert.Len(t, result, 3)
+	assert.Equal(t, "Moved: test2.txt - "+filepath.Join(quarantine, "test2.txt"), result[2])
+	assert.False(t, fileExists(filepath.Join(path, "test2.txt")))
+	assert.True(t, fileExists(filepath.Join(quarantine, "test2.txt")))
+	// a later processing of the same statement keeps the latest and moves the older copies
+	createFile(path, "test5.txt", statement(strings.Replace(cielosales, "20210310", "20210311", 1), cieloTrailer))
+	logx = NewLoggerMock()
+	cm = NewCommandLine(logx)
+	args = []string{"pm", "duplicates", "cielovendas", path, quarantine}
+	err = cm.Run(args)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Logical: test5.txt, test1.txt, test3.txt - CIELO-1023863232-03-2021_03_10-2021_03_10-0008246"}, logx.GetLines())
+	assert.True(t, fileExists(filepath.Join(path, "test1.txt")))
+	logx = NewLoggerMock()
+	cm = NewCommandLine(logx)
+	args = []string{"pm", "duplicates", "cielovendas", path, quarantine, "--logical"}
+	err = cm.Run(args)
+	assert.Nil(t, err)
+	result = logx.GetLines()
+	assert.Len(t, result, 3)
+	assert.Equal(t, "Moved: test1.txt - "+filepath.Join(quarantine, "test1.txt"), result[1])
+	assert.Equal(t, "Moved: test3.txt - "+filepath.Join(quarantine, "test3.txt"), result[2])
+	assert.True(t, fileExists(filepath.Join(path, "test5.txt")))
+	assert.False(t, fileExists(filepath.Join(path, "test1.txt")))
+	endPath(path)
+}
+
+func TestRenameQuarantineRequeue(t *testing.T) {
+	logx := NewLoggerMock()
+	cm := NewCommandLine(logx)
+	path := "./f17"
+	initPath(path)
+	createFile(path, "test1.txt", statement(cielosales, cieloTrailer))
+	createFile(path, "test2.txt", statement(cielofinanc, cieloTrailer))
+	quarantine := filepath.Join(path, "quarantine")
+	args := []string{"pm", "rename", "cielovendas", path, quarantine}
+	err := cm.Run(args)
+	assert.Nil(t, err)
+	result := logx.GetLines()
+	assert.Len(t, result, 3)
+	assert.Equal(t, "No: test2.txt - invalid file", result[1])
+	assert.Equal(t, "Quarantined: test2.txt - "+filepath.Join(quarantine, "test2.txt"), result[2])
+	assert.False(t, fileExists(filepath.Join(path, "test2.txt")))
+	assert.True(t, fileExists(filepath.Join(quarantine, "test2.txt")))
+	data, err := os.ReadFile(filepath.Join(quarantine, "test2.txt.reason.json"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"stage": "validate"`)
+	assert.Contains(t, string(data), `"cielovendas"`)
+	// still invalid for cielovendas
+	logx = NewLoggerMock()
+	cm = NewCommandLine(logx)
+	args = []string{"pm", "requeue", "cielovendas", path}
+	err = cm.Run(args)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"No: test2.txt - invalid file"}, logx.GetLines())
+	// accepted by cielofinanceiro layout
+	logx = NewLoggerMock()
+	cm = NewCommandLine(logx)
+	args = []string{"pm", "requeue", "cielofinanceiro", path}
+	err = cm.Run(args)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Yes: test2.txt - " + filepath.Join(path, "test2.txt")}, logx.GetLines())
+	assert.True(t, fileExists(filepath.Join(path, "test2.txt")))
+	assert.False(t, fileExists(filepath.Join(quarantine, "test2.txt.reason.json")))
+	endPath(path)
+}
+
+func TestWatchCieloSales(t *testing.T) {
+	logx := NewLoggerMock()
+	path := "./f18"
+	initPath(path)
+	createFile(path, "test1.txt", statement(cielosales, cieloTrailer))
+	header := domain.NewHeader(&domain.HeaderCielo{Statement: "vendas"}, string_parser.NewStringParser("position"))
+	service := services.NewService(file_manager.NewFileManager(), header)
+	watcher := file_watcher.NewFileWatcher(10*time.Millisecond, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	err := watchFiles(ctx, newOutput(tableOutput, logx, nil), service, watcher, path)
+	assert.Nil(t, err)
+	result := logx.GetLines()
+	assert.Len(t, result, 3)
+	assert.Equal(t, "watching ./f18", result[0])
+	assert.Equal(t, "Yes: test1.txt - CIELO-1023863232-03-2021_03_10-2021_03_10-N-2021_03_10-L013.txt", result[1])
+	assert.Equal(t, "stopped watching ./f18", result[2])
+	endPath(path)
+}
+
+func TestOutputJsonCsv(t *testing.T) {
+	logx := NewLoggerMock()
+	writer := &bytes.Buffer{}
+	cm := CommandLine{logger: logx, writer: writer}
+	path := "./f19"
+	initPath(path)
+	createFile(path, "test1.txt", statement(cielosales, cieloTrailer))
+	args := []string{"pm", "gaps", "cielovendas", path, "01/03/2021", "30/03/2021", "--output", "json"}
+	err := cm.Run(args)
+	assert.Nil(t, err)
+	assert.Len(t, logx.GetLines(), 0)
+	ranges := []ports.DateRange{}
+	err = json.Unmarshal(writer.Bytes(), &ranges)
+	assert.Nil(t, err)
+	assert.Len(t, ranges, 2)
+	assert.Equal(t, "2021-03-01", ranges[0].Start.Format("2006-01-02"))
+	assert.Equal(t, "2021-03-09", ranges[0].End.Format("2006-01-02"))
+	writer.Reset()
+	args = []string{"pm", "periods", "--output=csv", "cielovendas", path}
+	err = cm.Run(args)
+	assert.Nil(t, err)
+	assert.Equal(t, "start,end\n2021-03-10,2021-03-10\n", writer.String())
+	writer.Reset()
+	createFile(path, "test2.txt", statement(cielofinanc, cieloTrailer))
+	args = []string{"pm", "rename", "cielovendas", path, "--output", "csv"}
+	err = cm.Run(args)
+	assert.Nil(t, err)
+	assert.Equal(t, "file,status,newName,reason\n"+
+		"test1.txt,renamed,CIELO-1023863232-03-2021_03_10-2021_03_10-N-2021_03_10-L013.txt,\n"+
+		"test2.txt,failed,,invalid file\n", writer.String())
+	args = []string{"pm", "periods", "cielovendas", path, "--output", "xml"}
+	err = cm.Run(args)
+	assert.NotNil(t, err)
+	assert.Equal(t, "output format xml not found (should be table, json, csv)", err.Error())
+	endPath(path)
+}
+
+func TestFlags(t *testing.T) {
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	path := "./f20"
+	initPath(path)
+	createFile(path, "test1.txt", statement(cielosales, cieloTrailer))
+	args := []string{"pm", "gaps", "--acquirer", "cielovendas", "--path", path, "--from", "2021-03-01", "--to=30/03/2021"}
+	err := cm.Run(args)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"01/03/2021 - 09/03/2021", "11/03/2021 - 30/03/2021"}, logx.GetLines())
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	args = []string{"pm", "periods", "cielovendas", "--path", path}
+	err = cm.Run(args)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"10/03/2021 - 10/03/2021"}, logx.GetLines())
+	endPath(path)
+}
+
+func TestUsageErrors(t *testing.T) {
+	path := "./f21"
+	initPath(path)
+	tests := []struct {
+		args []string
+		msg  string
+	}{
+		{[]string{"pm"}, "command not found (should be rename, gaps, check, periods, validate, settlements, sales, anticipations, receivables, transactions, reconcile, deposits, duplicates, requeue, watch, inspect, serve, version, completion, help)"},
+		{[]string{"pm", "list"}, "command list not found (should be rename, gaps, check, periods, validate, settlements, sales, anticipations, receivables, transactions, reconcile, deposits, duplicates, requeue, watch, inspect, serve, version, completion, help)"},
+		{[]string{"pm", "gaps", "cielovendas", path, "01/03/2021"}, "to date not found (should be --to dd/mm/yyyy)"},
+		{[]string{"pm", "gaps", "cielovendas", path, "01/03/2021", "2021"}, "to date error 2021 (should be dd/mm/yyyy or yyyy-mm-dd)"},
+		{[]string{"pm", "gaps", "cielovendas", path, "30/03/2021", "01/03/2021"}, "from date after to date"},
+		{[]string{"pm", "periods", "redebito", path}, "acquirer name redebito not found (should be cieloalelo, cieloantecipacoes, cielocessoes, cielofinanceiro, cielosaldo, cielovendas, getnet, redecredito, rededebito, redefinanceiro)"},
+		{[]string{"pm", "periods", "--path", path}, "acquirer not found (should be --acquirer cieloalelo|cieloantecipacoes|cielocessoes|cielofinanceiro|cielosaldo|cielovendas|getnet|redecredito|rededebito|redefinanceiro)"},
+		{[]string{"pm", "periods", "cielovendas"}, "path not found (should be --path directory)"},
+		{[]string{"pm", "periods", "cielovendas", path, "extra"}, "too many arguments for periods (see pm help periods)"},
+		{[]string{"pm", "periods", "--acquirer", "cielovendas", "cielovendas", path}, "acquirer given as flag and as argument"},
+		{[]string{"pm", "periods", "--unknown", "cielovendas", path}, "flag provided but not defined: -unknown"},
+		{[]string{"pm", "periods", "cielovendas", path, "--output", "xml"}, "output format xml not found (should be table, json, csv)"},
+		{[]string{"pm", "completion", "powershell"}, "shell powershell not found (should be bash, zsh, fish)"},
+	}
+	for _, test := range tests {
+		cm := CommandLine{logger: NewLoggerMock(), writer: &bytes.Buffer{}}
+		err := cm.Run(test.args)
+		assert.NotNil(t, err, test.args)
+		assert.Equal(t, test.msg, err.Error())
+		assert.Equal(t, ExitUsage, ExitCode(err))
+	}
+	endPath(path)
+}
+
+func TestProfile(t *testing.T) {
+	path := "./f22"
+	initPath(path)
+	os.Mkdir(filepath.Join(path, "cielo"), 0755)
+	os.Mkdir(filepath.Join(path, "rede"), 0755)
+	createFile(filepath.Join(path, "cielo"), "test1.txt", statement(cielosales, cieloTrailer))
+	createFile(filepath.Join(path, "rede"), "test2.txt", statement(redecredit, redecreditTrailer))
+	createFile(path, "config.yaml", `
+profiles:
+  clientx:
+    paths:
+      cielovendas: f22/cielo
+      redecredito: f22/rede
+    acquirers: [cielovendas, redecredito]
+    nameTemplate: "{acquirer}_{headquarter}_{periodInit}.txt"
+    calendar:
+      weekdays: [mon, tue, wed, thu, fri]
+`)
+	config := filepath.Join(path, "config.yaml")
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	args := []string{"pm", "gaps", "--profile", "clientx", "--config", config, "--from", "2021-03-01", "--to", "2021-03-14"}
+	err := cm.Run(args)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cielovendas: 01/03/2021 - 05/03/2021", "cielovendas: 08/03/2021 - 09/03/2021",
+		"cielovendas: 11/03/2021 - 12/03/2021", "redecredito: 01/03/2021 - 05/03/2021",
+		"redecredito: 08/03/2021 - 12/03/2021"}, logx.GetLines())
+	writer := &bytes.Buffer{}
+	cm = CommandLine{logger: NewLoggerMock(), writer: writer}
+	os.Setenv("CIELO_EDI_HEADQUARTERS", "1023863232")
+	args = []string{"pm", "periods", "--profile", "clientx", "--config", config, "--acquirer", "cielovendas", "--output", "csv"}
+	err = cm.Run(args)
+	os.Unsetenv("CIELO_EDI_HEADQUARTERS")
+	assert.Nil(t, err)
+	assert.Equal(t, "acquirer,headquarter,start,end\ncielovendas,1023863232,2021-03-10,2021-03-10\n", writer.String())
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	args = []string{"pm", "rename", "--profile", "clientx", "--config", config}
+	err = cm.Run(args)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Yes: test1.txt - CIELO_1023863232_2021_03_10.txt", "Yes: test2.txt - REDECARD_0021644942_2021_02_07.txt"}, logx.GetLines())
+	assert.True(t, fileExists(filepath.Join(path, "cielo", "CIELO_1023863232_2021_03_10.txt")))
+	cm = CommandLine{logger: NewLoggerMock(), writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "gaps", "--profile", "clienty", "--config", config, "--from", "2021-03-01", "--to", "2021-03-14"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "profile clienty not found (should be clientx)", err.Error())
+	assert.Equal(t, ExitUsage, ExitCode(err))
+	err = cm.Run([]string{"pm", "gaps", "--profile", "clientx", "--config", filepath.Join(path, "none.yaml"), "--from", "2021-03-01", "--to", "2021-03-14"})
+	assert.NotNil(t, err)
+	assert.Equal(t, ExitFailure, ExitCode(err))
+	endPath(path)
+}
+
+func TestCheck(t *testing.T) {
+	path := "./f23"
+	initPath(path)
+	createFile(path, "test1.txt", statement(cielosales, cieloTrailer))
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	textfile := filepath.Join(path, "cielo_edi.prom")
+	err := cm.Run([]string{"pm", "check", "cielovendas", path, "01/03/2021", "30/03/2021", "--textfile", textfile})
+	assert.NotNil(t, err)
+	assert.Equal(t, "29 missing days", err.Error())
+	assert.Equal(t, ExitGaps, ExitCode(err))
+	assert.Equal(t, []string{"WARNING - 29 missing days in 1 of 1 statements, oldest gap 01/03/2021 | missing_days=29",
+		"cielovendas 1023863232: 29 missing days, oldest gap 01/03/2021 - 09/03/2021"}, logx.GetLines())
+	metrics, _ := os.ReadFile(textfile)
+	assert.Contains(t, string(metrics), "cielo_edi_check_success 1\n")
+	assert.Contains(t, string(metrics), `cielo_edi_missing_days{statement="cielovendas",headquarter="1023863232"} 29`)
+	assert.Contains(t, string(metrics), `cielo_edi_oldest_gap_start_seconds{statement="cielovendas",headquarter="1023863232"} 1614556800`)
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "check", "cielovendas", path, "10/03/2021", "10/03/2021"})
+	assert.Nil(t, err)
+	assert.Equal(t, ExitOk, ExitCode(err))
+	assert.Equal(t, []string{"OK - 1 statements fully covered | missing_days=0", "cielovendas 1023863232: covered"}, logx.GetLines())
+	writer := &bytes.Buffer{}
+	cm = CommandLine{logger: NewLoggerMock(), writer: writer}
+	err = cm.Run([]string{"pm", "check", "cielovendas", path, "09/03/2021", "10/03/2021", "--output", "json"})
+	assert.Equal(t, ExitGaps, ExitCode(err))
+	result := checkResult{}
+	assert.Nil(t, json.Unmarshal(writer.Bytes(), &result))
+	assert.Equal(t, "WARNING", result.Status)
+	assert.Equal(t, 1, result.MissingDays)
+	assert.Equal(t, "09/03/2021 - 09/03/2021", result.OldestGap.String())
+	err = cm.Run([]string{"pm", "check", "cielovendas", path, "01/03/2021"})
+	assert.Equal(t, ExitUsage, ExitCode(err))
+	// profile errors are check errors, not gaps
+	err = cm.Run([]string{"pm", "check", "--profile", "clientx", "--config", filepath.Join(path, "none.yaml"),
+		"--from", "2021-03-01", "--to", "2021-03-14", "--textfile", textfile})
+	assert.NotNil(t, err)
+	assert.Equal(t, ExitCheckError, ExitCode(err))
+	metrics, _ = os.ReadFile(textfile)
+	assert.Contains(t, string(metrics), "cielo_edi_check_success 0\n")
+	createFile(path, "config.yaml", `
+profiles:
+  clientx:
+    path: f23
+    acquirers: [cielovendas]
+    calendar:
+      weekdays: [funday]
+`)
+	err = cm.Run([]string{"pm", "check", "--profile", "clientx", "--config", filepath.Join(path, "config.yaml"),
+		"--from", "2021-03-01", "--to", "2021-03-14"})
+	assert.NotNil(t, err)
+	assert.Equal(t, ExitCheckError, ExitCode(err))
+	err = cm.Run([]string{"pm", "check", "--profile", "clienty", "--config", filepath.Join(path, "config.yaml"),
+		"--from", "2021-03-01", "--to", "2021-03-14"})
+	assert.Equal(t, ExitUsage, ExitCode(err))
+	endPath(path)
+}
+
+func TestInspect(t *testing.T) {
+	path := "./f24"
+	initPath(path)
+	file := createFile(path, "test1.txt", statement(cielofinanc, cieloTrailer))
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err := cm.Run([]string{"pm", "inspect", file})
+	assert.Nil(t, err)
+	lines := strings.Join(logx.GetLines(), "\n")
+	assert.Contains(t, lines, "cielofinanceiro: valid")
+	assert.Contains(t, lines, "cielovendas: validate error - statement id 04 should be 03 (vendas)")
+	assert.Contains(t, lines, "getnet: parse error - ProcessingDate: ")
+	assert.Contains(t, lines, "redecredito: parse error - ProcessingDate: parsing time \"23863232\": month out of range")
+	writer := &bytes.Buffer{}
+	cm = CommandLine{logger: NewLoggerMock(), writer: writer}
+	err = cm.Run([]string{"pm", "inspect", "--file", file, "--output", "json"})
+	assert.Nil(t, err)
+	inspections := []ports.LayoutInspection{}
+	assert.Nil(t, json.Unmarshal(writer.Bytes(), &inspections))
+	assert.Len(t, inspections, len(acquirerMap))
+	assert.Equal(t, "cielofinanceiro", inspections[3].Layout)
+	assert.True(t, inspections[3].Valid)
+	assert.Equal(t, ports.FieldInspection{Name: "StatementId", Offset: 47, Length: 2, Raw: "04", Value: "4"}, inspections[3].Fields[7])
+	assert.Equal(t, "rededebito", inspections[8].Layout)
+	assert.Equal(t, "parse", inspections[8].Stage)
+	err = cm.Run([]string{"pm", "inspect", path})
+	assert.Equal(t, ExitUsage, ExitCode(err))
+	endPath(path)
+}
+
+func TestValidateTrailer(t *testing.T) {
+	path := "./f25"
+	initPath(path)
+	detail := "\r\n5" + strings.Repeat("0", 249)
+	createFile(path, "test1.txt", cielosales+detail+detail+"\r\n900000000004\r\n")
+	createFile(path, "test2.txt", strings.Replace(cielosales, "0008246", "0008247", 1)+detail)
+	createFile(path, "test3.txt", statement(cielofinanc, cieloTrailer))
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err := cm.Run([]string{"pm", "validate", "cielovendas", path})
+	assert.NotNil(t, err)
+	assert.Equal(t, "1 truncated files", err.Error())
+	assert.Equal(t, ExitFailure, ExitCode(err))
+	assert.Equal(t, []string{"Ok: test1.txt - 4 records",
+		"No: test2.txt - trailer not found (last record type 5 should be 9)",
+		"Skipped: test3.txt - invalid file"}, logx.GetLines())
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "rename", "cielovendas", path})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Yes: test1.txt - CIELO-1023863232-03-2021_03_10-2021_03_10-N-2021_03_10-L013.txt",
+		"No: test2.txt - truncated file: trailer not found (last record type 5 should be 9)", "No: test3.txt - invalid file"},
+		logx.GetLines())
+	// the check can be turned off
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "periods", "cielovendas", path, "--trailer=false"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"10/03/2021 - 10/03/2021"}, logx.GetLines())
+	endPath(path)
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, ExitOk, ExitCode(nil))
+	assert.Equal(t, ExitFailure, ExitCode(fmt.Errorf("runtime")))
+	assert.Equal(t, ExitUsage, ExitCode(usageErrorf("usage")))
+	assert.Equal(t, ExitCheckError, ExitCode(&ExitError{Code: ExitCheckError, Err: fmt.Errorf("check")}))
+}
+
+func TestHelpVersionCompletion(t *testing.T) {
+	writer := &bytes.Buffer{}
+	cm := CommandLine{logger: NewLoggerMock(), writer: writer}
+	err := cm.Run([]string{"/usr/bin/pm", "--help"})
+	assert.Nil(t, err)
+	assert.Contains(t, writer.String(), "Usage: pm <command> [flags]")
+	assert.Contains(t, writer.String(), "  gaps         list the days of a period without files")
+	writer.Reset()
+	err = cm.Run([]string{"pm", "gaps", "--help"})
+	assert.Nil(t, err)
+	assert.Contains(t, writer.String(), "Usage: pm gaps [flags]")
+	assert.Contains(t, writer.String(), "-from string")
+	assert.Contains(t, writer.String(), "pm gaps acquirer path from to")
+	writer.Reset()
+	err = cm.Run([]string{"pm", "help", "rename"})
+	assert.Nil(t, err)
+	assert.Contains(t, writer.String(), "-quarantine string")
+	writer.Reset()
+	err = cm.Run([]string{"pm", "version"})
+	assert.Nil(t, err)
+	assert.Equal(t, "pm dev\n", writer.String())
+	writer.Reset()
+	err = cm.Run([]string{"pm", "completion", "bash"})
+	assert.Nil(t, err)
+	assert.Contains(t, writer.String(), "complete -F _pm pm")
+	assert.Contains(t, writer.String(), `gaps) COMPREPLY=($(compgen -W "--acquirer --path --from --to --profile --config --trailer --encoding --output" -- "$cur")) ;;`)
+	writer.Reset()
+	err = cm.Run([]string{"pm", "completion", "--shell", "zsh"})
+	assert.Nil(t, err)
+	assert.Contains(t, writer.String(), "#compdef pm")
+	writer.Reset()
+	err = cm.Run([]string{"pm", "completion", "fish"})
+	assert.Nil(t, err)
+	assert.Contains(t, writer.String(), "complete -c pm -n '__fish_seen_subcommand_from gaps' -l acquirer -x -a 'cieloalelo")
+}
+
+func TestEncoding(t *testing.T) {
+	path := "./f26"
+	initPath(path)
+	latin1 := strings.Replace(redecredit, "NESPRESSO PJM         ", "CAF\xc9 S\xc3O PAULO        ", 1)
+	file := createFile(path, "test1.txt", "\xef\xbb\xbf"+statement(latin1, redecreditTrailer)+"\r\n")
+	writer := &bytes.Buffer{}
+	cm := CommandLine{logger: NewLoggerMock(), writer: writer}
+	err := cm.Run([]string{"pm", "inspect", file, "--encoding", "iso-8859-1", "--output", "json"})
+	assert.Nil(t, err)
+	inspections := []ports.LayoutInspection{}
+	assert.Nil(t, json.Unmarshal(writer.Bytes(), &inspections))
+	assert.Equal(t, "redecredito", inspections[7].Layout)
+	assert.True(t, inspections[7].Valid)
+	values := make(map[string]string)
+	for _, f := range inspections[7].Fields {
+		values[f.Name] = f.Value
+	}
+	assert.Equal(t, "CAFÉ SÃO PAULO        ", values["HeadquarterName"])
+	assert.Equal(t, "21644942", values["Headquarter"])
+	logx := NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "periods", "redecredito", path, "--encoding", "windows-1252"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"07/02/2021 - 07/02/2021"}, logx.GetLines())
+	err = cm.Run([]string{"pm", "periods", "redecredito", path, "--encoding", "ebcdic"})
+	assert.Equal(t, ExitUsage, ExitCode(err))
+	assert.Equal(t, "encoding ebcdic not found (should be iso-8859-1, utf-8, windows-1252)", err.Error())
+	endPath(path)
+}
+
+func TestValidateTotals(t *testing.T) {
+	path := "./f27"
+	initPath(path)
+	summary := "006" + "012345678" + "000000101" + "341" + "01234" + "00000123456" + "07022021" + "00002" +
+		"000000000015000" + "000000000000000" + "000000000000000" + "000000000000300" + "000000000014700" + "08032021" + "M"
+	sale := "008" + "012345678" + "000000101" + "07022021" + "000000000010000" + "000000000000000" +
+		"123456******1234" + "APR" + "000000000001" + "REF0000000001" + "A1B2C3" + "101530"
+	sale2 := strings.Replace(strings.Replace(sale, "000000000010000", "000000000005000", 1), "000000000001", "000000000002", 1)
+	trailer := "028" + "0001" + "000005" + "000001" + "000002" + "000000000015000" + "000000000000000" +
+		"000000000000300" + "000000000014700"
+	createFile(path, "test1.txt", strings.Join([]string{redecredit, summary, sale, sale2, trailer}, "\r\n")+"\r\n")
+	createFile(path, "test2.txt", strings.Join([]string{redecredit, summary, sale, trailer}, "\r\n"))
+	createFile(path, "test3.txt", strings.Join([]string{redecredit, summary, sale[:40], sale2, trailer}, "\r\n"))
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err := cm.Run([]string{"pm", "validate", "redecredito", path})
+	assert.NotNil(t, err)
+	assert.Equal(t, "1 truncated files, 1 files with invalid records or totals", err.Error())
+	assert.Equal(t, []string{"Ok: test1.txt - 5 records",
+		"No: test2.txt - trailer declares 5 records but 4 were read",
+		"No: test3.txt - test3.txt line 3: record 008: GrossAmount: unexpected end of txt for parsing this field"}, logx.GetLines())
+	trailer = strings.Replace(strings.Replace(trailer, "000005000001000002", "000004000001000001", 1), "000000000015000", "000000000010000", 1)
+	createFile(path, "test2.txt", strings.Join([]string{redecredit, summary, sale, trailer}, "\r\n"))
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	cm.Run([]string{"pm", "validate", "redecredito", path})
+	assert.Equal(t, "No: test2.txt - trailer gross amount 100.00 does not match the 150.00 read", logx.GetLines()[1])
+	endPath(path)
+}
+
+func TestSettlements(t *testing.T) {
+	path := "./f28"
+	initPath(path)
+	payment := "034" + "012345678" + "00000000001" + "10032021" + "11042021" + "000000000014700" + "341" + "01234" +
+		"00000123456" + "000000101" + "10032021" + "M" + "01"
+	debit := "038" + "012345678" + "00000000004" + "01042021" + "11042021" + "000000000001000" + "000000000003000" +
+		"21" + "ALUGUEL DE EQUIPAMENTO      " + "341" + "01234" + "00000123456"
+	payment2 := strings.Replace(payment, "11042021", "12042021", 1)
+	trailer := "052" + "0001" + "000005" + "000002" + "000000000029400" + "000000" + "000000000000000" +
+		"000001" + "000000000001000"
+	createFile(path, "test1.txt", strings.Join([]string{redefin, payment, debit, payment2, trailer}, "\r\n"))
+	createFile(path, "test2.txt", statement(cielosales, cieloTrailer))
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err := cm.Run([]string{"pm", "settlements", "redefinanceiro", path})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"redefinanceiro 11/04/2021 12345678 341/01234/123456: 137.00 (credits 147.00, debits 10.00, 2 records)",
+		"redefinanceiro 12/04/2021 12345678 341/01234/123456: 147.00 (credits 147.00, debits 0.00, 1 records)"}, logx.GetLines())
+	writer := &bytes.Buffer{}
+	cm = CommandLine{logger: NewLoggerMock(), writer: writer}
+	err = cm.Run([]string{"pm", "settlements", "redefinanceiro", path, "--output", "csv"})
+	assert.Nil(t, err)
+	assert.Equal(t, "acquirer,date,establishment,bank,branch,account,credits,debits,net,records,negative\n"+
+		"redefinanceiro,2021-04-11,12345678,341,1234,123456,147.00,10.00,137.00,2,false\n"+
+		"redefinanceiro,2021-04-12,12345678,341,1234,123456,147.00,0.00,147.00,1,false\n", writer.String())
+	writer.Reset()
+	err = cm.Run([]string{"pm", "settlements", "redefinanceiro", path, "--output", "json"})
+	assert.Nil(t, err)
+	assert.Contains(t, writer.String(), `"net": 137.00`)
+	err = cm.Run([]string{"pm", "settlements", "cielovendas", path})
+	assert.Nil(t, err)
+	err = cm.Run([]string{"pm", "validate", "redefinanceiro", path})
+	assert.Nil(t, err)
+	endPath(path)
+}
+
+func TestSales(t *testing.T) {
+	path := "./f29"
+	initPath(path)
+	summary := "01,012345678,000000201,21092021,22092021,000002,150.00,3.00,147.00,MAESTRO,341,01234,00000123456"
+	sale := "05,012345678,000000201,21092021,101530,000000000011,123456******1234,MAESTRO,100.00,2.00,98.00,A1B2C3,TERM0001"
+	sale2 := "05,012345678,000000201,21092021,113000,000000000012,654321******4321,MAESTRO,50.00,1.00,49.00,D4E5F6,TERM0001"
+	summary2 := "01,012345678,000000202,21092021,22092021,000001,80.00,1.60,78.40,ELO,341,01234,00000123456"
+	sale3 := "05,012345678,000000202,21092021,120000,000000000013,111111******1111,ELO,80.00,1.60,78.40,G7H8I9,TERM0002"
+	trailer := "04,0001,000007,000002,000003,230.00,4.60,225.40"
+	createFile(path, "test1.txt", strings.Join([]string{rededebt, summary, sale, sale2, summary2, sale3, trailer}, "\r\n"))
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err := cm.Run([]string{"pm", "sales", "rededebito", path})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"rededebito 21/09/2021 ELO: 78.40 (gross 80.00, discount 1.60, 1 sales)",
+		"rededebito 21/09/2021 MAESTRO: 147.00 (gross 150.00, discount 3.00, 2 sales)"}, logx.GetLines())
+	writer := &bytes.Buffer{}
+	cm = CommandLine{logger: NewLoggerMock(), writer: writer}
+	err = cm.Run([]string{"pm", "sales", "rededebito", path, "--output", "csv"})
+	assert.Nil(t, err)
+	assert.Equal(t, "acquirer,date,brand,sales,gross,discount,net\n"+
+		"rededebito,2021-09-21,ELO,1,80.00,1.60,78.40\n"+
+		"rededebito,2021-09-21,MAESTRO,2,150.00,3.00,147.00\n", writer.String())
+	err = cm.Run([]string{"pm", "validate", "rededebito", path})
+	assert.Nil(t, err)
+	createFile(path, "test1.txt", strings.Join([]string{rededebt, summary, sale, summary2, sale3, trailer}, "\r\n"))
+	err = cm.Run([]string{"pm", "validate", "rededebito", path})
+	assert.NotNil(t, err)
+	endPath(path)
+}
+
+func TestGetnetDetails(t *testing.T) {
+	path := "./f30"
+	initPath(path)
+	header := strings.TrimRight(getnet, " ") + fmt.Sprintf("%-25s", "V10.1")
+	establishment := "1447355        "
+	summary := "1" + establishment + "SM" + "POS" + "000000101" + "23072021" + "24082021" + "033" + "001234" +
+		"00000654321" + "000000001" + "000000010000" + "000000000200" + "000000009800" + "MC "
+	sale := "2" + establishment + "000000101" + "000000000011" + "23072021" + "101530" + "123456******1234   " +
+		"000000010000" + "000000000200" + "000000009800" + "01" + "01" + "A1B2C3" + "MC " + "TERM0001"
+	adjustment := "3" + establishment + "000000101" + "25072021" + "24082021" + "D" + "000000001000" + "17" +
+		"CANCELAMENTO DE VENDA         " + "033" + "001234" + "00000654321"
+	trailer := "9" + "000000005" + "000000001" + "000000001" + "000000000010000" + "000000000009800"
+	createFile(path, "test1.txt", strings.Join([]string{header, summary, sale, adjustment, trailer}, "\r\n"))
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err := cm.Run([]string{"pm", "validate", "getnet", path})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Ok: test1.txt - 5 records"}, logx.GetLines())
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "settlements", "getnet", path})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"getnet 24/08/2021 1447355 033/01234/654321: 88.00 (credits 98.00, debits 10.00, 2 records)"}, logx.GetLines())
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "sales", "getnet", path})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"getnet 23/07/2021 MC: 98.00 (gross 100.00, discount 2.00, 1 sales)"}, logx.GetLines())
+	createFile(path, "test1.txt", strings.Join([]string{header, summary, adjustment, trailer}, "\r\n"))
+	err = cm.Run([]string{"pm", "validate", "getnet", path})
+	assert.NotNil(t, err)
+	endPath(path)
+}
+
+func TestSettlementsCieloFin(t *testing.T) {
+	path := "./f31"
+	initPath(path)
+	payment := "1" + "1023863232" + "0000101" + "00" + " " + "01" + "01" + "210628" + "210630" + "210629" +
+		"+" + "0000000015000" + "-" + "0000000000300" + "+" + "0000000000000" + "+" + "0000000014700" +
+		"0341" + "01234" + "00000000123456" + "00" + "000002" + "01" + "000000" + " " + "210628" + "00" + cieloRoTail + "002"
+	adjustment := "1" + "1023863232" + "0000102" + "00" + " " + "00" + "03" + "210628" + "210630" + "210629" +
+		"-" + "0000000020000" + "+" + "0000000000000" + "+" + "0000000000000" + "-" + "0000000020000" +
+		"0341" + "01234" + "00000000123456" + "00" + "000000" + "00" + "000000" + " " + "210625" + "07" + cieloRoTail + "001"
+	payment2 := strings.Replace(payment, "210630", "210701", 1)
+	createFile(path, "test1.txt", strings.Join([]string{cielofinanc, payment, adjustment, payment2, "900000000005"}, "\r\n"))
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err := cm.Run([]string{"pm", "settlements", "cielofinanceiro", path})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"cielofinanceiro 30/06/2021 1023863232 341/01234/123456: -53.00 (credits 147.00, debits 200.00, 2 records) - negative",
+		"cielofinanceiro 01/07/2021 1023863232 341/01234/123456: 147.00 (credits 147.00, debits 0.00, 1 records)"}, logx.GetLines())
+	writer := &bytes.Buffer{}
+	cm = CommandLine{logger: NewLoggerMock(), writer: writer}
+	err = cm.Run([]string{"pm", "settlements", "cielofinanceiro", path, "--output", "json"})
+	assert.Nil(t, err)
+	assert.Contains(t, writer.String(), `"negative": true`)
+	err = cm.Run([]string{"pm", "validate", "cielofinanceiro", path})
+	assert.Nil(t, err)
+	endPath(path)
+}
+
+func TestAnticipations(t *testing.T) {
+	path := "./f32"
+	initPath(path)
+	operation := "5" + "1023863232" + "000000077" + "20210610" + "+" + "0000000010000" + "+" + "0000000000200" +
+		"+" + "0000000009800" + "0341" + "01234" + "00000000123456"
+	summary := "6" + "1023863232" + "000000077" + "20210710" + "0000101" + "01" + "03" + "001" +
+		"+" + "0000000010000" + "+" + "0000000000200" + "+" + "0000000009800"
+	createFile(path, "test1.txt", strings.Join([]string{cieloant, operation, summary, "900000000004"}, "\r\n"))
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err := cm.Run([]string{"pm", "anticipations", "cieloantecipacoes", path})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cieloantecipacoes 10/06/2021 1023863232 operation 77: 2.04% monthly, 27.43% annual " +
+		"(gross 100.00, fee 2.00, net 98.00, 30.0 days)"}, logx.GetLines())
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "anticipations", "cieloantecipacoes", path, "--rate", "1.99"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cieloantecipacoes 10/06/2021 1023863232 operation 77: 2.04% monthly, 27.43% annual " +
+		"(gross 100.00, fee 2.00, net 98.00, 30.0 days) - above contracted rate"}, logx.GetLines())
+	writer := &bytes.Buffer{}
+	cm = CommandLine{logger: NewLoggerMock(), writer: writer}
+	err = cm.Run([]string{"pm", "anticipations", "cieloantecipacoes", path, "--rate", "2.5", "--output", "csv"})
+	assert.Nil(t, err)
+	assert.Equal(t, "acquirer,date,establishment,operation,gross,fee,net,days,monthlyRate,annualRate,aboveRate\n"+
+		"cieloantecipacoes,2021-06-10,1023863232,77,100.00,2.00,98.00,30.0,2.0408,27.4345,false\n", writer.String())
+	err = cm.Run([]string{"pm", "anticipations", "cieloantecipacoes", path, "--rate", "-1"})
+	assert.NotNil(t, err)
+	assert.Equal(t, ExitUsage, ExitCode(err))
+	endPath(path)
+}
+
+func TestCieloAssignmentsAndBalances(t *testing.T) {
+	path := "./f33"
+	initPath(path)
+	assignmentHeader := strings.Replace(cielofinanc, "CIELO04", "CIELO07", 1)
+	assignment := "5" + "1023863232" + "000000088" + "20210609" + "20210610" + "12345678000199" + "+" + "0000000020000" +
+		"+" + "0000000000600" + "+" + "0000000019400" + "0341" + "01234" + "00000000123456"
+	receivable := "6" + "1023863232" + "000000088" + "20210810" + "0000101" + "02" + "03" + "002" +
+		"+" + "0000000020000" + "+" + "0000000019400"
+	createFile(path, "test1.txt", strings.Join([]string{assignmentHeader, assignment, receivable, "900000000004"}, "\r\n"))
+	balanceHeader := strings.Replace(cielofinanc, "CIELO04", "CIELO08", 1)
+	oldHeader := strings.Replace(balanceHeader, "202106302021063020210630", "202106292021062920210629", 1)
+	balance := "5" + "1023863232" + "001" + "040" + "20210810" + "000000003" + "+" + "0000000030000" + "+" + "0000000029100"
+	balance2 := "5" + "1023863232" + "002" + "040" + "20210710" + "000000001" + "+" + "0000000010000" + "+" + "0000000009700"
+	createFile(path, "test2.txt", strings.Join([]string{balanceHeader, balance, balance2, "900000000004"}, "\r\n"))
+	createFile(path, "test3.txt", strings.Join([]string{oldHeader, balance, balance, "900000000004"}, "\r\n"))
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err := cm.Run([]string{"pm", "periods", "cielosaldo", path})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"29/06/2021 - 30/06/2021"}, logx.GetLines())
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "receivables", "cielosaldo", path})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cielosaldo 1023863232 001 10/08/2021: 291.00 (gross 300.00, 3 receivables)",
+		"cielosaldo 1023863232 002 10/07/2021: 97.00 (gross 100.00, 1 receivables)"}, logx.GetLines())
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	// assignments are not anticipations
+	err = cm.Run([]string{"pm", "anticipations", "cielocessoes", path})
+	assert.Nil(t, err)
+	assert.Len(t, logx.GetLines(), 0)
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "rename", "cielocessoes", path})
+	assert.Nil(t, err)
+	assert.Equal(t, "Yes: test1.txt - CIELO-1023863232-07-2021_06_30-2021_06_30-N-2021_06_30-L014.txt", logx.GetLines()[0])
+	endPath(path)
+}
+
+func TestCieloAlelo(t *testing.T) {
+	path := "./f34"
+	initPath(path)
+	summary := "1" + "1349678200" + "0000301" + "01" + "20201231" + "20210130" + "000002" + "+" + "0000000006000" +
+		"+" + "0000000000300" + "+" + "0000000005700" + "0237" + "00123" + "00000000045678"
+	sale := "2" + "1349678200" + "0000301" + "01" + "20201231" + "121500" + "5067******1234     " + "000000000021" +
+		"A1B2C3" + "+" + "0000000004000" + "+" + "0000000000200" + "+" + "0000000003800" + "TERM0001"
+	sale2 := "2" + "1349678200" + "0000301" + "01" + "20201231" + "123000" + "5067******4321     " + "000000000022" +
+		"D4E5F6" + "+" + "0000000002000" + "+" + "0000000000100" + "+" + "0000000001900" + "TERM0001"
+	createFile(path, "test1.txt", strings.Join([]string{cieloalelo, summary, sale, sale2, "900000000005"}, "\r\n"))
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err := cm.Run([]string{"pm", "sales", "cieloalelo", path})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cieloalelo 31/12/2020 ALELO REFEICAO: 57.00 (gross 60.00, discount 3.00, 2 sales)"}, logx.GetLines())
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "settlements", "cieloalelo", path})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cieloalelo 30/01/2021 1349678200 237/00123/45678: 57.00 (credits 57.00, debits 0.00, 1 records)"},
+		logx.GetLines())
+	err = cm.Run([]string{"pm", "validate", "cieloalelo", path})
+	assert.Nil(t, err)
+	createFile(path, "test1.txt", strings.Join([]string{cieloalelo, summary, sale, "900000000004"}, "\r\n"))
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "validate", "cieloalelo", path})
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"No: test1.txt - establishment 1349678200 summary 301 sale count 2 does not match the 1 read"}, logx.GetLines())
+	endPath(path)
+}
+
+func TestCieloSalesVersions(t *testing.T) {
+	path := "./f35"
+	initPath(path)
+	summary := "1" + "1023863232" + "0000101" + "00" + " " + "00" + "01" + "210310" + "210409" + "210408" +
+		"+" + "0000000015000" + "+" + "0000000000450" + "+" + "0000000000000" + "+" + "0000000014550" +
+		"0341" + "01234" + "00000000123456" + "00" + "000002" + "01" + "000000" + " " + "210310" + "00" + cieloRoTail + "001"
+	sale := "2" + "1023863232" + "0000101" + "455187******1234   " + "20210310" + "+" + "0000000010000" + "0101" +
+		"   " + "A1B2C3" + "10017348980310A1B2C3"
+	v15 := strings.Replace(cielosales, "013 ", "015 ", 1)
+	createFile(path, "test1.txt", strings.Join([]string{cielosales, summary, sale + "123456", "900000000004"}, "\r\n"))
+	createFile(path, "test2.txt", strings.Join([]string{v15, summary, sale + "000000123456101530", "900000000004"}, "\r\n"))
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err := cm.Run([]string{"pm", "validate", "cielovendas", path})
+	assert.Nil(t, err)
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "sales", "cielovendas", path})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cielovendas 10/03/2021 001: 291.00 (gross 300.00, discount 9.00, 4 sales)"}, logx.GetLines())
+	createFile(path, "test2.txt", strings.Join([]string{strings.Replace(cielosales, "013 ", "016 ", 1), summary,
+		sale + "000000123456101530", "900000000004"}, "\r\n"))
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "validate", "cielovendas", path})
+	assert.NotNil(t, err)
+	assert.Contains(t, logx.GetLines(), "No: test2.txt - test2.txt line 1: layout version 16 not supported (should be 13, 14, 15)")
+	endPath(path)
+}
+
+func TestTransactions(t *testing.T) {
+	path := "./f36"
+	initPath(path)
+	summary := "1" + "1349678200" + "0000301" + "01" + "20201231" + "20210130" + "000001" + "+" + "0000000004000" +
+		"+" + "0000000000200" + "+" + "0000000003800" + "0237" + "00123" + "00000000045678"
+	sale := "2" + "1349678200" + "0000301" + "01" + "20201231" + "121500" + "5067******1234     " + "000000000021" +
+		"A1B2C3" + "+" + "0000000004000" + "+" + "0000000000200" + "+" + "0000000003800" + "TERM0001"
+	createFile(path, "test1.txt", strings.Join([]string{cieloalelo, summary, sale, "900000000004"}, "\r\n"))
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err := cm.Run([]string{"pm", "transactions", "cieloalelo", path})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"CIELO settlement 1349678200 ALELO: 38.00 (gross 40.00, fee 2.00, sale 31/12/2020, payment 30/01/2021)",
+		"CIELO sale 1349678200 ALELO: 38.00 (gross 40.00, fee 2.00, sale 31/12/2020, payment -)"}, logx.GetLines())
+	writer := &bytes.Buffer{}
+	cm = CommandLine{logger: NewLoggerMock(), writer: writer}
+	err = cm.Run([]string{"pm", "transactions", "cieloalelo", path, "--output", "csv"})
+	assert.Nil(t, err)
+	assert.Equal(t, "acquirer,kind,establishment,brand,product,summary,nsu,authorization,card,installment,installmentCount,"+
+		"gross,fee,net,saleDate,paymentDate\n"+
+		"CIELO,settlement,1349678200,ALELO,ALELO REFEICAO,301,0,,,0,0,40.00,2.00,38.00,2020-12-31,2021-01-30\n"+
+		"CIELO,sale,1349678200,ALELO,ALELO REFEICAO,301,21,A1B2C3,5067******1234,0,0,40.00,2.00,38.00,2020-12-31,\n",
+		writer.String())
+	writer = &bytes.Buffer{}
+	cm = CommandLine{logger: NewLoggerMock(), writer: writer}
+	err = cm.Run([]string{"pm", "transactions", "cieloalelo", path, "--output", "json"})
+	assert.Nil(t, err)
+	assert.Contains(t, writer.String(), `"kind": "sale"`)
+	assert.Contains(t, writer.String(), `"nsu": 21`)
+	endPath(path)
+}
+
+func TestReconcile(t *testing.T) {
+	path := "./f37"
+	initPath(path)
+	summary := func(ro string, installment string, date string, net string) string {
+		return "1" + "1023863232" + ro + installment + " " + "02" + "01" + "210310" + date + date + "+" + net + "+" +
+			"0000000000000" + "+" + "0000000000000" + "+" + net + "0341" + "01234" + "00000000123456" + "00" + "000001" +
+			"01" + "000000" + " " + "210310" + "00" + cieloRoTail + "001"
+	}
+	payment := func(ro string, installment string, date string, net string) string {
+		return "1" + "1023863232" + ro + installment + " " + "02" + "01" + "210310" + date + date + "+" + net + "+" +
+			"0000000000000" + "+" + "0000000000000" + "+" + net + "0341" + "01234" + "00000000123456" + "00" + "000001" +
+			"01" + "000000" + " " + "210628" + "00" + cieloRoTail + "002"
+	}
+	createFile(path, "test1.txt", strings.Join([]string{cielosales,
+		summary("0000101", "00", "210409", "0000000014550"), summary("0000102", "00", "210409", "0000000010000"),
+		summary("0000103", "01", "210409", "0000000005000"), summary("0000103", "02", "210509", "0000000005000"),
+		summary("0000104", "00", "210409", "0000000002000"), "900000000007"}, "\r\n"))
+	createFile(path, "test2.txt", strings.Join([]string{cielofinanc,
+		payment("0000101", "00", "210409", "0000000014550"), payment("0000102", "00", "210412", "0000000010000"),
+		payment("0000103", "01", "210409", "0000000004900"), "900000000005"}, "\r\n"))
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err := cm.Run([]string{"pm", "reconcile", "cielovendas", path})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"CIELO 1023863232 101/0 09/04/2021: paid on time (expected 145.50, paid 145.50 on 09/04/2021, 1 settlements)",
+		"CIELO 1023863232 102/0 09/04/2021: paid late (expected 100.00, paid 100.00 on 12/04/2021, 1 settlements)",
+		"CIELO 1023863232 103/1 09/04/2021: paid with a different amount (expected 50.00, paid 49.00 on 09/04/2021, 1 settlements)",
+		"CIELO 1023863232 104/0 09/04/2021: open (expected 20.00)",
+		"CIELO 1023863232 103/2 09/05/2021: open (expected 50.00)"}, logx.GetLines())
+	writer := &bytes.Buffer{}
+	cm = CommandLine{logger: NewLoggerMock(), writer: writer}
+	err = cm.Run([]string{"pm", "reconcile", "cielovendas", path, "--output", "csv"})
+	assert.Nil(t, err)
+	lines := strings.Split(writer.String(), "\n")
+	assert.Equal(t, "acquirer,establishment,summary,installment,scheduledDate,expected,paymentDate,paid,settlements,status", lines[0])
+	assert.Equal(t, "CIELO,1023863232,102,0,2021-04-09,100.00,2021-04-12,100.00,1,late", lines[2])
+	assert.Equal(t, "CIELO,1023863232,104,0,2021-04-09,20.00,,0.00,0,open", lines[4])
+	writer = &bytes.Buffer{}
+	cm = CommandLine{logger: NewLoggerMock(), writer: writer}
+	err = cm.Run([]string{"pm", "reconcile", "cielovendas", path, "--output", "json"})
+	assert.Nil(t, err)
+	assert.Contains(t, writer.String(), `"status": "divergent"`)
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "reconcile", "cielofinanceiro", path})
+	assert.Nil(t, err)
+	assert.Len(t, logx.GetLines(), 0)
+	endPath(path)
+}
+
+func TestReconcileErp(t *testing.T) {
+	path := "./f38"
+	initPath(path)
+	os.Mkdir(filepath.Join(path, "cielo"), 0755)
+	os.Mkdir(filepath.Join(path, "erp"), 0755)
+	summary := "1" + "1349678200" + "0000301" + "01" + "20201231" + "20210130" + "000002" + "+" + "0000000006000" +
+		"+" + "0000000000300" + "+" + "0000000005700" + "0237" + "00123" + "00000000045678"
+	sale1 := "2" + "1349678200" + "0000301" + "01" + "20201231" + "121500" + "5067******1234     " + "000000000021" +
+		"A1B2C3" + "+" + "0000000004000" + "+" + "0000000000200" + "+" + "0000000003800" + "TERM0001"
+	sale2 := "2" + "1349678200" + "0000301" + "01" + "20201231" + "123000" + "5067******4321     " + "000000000022" +
+		"D4E5F6" + "+" + "0000000002000" + "+" + "0000000000100" + "+" + "0000000001900" + "TERM0001"
+	createFile(filepath.Join(path, "cielo"), "test1.txt", strings.Join([]string{cieloalelo, summary, sale1, sale2, "900000000005"}, "\r\n"))
+	createFile(filepath.Join(path, "erp"), "sales.csv", "Data Venda;Valor;NSU;Cart\xe3o\r\n31/12/2020;41;21;1234\r\n"+
+		"31/12/2020;\"1.010,00\";;9999\r\n")
+	createFile(path, "config.yaml", `
+profiles:
+  clientx:
+    paths:
+      cieloalelo: f38/cielo
+    acquirers: [cieloalelo]
+    encoding: iso-8859-1
+    erp:
+      columns:
+        Date: Data Venda
+        amount: Valor
+        nsu: NSU
+        card: Cartão
+      dateFormat: dd/mm/yyyy
+      amountTolerance: 0.05
+`)
+	config := filepath.Join(path, "config.yaml")
+	erp := filepath.Join(path, "erp")
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err := cm.Run([]string{"pm", "reconcile", "--profile", "clientx", "--config", config, "--erp", erp})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"31/12/2020 sales.csv:2 CIELO 1349678200: different amount (erp 41.00, acquirer 40.00, nsu 21, card 1234)",
+		"31/12/2020 sales.csv:3: missing at the acquirer (erp 1010.00, card 9999)",
+		"31/12/2020 CIELO 1349678200: missing in the ERP (acquirer 20.00, nsu 22, authorization D4E5F6, card 5067******4321)"},
+		logx.GetLines())
+	writer := &bytes.Buffer{}
+	cm = CommandLine{logger: NewLoggerMock(), writer: writer}
+	err = cm.Run([]string{"pm", "reconcile", "--profile", "clientx", "--config", config, "--erp", erp, "--output", "csv"})
+	assert.Nil(t, err)
+	lines := strings.Split(writer.String(), "\n")
+	assert.Equal(t, "status,file,line,acquirer,establishment,date,nsu,authorization,card,erpAmount,acquirerAmount", lines[0])
+	assert.Equal(t, "erponly,sales.csv,3,,0,2020-12-31,0,,9999,1010.00,0.00", lines[2])
+	// the encoding flag overrides the profile
+	cm = CommandLine{logger: NewLoggerMock(), writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "reconcile", "--profile", "clientx", "--config", config, "--erp", erp, "--encoding", "utf-8"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "sales.csv: column Cartão not found on the csv header", err.Error())
+	// default columns
+	cm = CommandLine{logger: NewLoggerMock(), writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "reconcile", "cieloalelo", filepath.Join(path, "cielo"), "--erp", erp})
+	assert.NotNil(t, err)
+	assert.Equal(t, "sales.csv: column amount not found on the csv header", err.Error())
+	err = cm.Run([]string{"pm", "reconcile", "cieloalelo", filepath.Join(path, "cielo"), "--erp", filepath.Join(path, "none")})
+	assert.NotNil(t, err)
+	endPath(path)
+}
+
+func TestDeposits(t *testing.T) {
+	path := "./f39"
+	initPath(path)
+	os.Mkdir(filepath.Join(path, "cielo"), 0755)
+	os.Mkdir(filepath.Join(path, "bank"), 0755)
+	payment := func(ro string, date string, net string) string {
+		return "1" + "1023863232" + ro + "00" + " " + "02" + "01" + "210310" + date + date + "+" + net + "+" +
+			"0000000000000" + "+" + "0000000000000" + "+" + net + "0341" + "01234" + "00000000123456" + "00" + "000001" +
+			"01" + "000000" + " " + "210628" + "00" + cieloRoTail + "002"
+	}
+	entry := func(sequence string, amount string, entryType string) string {
+		return "341" + "0001" + "3" + sequence + "E" + "   " + "2" + "12345678000199" + fmt.Sprintf("%-20s", "CONV01") + "01234" +
+			"5" + "000000123456" + "7" + " " + fmt.Sprintf("%-30s", "CLIENTE X") + "      " + "DPV" + "  " + strings.Repeat(" ", 20) +
+			" " + "09042021" + "09042021" + amount + entryType + "213" + "0123" + fmt.Sprintf("%-25s", "CIELO SA") +
+			fmt.Sprintf("%-39s", "1023863232")
+	}
+	trailer := func(debits string, credits string) string {
+		return "341" + "0001" + "5" + strings.Repeat(" ", 9) + "2" + "12345678000199" + fmt.Sprintf("%-20s", "CONV01") + "01234" +
+			"5" + "000000123456" + "7" + " " + strings.Repeat(" ", 16) + strings.Repeat("0", 54) + "09042021" +
+			"000000000000100000" + "P" + "F" + "000004" + debits + credits + strings.Repeat(" ", 28)
+	}
+	createFile(filepath.Join(path, "cielo"), "test1.txt", strings.Join([]string{cielofinanc,
+		payment("0000101", "210409", "0000000014550"), payment("0000102", "210412", "0000000010000"), "900000000004"}, "\r\n"))
+	bank := filepath.Join(path, "bank")
+	createFile(bank, "bank.ret", strings.Join([]string{"34100000" + strings.Repeat(" ", 232),
+		entry("00001", "000000000000014550", "C"), entry("00002", "000000000000001000", "D"),
+		trailer("000000000000001000", "000000000000014550")}, "\r\n"))
+	createFile(bank, "readme.txt", "bank statements of client x")
+	logx := NewLoggerMock()
+	cm := CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err := cm.Run([]string{"pm", "deposits", "cielofinanceiro", filepath.Join(path, "cielo"), bank})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"cielofinanceiro 09/04/2021 1023863232 341/01234/123456: deposited (expected 145.50, entry bank.ret:2 CIELO SA)",
+		"cielofinanceiro 12/04/2021 1023863232 341/01234/123456: missing payment (expected 100.00)"}, logx.GetLines())
+	writer := &bytes.Buffer{}
+	cm = CommandLine{logger: NewLoggerMock(), writer: writer}
+	err = cm.Run([]string{"pm", "deposits", "cielofinanceiro", filepath.Join(path, "cielo"), "--bank", bank, "--output", "csv"})
+	assert.Nil(t, err)
+	assert.Equal(t, "acquirer,date,establishment,bank,branch,account,expected,file,line,history,document,status\n"+
+		"cielofinanceiro,2021-04-09,1023863232,341,1234,123456,145.50,bank.ret,2,CIELO SA,1023863232,deposited\n"+
+		"cielofinanceiro,2021-04-12,1023863232,341,1234,123456,100.00,,0,,,missing\n", writer.String())
+	// profile bank directory
+	createFile(path, "config.yaml", `
+profiles:
+  clientx:
+    paths:
+      cielofinanceiro: f39/cielo
+    acquirers: [cielofinanceiro]
+    bank:
+      path: f39/bank
+      dayTolerance: 3
+`)
+	late := strings.Replace(entry("00001", "000000000000010000", "C"), "0904202109042021", "1304202113042021", 1)
+	createFile(bank, "bank2.ret", strings.Join([]string{"34100000", late,
+		trailer("000000000000000000", "000000000000010000")}, "\r\n"))
+	logx = NewLoggerMock()
+	cm = CommandLine{logger: logx, writer: &bytes.Buffer{}}
+	err = cm.Run([]string{"pm", "deposits", "--profile", "clientx", "--config", filepath.Join(path, "config.yaml")})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"cielofinanceiro 09/04/2021 1023863232 341/01234/123456: deposited (expected 145.50, entry bank.ret:2 CIELO SA)",
+		"cielofinanceiro 12/04/2021 1023863232 341/01234/123456: deposited (expected 100.00, entry bank2.ret:2 CIELO SA)"},
+		logx.GetLines())
+	// errors
+	err = cm.Run([]string{"pm", "deposits", "cielofinanceiro", filepath.Join(path, "cielo")})
+	assert.NotNil(t, err)
+	assert.Equal(t, "bank not found (should be --bank directory)", err.Error())
+	createFile(bank, "bank.ret", strings.Join([]string{"34100000", entry("00001", "000000000000014550", "C"),
+		trailer("000000000000000000", "000000000000010000")}, "\r\n"))
+	err = cm.Run([]string{"pm", "deposits", "cielofinanceiro", filepath.Join(path, "cielo"), bank})
+	assert.NotNil(t, err)
+	assert.Equal(t, "bank.ret: trailer batch 1 credit amount 100.00 does not match the 145.50 read", err.Error())
+	endPath(path)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lavinas/cielo-edi/internal/utils/file_manager"
+)
+
+const (
+	bashCompletion = `# bash completion for %[1]s
+_%[2]s() {
+    local cur prev
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+    if [ "$COMP_CWORD" -eq 1 ]; then
+        COMPREPLY=($(compgen -W "%[3]s" -- "$cur"))
+        return
+    fi
+    case "$prev" in
+        --acquirer) COMPREPLY=($(compgen -W "%[4]s" -- "$cur")); return ;;
+        --output) COMPREPLY=($(compgen -W "%[5]s" -- "$cur")); return ;;
+        --shell) COMPREPLY=($(compgen -W "%[6]s" -- "$cur")); return ;;
+        --encoding) COMPREPLY=($(compgen -W "%[8]s" -- "$cur")); return ;;
+        --path|--quarantine) COMPREPLY=($(compgen -d -- "$cur")); return ;;
+        --config|--textfile|--file) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+    esac
+    case "${COMP_WORDS[1]}" in
+%[7]s    esac
+}
+complete -F _%[2]s %[1]s
+`
+	zshCompletion = `#compdef %[1]s
+autoload -U +X bashcompinit && bashcompinit
+%[2]s`
+	fishCompletion = `# fish completion for %[1]s
+complete -c %[1]s -f
+complete -c %[1]s -n '__fish_use_subcommand' -a '%[2]s'
+%[3]s`
+)
+
+var (
+	completionShells = []string{"bash", "zsh", "fish"}
+)
+
+// completionScript generates the completion script of a shell from the commands and flags of the command line
+func completionScript(shell string, program string) (string, error) {
+	switch shell {
+	case "bash":
+		return bashScript(program), nil
+	case "zsh":
+		return fmt.Sprintf(zshCompletion, program, bashScript(program)), nil
+	case "fish":
+		return fishScript(program), nil
+	}
+	return "", usageErrorf("shell %s not found (should be %s)", shell, strings.Join(completionShells, ", "))
+}
+
+func bashScript(program string) string {
+	cases := ""
+	for _, cmd := range commands {
+		if len(cmd.flags) == 0 {
+			continue
+		}
+		cases += fmt.Sprintf("        %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", cmd.name, strings.Join(flagNames(cmd), " "))
+	}
+	function := strings.NewReplacer("-", "_", ".", "_").Replace(program)
+	return fmt.Sprintf(bashCompletion, program, function, strings.Join(getCommandNames(), " "),
+		strings.Join(getAcquirerNames(), " "), strings.Join(outputFormats, " "), strings.Join(completionShells, " "), cases,
+		strings.Join(file_manager.GetEncodings(), " "))
+}
+
+func fishScript(program string) string {
+	lines := ""
+	for _, cmd := range commands {
+		lines += fmt.Sprintf("complete -c %s -n '__fish_seen_subcommand_from %s' -d '%s'\n", program, cmd.name, cmd.description)
+		for _, name := range cmd.flags {
+			values := ""
+			switch name {
+			case "acquirer":
+				values = fmt.Sprintf(" -x -a '%s'", strings.Join(getAcquirerNames(), " "))
+			case "output":
+				values = fmt.Sprintf(" -x -a '%s'", strings.Join(outputFormats, " "))
+			case "shell":
+				values = fmt.Sprintf(" -x -a '%s'", strings.Join(completionShells, " "))
+			case "encoding":
+				values = fmt.Sprintf(" -x -a '%s'", strings.Join(file_manager.GetEncodings(), " "))
+			case "path", "quarantine":
+				values = " -r -a '(__fish_complete_directories)'"
+			case "config", "textfile", "file":
+				values = " -r -F"
+			}
+			lines += fmt.Sprintf("complete -c %s -n '__fish_seen_subcommand_from %s' -l %s%s\n", program, cmd.name, name, values)
+		}
+	}
+	return fmt.Sprintf(fishCompletion, program, strings.Join(getCommandNames(), " "), lines)
+}
+
+func flagNames(cmd command) []string {
+	names := make([]string, 0, len(cmd.flags))
+	for _, name := range cmd.flags {
+		names = append(names, "--"+name)
+	}
+	return names
+}
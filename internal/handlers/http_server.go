@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+)
+
+const (
+	jsonContentType    = "application/json"
+	problemContentType = "application/problem+json"
+	apiDateFormat      = "2006-01-02"
+	shutdownTimeout    = 10 * time.Second
+)
+
+// Problem is the body of an error response (RFC 7807 problem details)
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// HttpServer exposes the services of a path on a REST API
+type HttpServer struct {
+	logger     ports.LoggerInterface
+	path       string
+	mux        *http.ServeMux
+	newService func(string) (ports.ServiceInterface, error)
+}
+
+func NewHttpServer(logger ports.LoggerInterface, path string) *HttpServer {
+	h := &HttpServer{logger: logger, path: path, mux: http.NewServeMux(), newService: getAcquirerService}
+	h.mux.HandleFunc("/periods", h.periods)
+	h.mux.HandleFunc("/gaps", h.gaps)
+	h.mux.HandleFunc("/files", h.files)
+	h.mux.HandleFunc("/rename", h.rename)
+	return h
+}
+
+func (h HttpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Run listens on address until the context is done and then shuts the server down gracefully
+func (h HttpServer) Run(ctx context.Context, address string) error {
+	server := &http.Server{Addr: address, Handler: h}
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.ListenAndServe()
+	}()
+	h.logger.Printf("serving %s on %s", h.path, address)
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	h.logger.Printf("stopped serving %s", h.path)
+	return server.Shutdown(shutdownCtx)
+}
+
+func (h HttpServer) periods(w http.ResponseWriter, r *http.Request) {
+	if !h.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	acquirer, service, headquarter, ok := h.getQuery(w, r)
+	if !ok {
+		return
+	}
+	periods, err := service.GetHeadquarterPeriodGrouped(h.path, headquarter)
+	if err != nil {
+		h.writeProblem(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	h.writeJSON(w, r, map[string]interface{}{"acquirer": acquirer, "headquarter": headquarter, "periods": periods})
+}
+
+func (h HttpServer) gaps(w http.ResponseWriter, r *http.Request) {
+	if !h.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	acquirer, service, headquarter, ok := h.getQuery(w, r)
+	if !ok {
+		return
+	}
+	from, err := time.Parse(apiDateFormat, r.URL.Query().Get("from"))
+	if err != nil {
+		h.writeProblem(w, r, http.StatusBadRequest, fmt.Errorf("from date error (should be %s)", apiDateFormat))
+		return
+	}
+	to, err := time.Parse(apiDateFormat, r.URL.Query().Get("to"))
+	if err != nil {
+		h.writeProblem(w, r, http.StatusBadRequest, fmt.Errorf("to date error (should be %s)", apiDateFormat))
+		return
+	}
+	if from.After(to) {
+		h.writeProblem(w, r, http.StatusBadRequest, fmt.Errorf("from date after to date"))
+		return
+	}
+	gaps, err := service.GetHeadquarterGapGrouped(h.path, headquarter, from, to)
+	if err != nil {
+		h.writeProblem(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	h.writeJSON(w, r, map[string]interface{}{"acquirer": acquirer, "headquarter": headquarter, "gaps": gaps})
+}
+
+func (h HttpServer) files(w http.ResponseWriter, r *http.Request) {
+	if !h.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	acquirer, service, _, ok := h.getQuery(w, r)
+	if !ok {
+		return
+	}
+	inventory, err := service.GetInventory(h.path)
+	if err != nil {
+		h.writeProblem(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	h.writeJSON(w, r, map[string]interface{}{"acquirer": acquirer, "files": inventory})
+}
+
+func (h HttpServer) rename(w http.ResponseWriter, r *http.Request) {
+	if !h.allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	acquirer, service, _, ok := h.getQuery(w, r)
+	if !ok {
+		return
+	}
+	results, err := service.FormatNames(h.path)
+	if err != nil {
+		h.writeProblem(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	h.writeJSON(w, r, map[string]interface{}{"acquirer": acquirer, "results": results})
+}
+
+// getQuery reads the acquirer and the optional ec (headquarter) parameters and writes a problem if they are invalid
+func (h HttpServer) getQuery(w http.ResponseWriter, r *http.Request) (string, ports.ServiceInterface, int64, bool) {
+	acquirer := r.URL.Query().Get("acquirer")
+	if acquirer == "" {
+		h.writeProblem(w, r, http.StatusBadRequest, fmt.Errorf("acquirer parameter is required"))
+		return "", nil, 0, false
+	}
+	service, err := h.newService(acquirer)
+	if err != nil {
+		h.writeProblem(w, r, http.StatusBadRequest, err)
+		return "", nil, 0, false
+	}
+	var headquarter int64
+	if ec := r.URL.Query().Get("ec"); ec != "" {
+		headquarter, err = strconv.ParseInt(ec, 10, 64)
+		if err != nil {
+			h.writeProblem(w, r, http.StatusBadRequest, fmt.Errorf("ec parameter should be numeric"))
+			return "", nil, 0, false
+		}
+	}
+	return acquirer, service, headquarter, true
+}
+
+func (h HttpServer) allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	h.writeProblem(w, r, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed (should be %s)", r.Method, method))
+	return false
+}
+
+func (h HttpServer) writeJSON(w http.ResponseWriter, r *http.Request, body interface{}) {
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.logger.Printf("%s %s - %v", r.Method, r.URL.Path, err)
+		return
+	}
+	h.logger.Printf("%s %s - %d", r.Method, r.URL.Path, http.StatusOK)
+}
+
+func (h HttpServer) writeProblem(w http.ResponseWriter, r *http.Request, status int, err error) {
+	problem := Problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Detail: err.Error(), Instance: r.URL.Path}
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem)
+	h.logger.Printf("%s %s - %d %v", r.Method, r.URL.Path, status, err)
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveRequest(server *HttpServer, method string, url string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, nil)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHttpPeriods(t *testing.T) {
+	path := "./h1"
+	initPath(path)
+	createFile(path, "test1.txt", statement(cielosales, cieloTrailer))
+	server := NewHttpServer(NewLoggerMock(), path)
+	rec := serveRequest(server, http.MethodGet, "/periods?acquirer=cielovendas")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, jsonContentType, rec.Header().Get("Content-Type"))
+	body := struct {
+		Acquirer string            `json:"acquirer"`
+		Periods  []ports.DateRange `json:"periods"`
+	}{}
+	err := json.Unmarshal(rec.Body.Bytes(), &body)
+	assert.Nil(t, err)
+	assert.Equal(t, "cielovendas", body.Acquirer)
+	assert.Len(t, body.Periods, 1)
+	assert.Equal(t, "10/03/2021 - 10/03/2021", body.Periods[0].String())
+	assert.Contains(t, rec.Body.String(), `{"start":"2021-03-10","end":"2021-03-10"}`)
+	rec = serveRequest(server, http.MethodGet, "/periods?acquirer=cielovendas&ec=1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	assert.Nil(t, err)
+	assert.Len(t, body.Periods, 0)
+	endPath(path)
+}
+
+func TestHttpGaps(t *testing.T) {
+	path := "./h2"
+	initPath(path)
+	createFile(path, "test1.txt", statement(cielosales, cieloTrailer))
+	server := NewHttpServer(NewLoggerMock(), path)
+	rec := serveRequest(server, http.MethodGet, "/gaps?acquirer=cielovendas&ec=1023863232&from=2021-03-01&to=2021-03-30")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := struct {
+		Headquarter int64             `json:"headquarter"`
+		Gaps        []ports.DateRange `json:"gaps"`
+	}{}
+	err := json.Unmarshal(rec.Body.Bytes(), &body)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1023863232), body.Headquarter)
+	assert.Len(t, body.Gaps, 2)
+	assert.Equal(t, "01/03/2021 - 09/03/2021", body.Gaps[0].String())
+	assert.Equal(t, "11/03/2021 - 30/03/2021", body.Gaps[1].String())
+	endPath(path)
+}
+
+func TestHttpFiles(t *testing.T) {
+	path := "./h3"
+	initPath(path)
+	createFile(path, "test1.txt", statement(cielosales, cieloTrailer))
+	createFile(path, "test2.txt", statement(cielofinanc, cieloTrailer))
+	server := NewHttpServer(NewLoggerMock(), path)
+	rec := serveRequest(server, http.MethodGet, "/files?acquirer=cielovendas")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := struct {
+		Files []struct {
+			Name        string `json:"name"`
+			Valid       bool   `json:"valid"`
+			Error       string `json:"error"`
+			Headquarter int64  `json:"headquarter"`
+		} `json:"files"`
+	}{}
+	err := json.Unmarshal(rec.Body.Bytes(), &body)
+	assert.Nil(t, err)
+	assert.Len(t, body.Files, 2)
+	assert.True(t, body.Files[0].Valid)
+	assert.Equal(t, int64(1023863232), body.Files[0].Headquarter)
+	assert.False(t, body.Files[1].Valid)
+	assert.Equal(t, "invalid file", body.Files[1].Error)
+	endPath(path)
+}
+
+func TestHttpRename(t *testing.T) {
+	path := "./h4"
+	initPath(path)
+	createFile(path, "test1.txt", statement(cielosales, cieloTrailer))
+	server := NewHttpServer(NewLoggerMock(), path)
+	rec := serveRequest(server, http.MethodGet, "/rename?acquirer=cielovendas")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+	rec = serveRequest(server, http.MethodPost, "/rename?acquirer=cielovendas")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := struct {
+		Results []ports.RenameResult `json:"results"`
+	}{}
+	err := json.Unmarshal(rec.Body.Bytes(), &body)
+	assert.Nil(t, err)
+	assert.Equal(t, []ports.RenameResult{{File: "test1.txt", NewName: "CIELO-1023863232-03-2021_03_10-2021_03_10-N-2021_03_10-L013.txt", Status: "renamed"}}, body.Results)
+	assert.True(t, fileExists(filepath.Join(path, "CIELO-1023863232-03-2021_03_10-2021_03_10-N-2021_03_10-L013.txt")))
+	endPath(path)
+}
+
+func TestHttpProblems(t *testing.T) {
+	path := "./h5"
+	initPath(path)
+	server := NewHttpServer(NewLoggerMock(), path)
+	tests := []struct {
+		method string
+		url    string
+		status int
+		detail string
+	}{
+		{http.MethodGet, "/periods", http.StatusBadRequest, "acquirer parameter is required"},
+		{http.MethodGet, "/periods?acquirer=redebito", http.StatusBadRequest, "acquirer name redebito not found (should be cieloalelo, cieloantecipacoes, cielocessoes, cielofinanceiro, cielosaldo, cielovendas, getnet, redecredito, rededebito, redefinanceiro)"},
+		{http.MethodGet, "/periods?acquirer=cielovendas&ec=x", http.StatusBadRequest, "ec parameter should be numeric"},
+		{http.MethodGet, "/gaps?acquirer=cielovendas&from=2021-03-01", http.StatusBadRequest, "to date error (should be 2006-01-02)"},
+		{http.MethodGet, "/gaps?acquirer=cielovendas&from=2021-03-10&to=2021-03-01", http.StatusBadRequest, "from date after to date"},
+		{http.MethodPost, "/files?acquirer=cielovendas", http.StatusMethodNotAllowed, "method POST not allowed (should be GET)"},
+	}
+	for _, test := range tests {
+		rec := serveRequest(server, test.method, test.url)
+		assert.Equal(t, test.status, rec.Code, test.url)
+		assert.Equal(t, problemContentType, rec.Header().Get("Content-Type"))
+		problem := Problem{}
+		err := json.Unmarshal(rec.Body.Bytes(), &problem)
+		assert.Nil(t, err)
+		assert.Equal(t, test.status, problem.Status)
+		assert.Equal(t, http.StatusText(test.status), problem.Title)
+		assert.Equal(t, test.detail, problem.Detail)
+	}
+	endPath(path)
+}
@@ -0,0 +1,438 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/lavinas/cielo-edi/internal/core/ports"
+	"github.com/lavinas/cielo-edi/internal/core/services"
+)
+
+const (
+	tableOutput = "table"
+	jsonOutput  = "json"
+	csvOutput   = "csv"
+)
+
+var (
+	outputFormats = []string{tableOutput, jsonOutput, csvOutput}
+)
+
+// output writes the results of a command as lines on the logger (table) or as json or csv on the writer
+type output struct {
+	format     string
+	log        ports.LoggerInterface
+	writer     io.Writer
+	headerDone bool
+}
+
+func newOutput(format string, log ports.LoggerInterface, writer io.Writer) *output {
+	return &output{format: format, log: log, writer: writer}
+}
+
+// write writes all results of a command
+//
+// value has the results to be encoded as json
+// lines has the results formatted as table lines
+// header and records have the results formatted as csv
+func (o *output) write(value interface{}, lines []string, header []string, records [][]string) error {
+	switch o.format {
+	case jsonOutput:
+		encoder := json.NewEncoder(o.writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case csvOutput:
+		writer := csv.NewWriter(o.writer)
+		writer.Write(header)
+		writer.WriteAll(records)
+		return writer.Error()
+	}
+	for _, line := range lines {
+		o.log.Println(line)
+	}
+	return nil
+}
+
+// writeItem writes a single result of a stream of results: a json line, a csv record
+// (after the header on the first call) or table lines
+func (o *output) writeItem(value interface{}, lines []string, header []string, record []string) error {
+	switch o.format {
+	case jsonOutput:
+		return json.NewEncoder(o.writer).Encode(value)
+	case csvOutput:
+		writer := csv.NewWriter(o.writer)
+		if !o.headerDone {
+			writer.Write(header)
+			o.headerDone = true
+		}
+		writer.Write(record)
+		writer.Flush()
+		return writer.Error()
+	}
+	for _, line := range lines {
+		o.log.Println(line)
+	}
+	return nil
+}
+
+func writeDateRanges(o *output, ranges []ports.DateRange) error {
+	lines := make([]string, 0, len(ranges))
+	records := make([][]string, 0, len(ranges))
+	for _, r := range ranges {
+		lines = append(lines, r.String())
+		records = append(records, []string{r.Start.Format(ports.DateFormat), r.End.Format(ports.DateFormat)})
+	}
+	return o.write(ranges, lines, []string{"start", "end"}, records)
+}
+
+// coverage has the date ranges of an acquirer and headquarter (zero for all headquarters) of a profile
+type coverage struct {
+	Acquirer    string            `json:"acquirer"`
+	Headquarter int64             `json:"headquarter,omitempty"`
+	Ranges      []ports.DateRange `json:"ranges"`
+}
+
+// writeCoverages writes the date ranges of each target and headquarter of a profile
+func writeCoverages(o *output, targets []target, getRanges func(target, int64) ([]ports.DateRange, error)) error {
+	coverages := make([]coverage, 0)
+	lines := make([]string, 0)
+	records := make([][]string, 0)
+	for _, t := range targets {
+		headquarters := t.headquarters
+		if len(headquarters) == 0 {
+			headquarters = []int64{0}
+		}
+		for _, hq := range headquarters {
+			ranges, err := getRanges(t, hq)
+			if err != nil {
+				return err
+			}
+			coverages = append(coverages, coverage{Acquirer: t.acquirer, Headquarter: hq, Ranges: ranges})
+			name := t.acquirer
+			if hq != 0 {
+				name = fmt.Sprintf("%s %d", t.acquirer, hq)
+			}
+			for _, r := range ranges {
+				lines = append(lines, fmt.Sprintf("%s: %s", name, r.String()))
+				records = append(records, []string{t.acquirer, fmt.Sprint(hq), r.Start.Format(ports.DateFormat), r.End.Format(ports.DateFormat)})
+			}
+		}
+	}
+	return o.write(coverages, lines, []string{"acquirer", "headquarter", "start", "end"}, records)
+}
+
+var renameHeader = []string{"file", "status", "newName", "reason"}
+
+func renameLines(r ports.RenameResult) []string {
+	switch r.Status {
+	case ports.RenamedStatus:
+		return []string{fmt.Sprintf("Yes: %s - %s", r.File, r.NewName)}
+	case ports.RequeuedStatus:
+		if r.Reason != "" {
+			return []string{fmt.Sprintf("Yes: %s - %s (%s)", r.File, r.NewName, r.Reason)}
+		}
+		return []string{fmt.Sprintf("Yes: %s - %s", r.File, r.NewName)}
+	case ports.QuarantinedStatus:
+		return []string{fmt.Sprintf("No: %s - %s", r.File, r.Reason), fmt.Sprintf("Quarantined: %s - %s", r.File, r.NewName)}
+	}
+	return []string{fmt.Sprintf("No: %s - %s", r.File, r.Reason)}
+}
+
+func renameRecord(r ports.RenameResult) []string {
+	return []string{r.File, r.Status, r.NewName, r.Reason}
+}
+
+func writeRenameResults(o *output, results []ports.RenameResult) error {
+	lines := make([]string, 0, len(results))
+	records := make([][]string, 0, len(results))
+	for _, r := range results {
+		lines = append(lines, renameLines(r)...)
+		records = append(records, renameRecord(r))
+	}
+	return o.write(results, lines, renameHeader, records)
+}
+
+func writeDuplicates(o *output, groups []ports.DuplicateGroup) error {
+	lines := make([]string, 0)
+	records := make([][]string, 0)
+	moves := make([]string, 0)
+	for _, g := range groups {
+		lines = append(lines, fmt.Sprintf("%s: %s - %s", g.Kind, strings.Join(g.Files, ", "), g.Key))
+		quarantine := make(map[string]ports.RenameResult)
+		for _, r := range g.Quarantine {
+			quarantine[r.File] = r
+			if r.Status == ports.QuarantinedStatus {
+				moves = append(moves, fmt.Sprintf("Moved: %s - %s", r.File, r.NewName))
+			} else {
+				moves = append(moves, fmt.Sprintf("No: %s - %s", r.File, r.Reason))
+			}
+		}
+		for _, f := range g.Files {
+			r := quarantine[f]
+			records = append(records, []string{g.Kind, g.Key, f, r.Status, r.NewName, r.Reason})
+		}
+	}
+	lines = append(lines, moves...)
+	return o.write(groups, lines, []string{"kind", "key", "file", "status", "newName", "reason"}, records)
+}
+
+// writeInspections writes the result of each layout followed by a line for each field with
+// its offset, length, raw text, decoded value and error
+func writeInspections(o *output, inspections []ports.LayoutInspection) error {
+	lines := make([]string, 0)
+	records := make([][]string, 0)
+	for _, i := range inspections {
+		if i.Valid {
+			lines = append(lines, fmt.Sprintf("%s: valid", i.Layout))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s: %s error - %s", i.Layout, i.Stage, i.Error))
+		}
+		lines = append(lines, fmt.Sprintf("  %-22s %6s %6s  %-32s %-22s %s", "field", "offset", "length", "raw", "value", "error"))
+		for _, f := range i.Fields {
+			lines = append(lines, fmt.Sprintf("  %-22s %6d %6d  %-32q %-22s %s", f.Name, f.Offset, f.Length, f.Raw, f.Value, f.Error))
+			records = append(records, []string{i.Layout, fmt.Sprint(i.Valid), f.Name, fmt.Sprint(f.Offset), fmt.Sprint(f.Length), f.Raw, f.Value, f.Error})
+		}
+	}
+	header := []string{"layout", "valid", "field", "offset", "length", "raw", "value", "error"}
+	return o.write(inspections, lines, header, records)
+}
+
+// writeValidations writes a line for each file: complete files, truncated files or files with invalid
+// records or totals and files skipped because they are not accepted by the header layout
+func writeValidations(o *output, validations []ports.FileValidation) error {
+	lines := make([]string, 0, len(validations))
+	records := make([][]string, 0, len(validations))
+	for _, v := range validations {
+		switch {
+		case v.Valid:
+			lines = append(lines, fmt.Sprintf("Ok: %s - %d records", v.File, v.Records))
+		case v.Stage == services.TrailerStage || v.Stage == services.RecordStage || v.Stage == services.TotalsStage:
+			lines = append(lines, fmt.Sprintf("No: %s - %s", v.File, v.Error))
+		default:
+			lines = append(lines, fmt.Sprintf("Skipped: %s - %s", v.File, v.Error))
+		}
+		records = append(records, []string{v.File, fmt.Sprint(v.Valid), fmt.Sprint(v.Records), fmt.Sprint(v.DeclaredRecords), v.Stage, v.Error})
+	}
+	return o.write(validations, lines, []string{"file", "valid", "records", "declaredRecords", "stage", "error"}, records)
+}
+
+// acquirerSettlement is a daily settlement of an acquirer
+type acquirerSettlement struct {
+	Acquirer string `json:"acquirer"`
+	ports.DailySettlement
+}
+
+// writeSettlements writes a line for each date, establishment and bank account with the net amount settled
+func writeSettlements(o *output, settlements []acquirerSettlement) error {
+	lines := make([]string, 0, len(settlements))
+	records := make([][]string, 0, len(settlements))
+	for _, s := range settlements {
+		line := fmt.Sprintf("%s %s %d %s: %s (credits %s, debits %s, %d records)", s.Acquirer,
+			s.Date.Format("02/01/2006"), s.Establishment, s.Account, s.Net, s.Credits, s.Debits, s.Records)
+		if s.Negative {
+			line += " - negative"
+		}
+		lines = append(lines, line)
+		records = append(records, []string{s.Acquirer, s.Date.Format(ports.DateFormat), fmt.Sprint(s.Establishment),
+			fmt.Sprint(s.Account.Bank), fmt.Sprint(s.Account.Branch), fmt.Sprint(s.Account.Account),
+			s.Credits.String(), s.Debits.String(), s.Net.String(), fmt.Sprint(s.Records), fmt.Sprint(s.Negative)})
+	}
+	header := []string{"acquirer", "date", "establishment", "bank", "branch", "account", "credits", "debits", "net", "records", "negative"}
+	return o.write(settlements, lines, header, records)
+}
+
+// acquirerSalesTotal is the sales total of a date and card brand of an acquirer
+type acquirerSalesTotal struct {
+	Acquirer string `json:"acquirer"`
+	ports.SalesTotal
+}
+
+// writeSalesTotals writes a line for each date and card brand with the amounts of its sales
+func writeSalesTotals(o *output, totals []acquirerSalesTotal) error {
+	lines := make([]string, 0, len(totals))
+	records := make([][]string, 0, len(totals))
+	for _, t := range totals {
+		lines = append(lines, fmt.Sprintf("%s %s %s: %s (gross %s, discount %s, %d sales)", t.Acquirer,
+			t.Date.Format("02/01/2006"), t.Brand, t.Net, t.Gross, t.Discount, t.Sales))
+		records = append(records, []string{t.Acquirer, t.Date.Format(ports.DateFormat), t.Brand, fmt.Sprint(t.Sales),
+			t.Gross.String(), t.Discount.String(), t.Net.String()})
+	}
+	header := []string{"acquirer", "date", "brand", "sales", "gross", "discount", "net"}
+	return o.write(totals, lines, header, records)
+}
+
+// acquirerAnticipationCost is the cost of an anticipation operation of an acquirer. AboveRate flags an effective
+// rate above the contracted one
+type acquirerAnticipationCost struct {
+	Acquirer string `json:"acquirer"`
+	ports.AnticipationCost
+	AboveRate bool `json:"aboveRate"`
+}
+
+// writeAnticipationCosts writes a line for each anticipation operation with its effective rates in percent
+func writeAnticipationCosts(o *output, costs []acquirerAnticipationCost) error {
+	lines := make([]string, 0, len(costs))
+	records := make([][]string, 0, len(costs))
+	for _, c := range costs {
+		line := fmt.Sprintf("%s %s %d operation %d: %.2f%% monthly, %.2f%% annual (gross %s, fee %s, net %s, %.1f days)",
+			c.Acquirer, c.Date.Format("02/01/2006"), c.Establishment, c.Operation, c.MonthlyRate*100, c.AnnualRate*100,
+			c.Gross, c.Fee, c.Net, c.Days)
+		if c.AboveRate {
+			line += " - above contracted rate"
+		}
+		lines = append(lines, line)
+		records = append(records, []string{c.Acquirer, c.Date.Format(ports.DateFormat), fmt.Sprint(c.Establishment),
+			fmt.Sprint(c.Operation), c.Gross.String(), c.Fee.String(), c.Net.String(), fmt.Sprintf("%.1f", c.Days),
+			fmt.Sprintf("%.4f", c.MonthlyRate*100), fmt.Sprintf("%.4f", c.AnnualRate*100), fmt.Sprint(c.AboveRate)})
+	}
+	header := []string{"acquirer", "date", "establishment", "operation", "gross", "fee", "net", "days", "monthlyRate",
+		"annualRate", "aboveRate"}
+	return o.write(costs, lines, header, records)
+}
+
+// acquirerReceivable is an outstanding receivable of an acquirer
+type acquirerReceivable struct {
+	Acquirer string `json:"acquirer"`
+	ports.OutstandingReceivable
+}
+
+// writeReceivables writes a line for each establishment, card brand and due date with the net amount to be paid
+func writeReceivables(o *output, receivables []acquirerReceivable) error {
+	lines := make([]string, 0, len(receivables))
+	records := make([][]string, 0, len(receivables))
+	for _, r := range receivables {
+		lines = append(lines, fmt.Sprintf("%s %d %s %s: %s (gross %s, %d receivables)", r.Acquirer, r.Establishment,
+			r.Brand, r.DueDate.Format("02/01/2006"), r.Net, r.Gross, r.Receivables))
+		records = append(records, []string{r.Acquirer, fmt.Sprint(r.Establishment), r.Brand, r.DueDate.Format(ports.DateFormat),
+			fmt.Sprint(r.Receivables), r.Gross.String(), r.Net.String()})
+	}
+	header := []string{"acquirer", "establishment", "brand", "dueDate", "receivables", "gross", "net"}
+	return o.write(receivables, lines, header, records)
+}
+
+// writeTransactions writes a line for each transaction of the canonical schema. Dates the statement does not
+// have are written as - on table lines and empty on csv
+func writeTransactions(o *output, transactions []ports.Transaction) error {
+	lines := make([]string, 0, len(transactions))
+	records := make([][]string, 0, len(transactions))
+	for _, t := range transactions {
+		lines = append(lines, fmt.Sprintf("%s %s %d %s: %s (gross %s, fee %s, sale %s, payment %s)", t.Acquirer, t.Kind,
+			t.Establishment, t.Brand, t.Net, t.Gross, t.Fee, transactionDate(t.SaleDate, "02/01/2006", "-"),
+			transactionDate(t.PaymentDate, "02/01/2006", "-")))
+		records = append(records, []string{t.Acquirer, t.Kind, fmt.Sprint(t.Establishment), t.Brand, t.Product,
+			fmt.Sprint(t.Summary), fmt.Sprint(t.Nsu), t.Authorization, t.Card, fmt.Sprint(t.Installment),
+			fmt.Sprint(t.InstallmentCount), t.Gross.String(), t.Fee.String(), t.Net.String(),
+			transactionDate(t.SaleDate, ports.DateFormat, ""), transactionDate(t.PaymentDate, ports.DateFormat, "")})
+	}
+	header := []string{"acquirer", "kind", "establishment", "brand", "product", "summary", "nsu", "authorization", "card",
+		"installment", "installmentCount", "gross", "fee", "net", "saleDate", "paymentDate"}
+	return o.write(transactions, lines, header, records)
+}
+
+// transactionDate formats a date of a transaction, or returns empty for a zero date
+func transactionDate(date time.Time, layout string, empty string) string {
+	if date.IsZero() {
+		return empty
+	}
+	return date.Format(layout)
+}
+
+// reconciliationStatus has the descriptions of the reconciliation statuses on table lines
+var reconciliationStatus = map[string]string{
+	ports.OnTimeStatus:    "paid on time",
+	ports.LateStatus:      "paid late",
+	ports.DivergentStatus: "paid with a different amount",
+	ports.OpenStatus:      "open",
+}
+
+// writeReconciliations writes a line for each scheduled payment with the amount paid and its status
+func writeReconciliations(o *output, reconciliations []ports.Reconciliation) error {
+	lines := make([]string, 0, len(reconciliations))
+	records := make([][]string, 0, len(reconciliations))
+	for _, r := range reconciliations {
+		line := fmt.Sprintf("%s %d %d/%d %s: %s (expected %s", r.Acquirer, r.Establishment, r.Summary, r.Installment,
+			r.ScheduledDate.Format("02/01/2006"), reconciliationStatus[r.Status], r.Expected)
+		if r.Settlements > 0 {
+			line += fmt.Sprintf(", paid %s on %s, %d settlements", r.Paid, r.PaymentDate.Format("02/01/2006"), r.Settlements)
+		}
+		lines = append(lines, line+")")
+		records = append(records, []string{r.Acquirer, fmt.Sprint(r.Establishment), fmt.Sprint(r.Summary),
+			fmt.Sprint(r.Installment), r.ScheduledDate.Format(ports.DateFormat), r.Expected.String(),
+			transactionDate(r.PaymentDate, ports.DateFormat, ""), r.Paid.String(), fmt.Sprint(r.Settlements), r.Status})
+	}
+	header := []string{"acquirer", "establishment", "summary", "installment", "scheduledDate", "expected", "paymentDate",
+		"paid", "settlements", "status"}
+	return o.write(reconciliations, lines, header, records)
+}
+
+// saleReconciliationStatus has the descriptions of the sales reconciliation statuses on table lines
+var saleReconciliationStatus = map[string]string{
+	ports.ErpOnlyStatus:      "missing at the acquirer",
+	ports.AcquirerOnlyStatus: "missing in the ERP",
+	ports.DivergentStatus:    "different amount",
+}
+
+// writeSaleReconciliations writes a line for each ERP or acquirer sale that does not reconcile with the amounts
+// and identification found
+func writeSaleReconciliations(o *output, reconciliations []ports.SaleReconciliation) error {
+	lines := make([]string, 0, len(reconciliations))
+	records := make([][]string, 0, len(reconciliations))
+	for _, r := range reconciliations {
+		sources, amounts := make([]string, 0, 2), make([]string, 0, 5)
+		if r.File != "" {
+			sources = append(sources, fmt.Sprintf("%s:%d", r.File, r.Line))
+			amounts = append(amounts, "erp "+r.ErpAmount.String())
+		}
+		if r.Acquirer != "" {
+			sources = append(sources, fmt.Sprintf("%s %d", r.Acquirer, r.Establishment))
+			amounts = append(amounts, "acquirer "+r.AcquirerAmount.String())
+		}
+		if r.Nsu != 0 {
+			amounts = append(amounts, fmt.Sprintf("nsu %d", r.Nsu))
+		}
+		if r.Authorization != "" {
+			amounts = append(amounts, "authorization "+r.Authorization)
+		}
+		if r.Card != "" {
+			amounts = append(amounts, "card "+r.Card)
+		}
+		lines = append(lines, fmt.Sprintf("%s %s: %s (%s)", r.Date.Format("02/01/2006"), strings.Join(sources, " "),
+			saleReconciliationStatus[r.Status], strings.Join(amounts, ", ")))
+		records = append(records, []string{r.Status, r.File, fmt.Sprint(r.Line), r.Acquirer, fmt.Sprint(r.Establishment),
+			r.Date.Format(ports.DateFormat), fmt.Sprint(r.Nsu), r.Authorization, r.Card, r.ErpAmount.String(),
+			r.AcquirerAmount.String()})
+	}
+	header := []string{"status", "file", "line", "acquirer", "establishment", "date", "nsu", "authorization", "card",
+		"erpAmount", "acquirerAmount"}
+	return o.write(reconciliations, lines, header, records)
+}
+
+// depositStatus has the descriptions of the deposit statuses on table lines
+var depositStatus = map[string]string{
+	ports.DepositedStatus: "deposited",
+	ports.MissingStatus:   "missing payment",
+}
+
+// writeDeposits writes a line for each acquirer credit with the bank entry that deposited it
+func writeDeposits(o *output, deposits []ports.Deposit) error {
+	lines := make([]string, 0, len(deposits))
+	records := make([][]string, 0, len(deposits))
+	for _, d := range deposits {
+		line := fmt.Sprintf("%s %s %d %s: %s (expected %s", d.Acquirer, d.Date.Format("02/01/2006"), d.Establishment,
+			d.Account, depositStatus[d.Status], d.Expected)
+		if d.File != "" {
+			line += fmt.Sprintf(", entry %s:%d %s", d.File, d.Line, d.History)
+		}
+		lines = append(lines, strings.TrimSpace(line)+")")
+		records = append(records, []string{d.Acquirer, d.Date.Format(ports.DateFormat), fmt.Sprint(d.Establishment),
+			fmt.Sprint(d.Account.Bank), fmt.Sprint(d.Account.Branch), fmt.Sprint(d.Account.Account), d.Expected.String(),
+			d.File, fmt.Sprint(d.Line), d.History, d.Document, d.Status})
+	}
+	header := []string{"acquirer", "date", "establishment", "bank", "branch", "account", "expected", "file", "line",
+		"history", "document", "status"}
+	return o.write(deposits, lines, header, records)
+}
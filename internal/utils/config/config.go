@@ -0,0 +1,166 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvPrefix is the prefix of the environment variables that override the profile values
+	EnvPrefix = "CIELO_EDI_"
+)
+
+// Calendar has the days a statement file is expected. Weekdays has day names (monday or mon)
+// and Holidays has dates (yyyy-mm-dd) without files. Empty weekdays means every day
+type Calendar struct {
+	Weekdays []string `yaml:"weekdays" json:"weekdays"`
+	Holidays []string `yaml:"holidays" json:"holidays"`
+}
+
+// Erp has the layout of the csv sales exports of the ERP or POS of a client. Columns maps the sale
+// fields (date, amount, nsu, authorization and card) to the names of the export columns and DateFormat is the format
+// of the dates (ex dd/mm/yyyy). Sales match the acquirer sales up to AmountTolerance (in units, ex 0.05) and
+// DayTolerance days apart
+type Erp struct {
+	Columns         map[string]string `yaml:"columns" json:"columns"`
+	DateFormat      string            `yaml:"dateFormat" json:"dateFormat"`
+	AmountTolerance float64           `yaml:"amountTolerance" json:"amountTolerance"`
+	DayTolerance    int               `yaml:"dayTolerance" json:"dayTolerance"`
+}
+
+// Bank has the directory of the bank statement return files of a client (Path). Credits are deposited by entries
+// up to DayTolerance days after or before their payment date
+type Bank struct {
+	Path         string `yaml:"path" json:"path"`
+	DayTolerance int    `yaml:"dayTolerance" json:"dayTolerance"`
+}
+
+// Profile has the settings of a client: the directory of the files (Path, or Paths by acquirer name),
+// the acquirer names, the headquarters (ECs), the template of renamed files, the calendar, the
+// encoding of the files (Encoding, or Encodings by acquirer name), the layout of its ERP sales exports
+// and its bank statement return files (Bank)
+type Profile struct {
+	Path         string            `yaml:"path" json:"path"`
+	Paths        map[string]string `yaml:"paths" json:"paths"`
+	Quarantine   string            `yaml:"quarantine" json:"quarantine"`
+	Acquirers    []string          `yaml:"acquirers" json:"acquirers"`
+	Headquarters []int64           `yaml:"headquarters" json:"headquarters"`
+	NameTemplate string            `yaml:"nameTemplate" json:"nameTemplate"`
+	Calendar     Calendar          `yaml:"calendar" json:"calendar"`
+	Encoding     string            `yaml:"encoding" json:"encoding"`
+	Encodings    map[string]string `yaml:"encodings" json:"encodings"`
+	Erp          Erp               `yaml:"erp" json:"erp"`
+	Bank         Bank              `yaml:"bank" json:"bank"`
+}
+
+// Config has the profiles of a configuration file
+type Config struct {
+	Profiles map[string]Profile `yaml:"profiles" json:"profiles"`
+}
+
+// Load reads a configuration file. Files with .json extension are read as json, others as yaml
+func Load(file string) (*Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	if strings.ToLower(filepath.Ext(file)) == ".json" {
+		err = json.Unmarshal(data, config)
+	} else {
+		err = yaml.Unmarshal(data, config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s error: %v", file, err)
+	}
+	return config, nil
+}
+
+// GetProfile returns a profile with the values overridden by the environment variables CIELO_EDI_PATH,
+// CIELO_EDI_QUARANTINE, CIELO_EDI_ACQUIRERS, CIELO_EDI_HEADQUARTERS, CIELO_EDI_NAME_TEMPLATE and CIELO_EDI_ENCODING.
+// CIELO_EDI_PATH and CIELO_EDI_ENCODING replace the values by acquirer too
+func (c Config) GetProfile(name string) (Profile, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("profile %s not found (should be %s)", name, strings.Join(c.GetProfileNames(), ", "))
+	}
+	if err := profile.applyEnv(os.Getenv); err != nil {
+		return Profile{}, err
+	}
+	return profile, nil
+}
+
+// GetProfileNames returns the sorted names of the profiles
+func (c Config) GetProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetPath returns the directory of the files of an acquirer
+func (p Profile) GetPath(acquirer string) string {
+	if path, ok := p.Paths[acquirer]; ok {
+		return path
+	}
+	return p.Path
+}
+
+// GetEncoding returns the encoding of the files of an acquirer. Empty means the default encoding
+func (p Profile) GetEncoding(acquirer string) string {
+	if encoding, ok := p.Encodings[acquirer]; ok {
+		return encoding
+	}
+	return p.Encoding
+}
+
+func (p *Profile) applyEnv(getenv func(string) string) error {
+	if v := getenv(EnvPrefix + "PATH"); v != "" {
+		p.Path = v
+		p.Paths = nil
+	}
+	if v := getenv(EnvPrefix + "QUARANTINE"); v != "" {
+		p.Quarantine = v
+	}
+	if v := getenv(EnvPrefix + "NAME_TEMPLATE"); v != "" {
+		p.NameTemplate = v
+	}
+	if v := getenv(EnvPrefix + "ENCODING"); v != "" {
+		p.Encoding = v
+		p.Encodings = nil
+	}
+	if v := getenv(EnvPrefix + "ACQUIRERS"); v != "" {
+		p.Acquirers = splitList(v)
+	}
+	if v := getenv(EnvPrefix + "HEADQUARTERS"); v != "" {
+		headquarters := make([]int64, 0)
+		for _, item := range splitList(v) {
+			hq, err := strconv.ParseInt(item, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%sHEADQUARTERS error: %s is not numeric", EnvPrefix, item)
+			}
+			headquarters = append(headquarters, hq)
+		}
+		p.Headquarters = headquarters
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	path       = "./temp"
+	yamlConfig = `
+profiles:
+  clientx:
+    path: /data/clientx
+    paths:
+      redecredito: /data/clientx/rede
+    acquirers: [cielovendas, redecredito]
+    headquarters: [1023863232]
+    nameTemplate: "{acquirer}-{headquarter}-{periodInit}.txt"
+    calendar:
+      weekdays: [mon, tue, wed, thu, fri]
+      holidays: ["2021-12-25"]
+    encodings:
+      redecredito: iso-8859-1
+    erp:
+      columns:
+        date: Data Venda
+        amount: Valor
+      dateFormat: dd/mm/yyyy
+      amountTolerance: 0.05
+      dayTolerance: 1
+    bank:
+      path: /data/clientx/bank
+      dayTolerance: 2
+  clienty:
+    path: /data/clienty
+    acquirers: [getnet]
+`
+	jsonConfig = `{"profiles": {"clientx": {"path": "/data/clientx", "acquirers": ["cielovendas"]}}}`
+)
+
+func initPath() {
+	err := os.Mkdir(path, 0755)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func endPath() {
+	err := os.RemoveAll(path)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestLoadYaml(t *testing.T) {
+	initPath()
+	fn := filepath.Join(path, "config.yaml")
+	os.WriteFile(fn, []byte(yamlConfig), 0644)
+	config, err := Load(fn)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"clientx", "clienty"}, config.GetProfileNames())
+	profile, err := config.GetProfile("clientx")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cielovendas", "redecredito"}, profile.Acquirers)
+	assert.Equal(t, []int64{1023863232}, profile.Headquarters)
+	assert.Equal(t, "/data/clientx", profile.GetPath("cielovendas"))
+	assert.Equal(t, "/data/clientx/rede", profile.GetPath("redecredito"))
+	assert.Equal(t, "{acquirer}-{headquarter}-{periodInit}.txt", profile.NameTemplate)
+	assert.Equal(t, []string{"mon", "tue", "wed", "thu", "fri"}, profile.Calendar.Weekdays)
+	assert.Equal(t, []string{"2021-12-25"}, profile.Calendar.Holidays)
+	assert.Equal(t, "", profile.GetEncoding("cielovendas"))
+	assert.Equal(t, "iso-8859-1", profile.GetEncoding("redecredito"))
+	assert.Equal(t, Erp{Columns: map[string]string{"date": "Data Venda", "amount": "Valor"},
+		DateFormat: "dd/mm/yyyy", AmountTolerance: 0.05, DayTolerance: 1}, profile.Erp)
+	assert.Equal(t, Bank{Path: "/data/clientx/bank", DayTolerance: 2}, profile.Bank)
+	_, err = config.GetProfile("clientz")
+	assert.NotNil(t, err)
+	assert.Equal(t, "profile clientz not found (should be clientx, clienty)", err.Error())
+	endPath()
+}
+
+func TestLoadJson(t *testing.T) {
+	initPath()
+	fn := filepath.Join(path, "config.json")
+	os.WriteFile(fn, []byte(jsonConfig), 0644)
+	config, err := Load(fn)
+	assert.Nil(t, err)
+	profile, err := config.GetProfile("clientx")
+	assert.Nil(t, err)
+	assert.Equal(t, "/data/clientx", profile.Path)
+	assert.Equal(t, []string{"cielovendas"}, profile.Acquirers)
+	os.WriteFile(fn, []byte("{"), 0644)
+	_, err = Load(fn)
+	assert.NotNil(t, err)
+	_, err = Load(filepath.Join(path, "none.yaml"))
+	assert.NotNil(t, err)
+	endPath()
+}
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"CIELO_EDI_PATH":         "/override",
+		"CIELO_EDI_ACQUIRERS":    "getnet, rededebito",
+		"CIELO_EDI_HEADQUARTERS": "1,2",
+		"CIELO_EDI_ENCODING":     "windows-1252",
+	}
+	profile := Profile{Path: "/data", Paths: map[string]string{"getnet": "/data/getnet"}, Acquirers: []string{"cielovendas"},
+		Encodings: map[string]string{"cielovendas": "utf-8"}}
+	err := profile.applyEnv(func(key string) string { return env[key] })
+	assert.Nil(t, err)
+	assert.Equal(t, "/override", profile.Path)
+	assert.Equal(t, "/override", profile.GetPath("getnet"))
+	assert.Equal(t, "windows-1252", profile.GetEncoding("cielovendas"))
+	assert.Equal(t, []string{"getnet", "rededebito"}, profile.Acquirers)
+	assert.Equal(t, []int64{1, 2}, profile.Headquarters)
+	env["CIELO_EDI_HEADQUARTERS"] = "1,x"
+	err = profile.applyEnv(func(key string) string { return env[key] })
+	assert.NotNil(t, err)
+}
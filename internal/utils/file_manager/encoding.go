@@ -0,0 +1,147 @@
+package file_manager
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultEncoding is the encoding of the files when none is set. Its lines are not decoded
+	DefaultEncoding = "utf-8"
+	// bom is the byte order mark some editors write at the start of UTF-8 files
+	bom = "\xef\xbb\xbf"
+)
+
+var (
+	// encodingMap maps the encoding names to the runes of the bytes 0x80 to 0xff. A nil table keeps the bytes
+	encodingMap = map[string]*[128]rune{
+		"utf-8":        nil,
+		"iso-8859-1":   latin1Table(),
+		"windows-1252": windows1252Table(),
+	}
+)
+
+// latin1Table maps each byte to the rune of the same value
+func latin1Table() *[128]rune {
+	table := [128]rune{}
+	for i := range table {
+		table[i] = rune(0x80 + i)
+	}
+	return &table
+}
+
+// windows1252Table is latin1 with printable characters on 0x80 to 0x9f
+func windows1252Table() *[128]rune {
+	table := latin1Table()
+	copy(table[:32], []rune{
+		'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+		'ˆ', '‰', 'Š', '‹', 'Œ', '\u008d', 'Ž', '\u008f',
+		'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+		'˜', '™', 'š', '›', 'œ', '\u009d', 'ž', 'Ÿ',
+	})
+	return table
+}
+
+// GetEncodings returns the names of the supported encodings
+func GetEncodings() []string {
+	names := make([]string, 0, len(encodingMap))
+	for name := range encodingMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getEncodingTable finds the table of an encoding name (case insensitive)
+func getEncodingTable(name string) (*[128]rune, error) {
+	table, ok := encodingMap[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("encoding %s not found (should be %s)", name, strings.Join(GetEncodings(), ", "))
+	}
+	return table, nil
+}
+
+// decode converts a text of a single byte encoding to UTF-8
+func decode(txt string, table *[128]rune) string {
+	if table == nil {
+		return txt
+	}
+	ascii := true
+	for i := 0; i < len(txt); i++ {
+		if txt[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return txt
+	}
+	var b strings.Builder
+	b.Grow(len(txt) * 2)
+	for i := 0; i < len(txt); i++ {
+		if txt[i] < 0x80 {
+			b.WriteByte(txt[i])
+		} else {
+			b.WriteRune(table[txt[i]-0x80])
+		}
+	}
+	return b.String()
+}
+
+// decodeReader reads a file converting its bytes to UTF-8
+type decodeReader struct {
+	reader  *bufio.Reader
+	closer  io.Closer
+	table   *[128]rune
+	pending []byte
+}
+
+// newDecodeReader skips the byte order mark of a file and decodes it with the table
+func newDecodeReader(file io.ReadCloser, table *[128]rune) *decodeReader {
+	reader := bufio.NewReader(file)
+	if start, err := reader.Peek(len(bom)); err == nil && string(start) == bom {
+		reader.Discard(len(bom))
+	}
+	return &decodeReader{reader: reader, closer: file, table: table}
+}
+
+func (d *decodeReader) Read(p []byte) (int, error) {
+	if d.table == nil {
+		return d.reader.Read(p)
+	}
+	n := 0
+	for n < len(p) {
+		if len(d.pending) > 0 {
+			c := copy(p[n:], d.pending)
+			d.pending = d.pending[c:]
+			n += c
+			continue
+		}
+		if n > 0 && d.reader.Buffered() == 0 {
+			break
+		}
+		b, err := d.reader.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		if b < 0x80 {
+			p[n] = b
+			n++
+			continue
+		}
+		buf := make([]byte, utf8.UTFMax)
+		d.pending = buf[:utf8.EncodeRune(buf, d.table[b-0x80])]
+	}
+	return n, nil
+}
+
+func (d *decodeReader) Close() error {
+	return d.closer.Close()
+}
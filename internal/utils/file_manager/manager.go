@@ -2,14 +2,33 @@ package file_manager
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"io/fs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-type FileManager struct{}
+const (
+	// lastLineChunk is the size of the blocks read backwards by GetLastLine
+	lastLineChunk = 4096
+	// maxLastLine bounds the bytes GetLastLine reads looking for the last line
+	maxLastLine = 1024 * 1024
+	// scanBuffer is the initial line buffer of the scanners, that grows up to maxLineSize
+	scanBuffer  = 64 * 1024
+	maxLineSize = 16 * 1024 * 1024
+)
+
+// FileManager reads and moves the statement files. Lines are decoded from the encoding of the files
+// (see SetEncoding) to UTF-8 and the byte order mark is removed
+type FileManager struct {
+	table *[128]rune
+}
 
 func NewFileManager() *FileManager {
 	return &FileManager{}
@@ -23,28 +42,137 @@ func (f FileManager) GetFiles(path string) ([]fs.FileInfo, error) {
 	return files, nil
 }
 
-func (f FileManager) GetFileScanner(path string, filename string) (*bufio.Scanner, error) {
+// SetEncoding sets the encoding of the files (utf-8, iso-8859-1 or windows-1252)
+func (f *FileManager) SetEncoding(name string) error {
+	table, err := getEncodingTable(name)
+	if err != nil {
+		return err
+	}
+	f.table = table
+	return nil
+}
+
+// GetFileScanner opens a file and returns a scanner of its raw lines (up to maxLineSize bytes) and the file to be closed
+func (f FileManager) GetFileScanner(path string, filename string) (*bufio.Scanner, io.Closer, error) {
 	fileIO, err := os.OpenFile(filepath.Join(path, filename), os.O_RDONLY, 0600)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	scanner := bufio.NewScanner(fileIO)
-	return scanner, nil
+	scanner.Buffer(make([]byte, scanBuffer), maxLineSize)
+	return scanner, fileIO, nil
 }
 
+// GetFirstLine returns the first line of a file decoded to UTF-8 without byte order mark and line break
 func (f FileManager) GetFirstLine(path string, file fs.FileInfo) (string, error) {
 	if file.IsDir() {
 		return "", fmt.Errorf("%s is a directory", file.Name())
 	}
-	buf, err := f.GetFileScanner(path, file.Name())
+	buf, closer, err := f.GetFileScanner(path, file.Name())
 	if err != nil {
 		return "", err
 	}
+	defer closer.Close()
 	if !buf.Scan() {
+		if err := buf.Err(); err != nil {
+			return "", fmt.Errorf("error scanning %s: %v", file.Name(), err)
+		}
 		return "", fmt.Errorf("error scanning %s", file.Name())
 	}
-	hDate := buf.Text()
-	return hDate, nil
+	line := strings.TrimPrefix(buf.Text(), bom)
+	return decode(strings.TrimRight(line, "\r"), f.table), nil
+}
+
+// GetLastLine returns the last non-blank line of a file decoded to UTF-8 without the line break (LF or CRLF).
+// The file is read backwards in blocks, so only its end is read
+func (f FileManager) GetLastLine(path string, file fs.FileInfo) (string, error) {
+	if file.IsDir() {
+		return "", fmt.Errorf("%s is a directory", file.Name())
+	}
+	fileIO, err := os.Open(filepath.Join(path, file.Name()))
+	if err != nil {
+		return "", err
+	}
+	defer fileIO.Close()
+	info, err := fileIO.Stat()
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, 0, lastLineChunk)
+	for pos := info.Size(); pos > 0; {
+		size := int64(lastLineChunk)
+		if pos < size {
+			size = pos
+		}
+		pos -= size
+		chunk := make([]byte, size, int64(len(buf))+size)
+		if _, err := fileIO.ReadAt(chunk, pos); err != nil {
+			return "", err
+		}
+		buf = append(chunk, buf...)
+		if pos == 0 {
+			buf = bytes.TrimPrefix(buf, []byte(bom))
+		}
+		if line, ok := findLastLine(buf, pos == 0); ok {
+			return decode(line, f.table), nil
+		}
+		if len(buf) >= maxLastLine {
+			return "", fmt.Errorf("last line of %s not found in %d bytes", file.Name(), maxLastLine)
+		}
+	}
+	return "", fmt.Errorf("%s is empty", file.Name())
+}
+
+// findLastLine finds the last non-blank line of the end of a file. start tells that buf is the whole file
+func findLastLine(buf []byte, start bool) (string, bool) {
+	for {
+		buf = bytes.TrimRight(buf, "\r\n")
+		i := bytes.LastIndexByte(buf, '\n')
+		if i < 0 {
+			if start && len(bytes.TrimSpace(buf)) > 0 {
+				return string(buf), true
+			}
+			return "", false
+		}
+		if line := buf[i+1:]; len(bytes.TrimSpace(line)) > 0 {
+			return string(line), true
+		}
+		buf = buf[:i]
+	}
+}
+
+// OpenReader opens a file for reading its content decoded to UTF-8 without byte order mark. The caller closes it
+func (f FileManager) OpenReader(path string, file fs.FileInfo) (io.ReadCloser, error) {
+	if file.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", file.Name())
+	}
+	fileIO, err := os.Open(filepath.Join(path, file.Name()))
+	if err != nil {
+		return nil, err
+	}
+	return newDecodeReader(fileIO, f.table), nil
+}
+
+// CountLines reads a file to the end and returns the number of non-blank lines
+func (f FileManager) CountLines(path string, file fs.FileInfo) (int, error) {
+	if file.IsDir() {
+		return 0, fmt.Errorf("%s is a directory", file.Name())
+	}
+	scanner, closer, err := f.GetFileScanner(path, file.Name())
+	if err != nil {
+		return 0, err
+	}
+	defer closer.Close()
+	count := 0
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) != "" {
+			count++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return count, nil
 }
 
 func (f FileManager) RenameFile(path string, nameFrom string, nameTo string) error {
@@ -55,3 +183,45 @@ func (f FileManager) RenameFile(path string, nameFrom string, nameTo string) err
 	}
 	return nil
 }
+
+func (f FileManager) MoveFile(pathFrom string, name string, pathTo string) error {
+	if err := os.MkdirAll(pathTo, 0755); err != nil {
+		return err
+	}
+	from := filepath.Join(pathFrom, name)
+	to := filepath.Join(pathTo, name)
+	if _, err := os.Stat(to); err == nil {
+		return fmt.Errorf("%s already exists", to)
+	}
+	if err := os.Rename(from, to); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (f FileManager) GetFileHash(path string, file fs.FileInfo) (string, error) {
+	if file.IsDir() {
+		return "", fmt.Errorf("%s is a directory", file.Name())
+	}
+	fileIO, err := os.Open(filepath.Join(path, file.Name()))
+	if err != nil {
+		return "", err
+	}
+	defer fileIO.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, fileIO); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (f FileManager) WriteFile(path string, name string, data []byte) error {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(path, name), data, 0644)
+}
+
+func (f FileManager) RemoveFile(path string, name string) error {
+	return os.Remove(filepath.Join(path, name))
+}
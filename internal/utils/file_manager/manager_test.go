@@ -1,10 +1,12 @@
 package file_manager
 
 import (
+	"io"
 	"io/fs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -73,6 +75,70 @@ func TestGetFirstLine(t *testing.T) {
 	endPath()
 }
 
+func TestCountLines(t *testing.T) {
+	initPath()
+	os.WriteFile(filepath.Join(path, listName[0]), []byte("header\r\ndetail\r\n\r\ntrailer\r\n\r\n"), 0755)
+	os.WriteFile(filepath.Join(path, listName[1]), []byte(""), 0755)
+	files, err := ioutil.ReadDir(path)
+	assert.Nil(t, err)
+	fm := NewFileManager()
+	count, err := fm.CountLines(path, files[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+	count, err = fm.CountLines(path, files[1])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+	endPath()
+}
+
+func TestGetLastLine(t *testing.T) {
+	initPath()
+	long := strings.Repeat("x", lastLineChunk*3)
+	tests := []struct {
+		content string
+		last    string
+	}{
+		{"header\r\ndetail\r\ntrailer  \r\n", "trailer  "},
+		{"header\ndetail\ntrailer", "trailer"},
+		{"header\r\ntrailer\r\n\r\n   \r\n", "trailer"},
+		{"trailer\n", "trailer"},
+		{"header\n" + long + "\n" + strings.Repeat("\n", lastLineChunk), long},
+	}
+	fm := NewFileManager()
+	for _, test := range tests {
+		os.WriteFile(filepath.Join(path, listName[0]), []byte(test.content), 0755)
+		files, err := ioutil.ReadDir(path)
+		assert.Nil(t, err)
+		line, err := fm.GetLastLine(path, files[0])
+		assert.Nil(t, err)
+		assert.Equal(t, test.last, line)
+	}
+	os.WriteFile(filepath.Join(path, listName[0]), []byte("\r\n \n"), 0755)
+	files, _ := ioutil.ReadDir(path)
+	_, err := fm.GetLastLine(path, files[0])
+	assert.NotNil(t, err)
+	assert.Equal(t, "file1.txt is empty", err.Error())
+	endPath()
+}
+
+func TestOpenReader(t *testing.T) {
+	initPath()
+	os.WriteFile(filepath.Join(path, listName[0]), []byte("abc"), 0755)
+	files, _ := ioutil.ReadDir(path)
+	fm := NewFileManager()
+	reader, err := fm.OpenReader(path, files[0])
+	assert.Nil(t, err)
+	data, err := io.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", string(data))
+	assert.Nil(t, reader.Close())
+	os.Mkdir(filepath.Join(path, "sub"), 0755)
+	files, _ = ioutil.ReadDir(path)
+	_, err = fm.OpenReader(path, files[1])
+	assert.NotNil(t, err)
+	endPath()
+}
+
 func TestRenameFile(t *testing.T) {
 	initPath()
 	first := "abc"
@@ -87,3 +153,109 @@ func TestRenameFile(t *testing.T) {
 	assert.Equal(t, listName[1], files[0].Name())
 	endPath()
 }
+
+func TestMoveFile(t *testing.T) {
+	initPath()
+	fn := filepath.Join(path, listName[0])
+	os.WriteFile(fn, []byte("abc"), 0755)
+	fm := NewFileManager()
+	quarantine := filepath.Join(path, "quarantine")
+	err := fm.MoveFile(path, listName[0], quarantine)
+	assert.Nil(t, err)
+	files, err := ioutil.ReadDir(quarantine)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files))
+	assert.Equal(t, listName[0], files[0].Name())
+	os.WriteFile(fn, []byte("abc"), 0755)
+	err = fm.MoveFile(path, listName[0], quarantine)
+	assert.NotNil(t, err)
+	endPath()
+}
+
+func TestGetFileHash(t *testing.T) {
+	initPath()
+	os.WriteFile(filepath.Join(path, listName[0]), []byte("abc"), 0755)
+	os.WriteFile(filepath.Join(path, listName[1]), []byte("abc"), 0755)
+	files, err := ioutil.ReadDir(path)
+	assert.Nil(t, err)
+	fm := NewFileManager()
+	h1, err := fm.GetFileHash(path, files[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", h1)
+	h2, err := fm.GetFileHash(path, files[1])
+	assert.Nil(t, err)
+	assert.Equal(t, h1, h2)
+	endPath()
+}
+
+func TestWriteRemoveFile(t *testing.T) {
+	initPath()
+	fm := NewFileManager()
+	sub := filepath.Join(path, "sub")
+	err := fm.WriteFile(sub, listName[0], []byte("abc"))
+	assert.Nil(t, err)
+	data, err := os.ReadFile(filepath.Join(sub, listName[0]))
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", string(data))
+	err = fm.RemoveFile(sub, listName[0])
+	assert.Nil(t, err)
+	files, err := ioutil.ReadDir(sub)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(files))
+	err = fm.RemoveFile(sub, listName[0])
+	assert.NotNil(t, err)
+	endPath()
+}
+
+func TestGetFirstLineEncoding(t *testing.T) {
+	initPath()
+	long := strings.Repeat("x", 100*1024)
+	tests := []struct {
+		encoding string
+		content  string
+		first    string
+	}{
+		{"utf-8", bom + "CAFÉ\r\ndetail\r\n", "CAFÉ"},
+		{"utf-8", long + "\ndetail", long},
+		{"iso-8859-1", "CAF\xc9 S\xc3O\r\n", "CAFÉ SÃO"},
+		{"windows-1252", "\x80 10 \x93ok\x94\n", "€ 10 “ok”"},
+		{"iso-8859-1", "\x80", "\u0080"},
+	}
+	for _, test := range tests {
+		os.WriteFile(filepath.Join(path, listName[0]), []byte(test.content), 0755)
+		files, _ := ioutil.ReadDir(path)
+		fm := NewFileManager()
+		assert.Nil(t, fm.SetEncoding(test.encoding))
+		line, err := fm.GetFirstLine(path, files[0])
+		assert.Nil(t, err)
+		assert.Equal(t, test.first, line)
+	}
+	fm := NewFileManager()
+	err := fm.SetEncoding("ebcdic")
+	assert.NotNil(t, err)
+	assert.Equal(t, "encoding ebcdic not found (should be iso-8859-1, utf-8, windows-1252)", err.Error())
+	endPath()
+}
+
+func TestOpenReaderEncoding(t *testing.T) {
+	initPath()
+	content := "CAF\xc9\r\n" + strings.Repeat("S\xc3O ", 5000) + "\r\n"
+	os.WriteFile(filepath.Join(path, listName[0]), []byte(bom+content), 0755)
+	files, _ := ioutil.ReadDir(path)
+	fm := NewFileManager()
+	fm.SetEncoding("iso-8859-1")
+	reader, err := fm.OpenReader(path, files[0])
+	assert.Nil(t, err)
+	data, err := io.ReadAll(reader)
+	assert.Nil(t, err)
+	reader.Close()
+	assert.Equal(t, "CAFÉ\r\n"+strings.Repeat("SÃO ", 5000)+"\r\n", string(data))
+	last, err := fm.GetLastLine(path, files[0])
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Repeat("SÃO ", 5000), last)
+	os.WriteFile(filepath.Join(path, listName[0]), []byte(bom+"trailer"), 0755)
+	last, err = fm.GetLastLine(path, files[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "trailer", last)
+	endPath()
+}
@@ -0,0 +1,99 @@
+package file_watcher
+
+import (
+	"context"
+	"io/fs"
+	"io/ioutil"
+	"time"
+)
+
+// watchedFile keeps the last size seen of a file and since when it is unchanged
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+	since   time.Time
+}
+
+// FileWatcher polls a directory and reports files once their size has been stable for a given interval
+type FileWatcher struct {
+	interval time.Duration
+	stable   time.Duration
+	files    map[string]watchedFile
+	done     map[string]bool
+}
+
+func NewFileWatcher(interval time.Duration, stable time.Duration) *FileWatcher {
+	return &FileWatcher{interval: interval, stable: stable, files: make(map[string]watchedFile), done: make(map[string]bool)}
+}
+
+// Poll reads the path once and returns the files that became stable since the last call
+//
+// path has the directory to be watched
+// now has the time of the poll
+//
+// returns the files that are ready to be processed and a possible error. Each file is returned only once
+// while it keeps its name
+func (w *FileWatcher) Poll(path string, now time.Time) ([]fs.FileInfo, error) {
+	ready := make([]fs.FileInfo, 0)
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return ready, err
+	}
+	present := make(map[string]bool)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		name := file.Name()
+		present[name] = true
+		if w.done[name] {
+			continue
+		}
+		last, ok := w.files[name]
+		if !ok || last.size != file.Size() || !last.modTime.Equal(file.ModTime()) {
+			w.files[name] = watchedFile{size: file.Size(), modTime: file.ModTime(), since: now}
+			continue
+		}
+		if now.Sub(last.since) >= w.stable {
+			ready = append(ready, file)
+			w.done[name] = true
+			delete(w.files, name)
+		}
+	}
+	for name := range w.files {
+		if !present[name] {
+			delete(w.files, name)
+		}
+	}
+	for name := range w.done {
+		if !present[name] {
+			delete(w.done, name)
+		}
+	}
+	return ready, nil
+}
+
+// Watch polls the path until the context is done and calls handle for each stable file
+//
+// returns nil when the context is done or the error of reading the path
+func (w *FileWatcher) Watch(ctx context.Context, path string, handle func(fs.FileInfo)) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		files, err := w.Poll(path, time.Now())
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if ctx.Err() != nil {
+				return nil
+			}
+			handle(file)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package file_watcher
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	path = "./temp"
+)
+
+func initPath() {
+	err := os.Mkdir(path, 0755)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func endPath() {
+	err := os.RemoveAll(path)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestPollStable(t *testing.T) {
+	initPath()
+	os.WriteFile(filepath.Join(path, "file1.txt"), []byte("abc"), 0755)
+	os.Mkdir(filepath.Join(path, "dir"), 0755)
+	w := NewFileWatcher(time.Second, 10*time.Second)
+	now := time.Now()
+	files, err := w.Poll(path, now)
+	assert.Nil(t, err)
+	assert.Len(t, files, 0)
+	files, err = w.Poll(path, now.Add(5*time.Second))
+	assert.Nil(t, err)
+	assert.Len(t, files, 0)
+	files, err = w.Poll(path, now.Add(10*time.Second))
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "file1.txt", files[0].Name())
+	files, err = w.Poll(path, now.Add(20*time.Second))
+	assert.Nil(t, err)
+	assert.Len(t, files, 0)
+	endPath()
+}
+
+func TestPollGrowing(t *testing.T) {
+	initPath()
+	fn := filepath.Join(path, "file1.txt")
+	os.WriteFile(fn, []byte("abc"), 0755)
+	w := NewFileWatcher(time.Second, 10*time.Second)
+	now := time.Now()
+	files, err := w.Poll(path, now)
+	assert.Nil(t, err)
+	assert.Len(t, files, 0)
+	os.WriteFile(fn, []byte("abcdef"), 0755)
+	files, err = w.Poll(path, now.Add(10*time.Second))
+	assert.Nil(t, err)
+	assert.Len(t, files, 0)
+	files, err = w.Poll(path, now.Add(20*time.Second))
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+	endPath()
+}
+
+func TestPollWrongPath(t *testing.T) {
+	w := NewFileWatcher(time.Second, time.Second)
+	_, err := w.Poll("./notexists", time.Now())
+	assert.NotNil(t, err)
+}
+
+func TestWatch(t *testing.T) {
+	initPath()
+	os.WriteFile(filepath.Join(path, "file1.txt"), []byte("abc"), 0755)
+	w := NewFileWatcher(10*time.Millisecond, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	names := make([]string, 0)
+	err := w.Watch(ctx, path, func(file fs.FileInfo) {
+		names = append(names, file.Name())
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"file1.txt"}, names)
+	endPath()
+}
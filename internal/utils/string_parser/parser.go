@@ -1,18 +1,23 @@
 package string_parser
 
 import (
+	"encoding/csv"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
-	"github.com/pkg/errors"
+	"github.com/lavinas/cielo-edi/internal/core/ports"
 )
 
 const (
 	// tag_name identifies a struct tag that contains parameters for parsing text
 	tag_name = "txt"
+	// unitsTag is the tag of Money fields with amounts in units (see ports.ParseUnits)
+	unitsTag = "units"
 )
 
 var (
@@ -24,6 +29,7 @@ var (
 		"yy-mm-dd":   "06-01-02",
 		"ddmmyyyy":   "02012006",
 		"dd-mm-yyyy": "02-01-2006",
+		"dd/mm/yyyy": "02/01/2006",
 	}
 	// time_type maps struct fields that this program can support for parsing txt
 	// others types should be implemented here
@@ -35,12 +41,16 @@ var (
 		"int64":  "d",
 		"string": "s",
 		"Time":   "t",
+		"Money":  "m",
 	}
 )
 
 // StringParser has ability to Parse text strings into the fields of generic struct
 type StringParser struct {
 	parserType string
+	separator  string
+	columns    map[string]int
+	dateFormat string
 }
 
 // UnmarshalField try to find a structure field and parse the value of a string based on the parameters of this field
@@ -65,40 +75,157 @@ func (s StringParser) ParseField(source interface{}, fieldName string, txt strin
 	if fieldTag == "-" {
 		return txtPos, nil
 	}
+	// named columns
+	if s.columns != nil {
+		return txtPos + 1, s.parseColumn(source, fieldName, fieldType, fieldIndex, fieldTag, txt)
+	}
 	// set values
 	var fieldLen int
 	switch fieldIndex {
 	case "d":
 		var dval int64
-		dval, fieldLen, err = getDecimal(fieldType, fieldIndex, fieldTag, s.parserType, txt, txtPos)
+		dval, fieldLen, err = getDecimal(fieldType, fieldIndex, fieldTag, s.parserType, s.separator, txt, txtPos)
 		if err != nil {
 			return txtPos, err
 		}
 		reflect.ValueOf(source).Elem().FieldByName(fieldName).SetInt(dval)
 	case "s":
 		var sVal string
-		sVal, fieldLen, err = getString(fieldIndex, fieldTag, s.parserType, txt, txtPos)
+		sVal, fieldLen, err = getString(fieldIndex, fieldTag, s.parserType, s.separator, txt, txtPos)
 		if err != nil {
 			return txtPos, err
 		}
 		reflect.ValueOf(source).Elem().FieldByName(fieldName).SetString(sVal)
 	case "t":
 		var tVal time.Time
-		tVal, fieldLen, err = getTime(fieldIndex, fieldTag, s.parserType, txt, txtPos)
+		tVal, fieldLen, err = getTime(fieldIndex, fieldTag, s.parserType, s.separator, txt, txtPos)
 		if err != nil {
 			return txtPos, err
 		}
 		reflect.ValueOf(source).Elem().FieldByName(fieldName).Set(reflect.ValueOf(tVal))
-
+	case "m":
+		var mVal ports.Money
+		mVal, fieldLen, err = getMoney(fieldIndex, fieldTag, s.parserType, s.separator, txt, txtPos)
+		if err != nil {
+			return txtPos, err
+		}
+		reflect.ValueOf(source).Elem().FieldByName(fieldName).SetInt(int64(mVal))
 	default:
 		return txtPos, fmt.Errorf("invalid type")
 	}
 	return txtPos + fieldLen, nil
 }
 
+// parseColumn sets a field with the value of its named column. Fields without a column are left empty
+func (s StringParser) parseColumn(source interface{}, fieldName string, fieldType string, fieldIndex string,
+	fieldTag string, txt string) error {
+	col, ok := s.columns[strings.ToLower(fieldName)]
+	if !ok {
+		return nil
+	}
+	txtSplit, err := splitCsv(txt, s.separator)
+	if err != nil {
+		return err
+	}
+	if col >= len(txtSplit) {
+		return fmt.Errorf("unexpected end of csv for parsing this field")
+	}
+	value := columnValue(txtSplit[col])
+	field := reflect.ValueOf(source).Elem().FieldByName(fieldName)
+	switch fieldIndex {
+	case "d":
+		dVal, err := toDecimal(fieldType, value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(dVal)
+	case "s":
+		field.SetString(value)
+	case "t":
+		if s.dateFormat != "" {
+			fieldTag = s.dateFormat
+		}
+		tVal, err := toTime(fieldTag, value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(tVal))
+	case "m":
+		mVal, err := toMoney(fieldTag, value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(mVal))
+	default:
+		return fmt.Errorf("invalid type")
+	}
+	return nil
+}
+
+// SetColumns makes a csv parser read the fields from named columns instead of the struct field order, for
+// files with a header line like the exports of ERP and POS systems. The columns are separated by semicolons
+// when the header has them, or by commas otherwise
+//
+// header has the header line of the csv file
+// columns maps the struct field names to the header column names (both case insensitive). Fields without a
+// column are left empty
+//
+// returns a error if a column is not found on the header
+func (s *StringParser) SetColumns(header string, columns map[string]string) error {
+	if s.parserType != "csv" {
+		return fmt.Errorf("named columns are only supported by csv parsers")
+	}
+	s.separator = ","
+	if strings.Contains(header, ";") {
+		s.separator = ";"
+	}
+	names, err := splitCsv(header, s.separator)
+	if err != nil {
+		return err
+	}
+	fields := make([]string, 0, len(columns))
+	for field := range columns {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	s.columns = make(map[string]int)
+	for _, field := range fields {
+		column := columns[field]
+		if column == "" {
+			continue
+		}
+		col := -1
+		for i, name := range names {
+			if strings.EqualFold(columnValue(name), strings.TrimSpace(column)) {
+				col = i
+				break
+			}
+		}
+		if col < 0 {
+			return fmt.Errorf("column %s not found on the csv header", column)
+		}
+		s.columns[strings.ToLower(field)] = col
+	}
+	return nil
+}
+
+// SetDateFormat overrides the format of the tags of Time fields read from named columns (ex dd/mm/yyyy)
+func (s *StringParser) SetDateFormat(format string) error {
+	if format != "" && time_replacer[format] == "" {
+		return fmt.Errorf("invalid date format %s (should be for ex yyyymmdd)", format)
+	}
+	s.dateFormat = format
+	return nil
+}
+
+// columnValue returns a csv column value without spaces and quotes
+func columnValue(value string) string {
+	return strings.Trim(strings.TrimSpace(value), "\"")
+}
+
 // Unmarshal try to find all structure fields values on a sequenced string based on this parameters (types and tags)
-// the possibles tags values are: a numeric value that represents the substring length if the field is integer or string or
-// a date format (ex yyyymmdd) if the field is a Time
+// the possibles tags values are: a numeric value that represents the substring length if the field is integer, string or
+// Money (cents), units for Money fields of csv amounts in units or a date format (ex yyyymmdd) if the field is a Time
 //
 // source has a structure that possible have the field
 // txt has the string to be parsed based on the parameters of this field
@@ -112,31 +239,149 @@ func (s StringParser) Parse(source interface{}, txt string) error {
 	// unmarshall all fields
 	var strPosition int = 0
 	var err error
-	fields := reflect.ValueOf(source).Elem()
-	for i := 0; i < fields.NumField(); i++ {
-		fieldName := fields.Type().Field(i).Name
+	for _, fieldName := range fieldNames(reflect.TypeOf(source).Elem()) {
 		strPosition, err = s.ParseField(source, fieldName, txt, strPosition)
 		if err != nil {
-			err = errors.Wrap(err, fieldName)
-			return err
+			return &ports.FieldError{Field: fieldName, Err: err}
 		}
 	}
 	return nil
 }
 
+// Inspect parses all structure fields like Parse but does not stop on the first error. Each field
+// is described with its offset, length, raw substring, decoded value and error, so a layout that
+// does not fit a txt can be debugged field by field
+//
+// source has a structure that possible have the field
+// txt has the string to be parsed based on the parameters of this field
+//
+// returns the description of each field that is not discarded ("-" tag)
+func (s StringParser) Inspect(source interface{}, txt string) []ports.FieldInspection {
+	inspection := make([]ports.FieldInspection, 0)
+	if err := verifyValidInterface(source); err != nil {
+		return inspection
+	}
+	var strPosition int = 0
+	fields := reflect.ValueOf(source).Elem()
+	for _, fieldName := range fieldNames(fields.Type()) {
+		field := ports.FieldInspection{Name: fieldName, Offset: strPosition}
+		_, fieldIndex, fieldTag, err := getFieldByName(source, fieldName)
+		if err != nil {
+			field.Error = err.Error()
+			inspection = append(inspection, field)
+			continue
+		}
+		if fieldTag == "-" {
+			continue
+		}
+		var fieldLen int
+		field.Raw, field.Length, fieldLen = s.getRaw(fieldIndex, fieldTag, txt, strPosition)
+		if _, err := s.ParseField(source, fieldName, txt, strPosition); err != nil {
+			field.Error = err.Error()
+		} else if t, ok := fields.FieldByName(fieldName).Interface().(time.Time); ok {
+			field.Value = t.Format("2006-01-02")
+		} else {
+			field.Value = fmt.Sprint(fields.FieldByName(fieldName).Interface())
+		}
+		strPosition += fieldLen
+		inspection = append(inspection, field)
+	}
+	return inspection
+}
+
+// fieldNames returns the names of the fields of a structure in txt order. The fields of an embedded
+// structure are taken in its place, so records that share a block of fields can embed it
+func fieldNames(source reflect.Type) []string {
+	names := make([]string, 0, source.NumField())
+	for i := 0; i < source.NumField(); i++ {
+		field := source.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			names = append(names, fieldNames(field.Type)...)
+			continue
+		}
+		names = append(names, field.Name)
+	}
+	return names
+}
+
+// getRaw returns the substring of a field even when it is shorter than expected
+//
+// returns the substring, the expected field length and the increment of the txt position
+func (s StringParser) getRaw(fieldIndex string, tagValue string, txt string, txtPos int) (string, int, int) {
+	if s.parserType == "csv" {
+		txtSplit, err := splitCsv(txt, s.separator)
+		if err != nil || txtPos >= len(txtSplit) {
+			return "", 0, 1
+		}
+		return txtSplit[txtPos], len(txtSplit[txtPos]), 1
+	}
+	fieldLen := len(tagValue)
+	if fieldIndex != "t" {
+		fieldLen, _ = strconv.Atoi(tagValue)
+	}
+	txtLen := utf8.RuneCountInString(txt)
+	if txtPos >= txtLen {
+		return "", fieldLen, fieldLen
+	}
+	end := txtPos + fieldLen
+	if end > txtLen {
+		end = txtLen
+	}
+	return substring(txt, txtPos, end), fieldLen, fieldLen
+}
+
+// substring returns the characters of txt from start to end. Positions count characters, not bytes,
+// so the offsets of a layout are kept when a decoded txt has accented letters. Each invalid UTF-8
+// byte counts as a character
+func substring(txt string, start int, end int) string {
+	if utf8.RuneCountInString(txt) == len(txt) {
+		return txt[start:end]
+	}
+	startByte, pos := len(txt), 0
+	for i := 0; i < len(txt); pos++ {
+		if pos == start {
+			startByte = i
+		}
+		if pos == end {
+			return txt[startByte:i]
+		}
+		_, size := utf8.DecodeRuneInString(txt[i:])
+		i += size
+	}
+	return txt[startByte:]
+}
+
 func NewStringParser(parserType string) *StringParser {
-	return &StringParser{parserType: strings.ToLower(parserType)}
+	return &StringParser{parserType: strings.ToLower(parserType), separator: ","}
+}
+
+// splitCsv splits a csv line into its values. Quoted values may have the separator and are returned without
+// their quotes; quotes inside unquoted values are kept
+func splitCsv(txt string, separator string) ([]string, error) {
+	if txt == "" {
+		return []string{""}, nil
+	}
+	reader := csv.NewReader(strings.NewReader(txt))
+	reader.Comma, _ = utf8.DecodeRuneInString(separator)
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+	values, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv line (%v)", err)
+	}
+	return values, nil
 }
 
 // getValue parse string and returns substring and its length based on field parameters (Index(type) and tag)
 //
 // fieldIndex describes field type (D - decimal/integer, S - string, T - Time)
 // tagValue has the tag value of struct field
+// separator has the separator of the values of a csv txt
 // txt has a string that is parsed
 // txtPos has the position of txt to start to parse
 //
 // returns a substring parsed, the len of this string and a possible error
-func getValue(fieldIndex string, tagValue string, ptype string, txt string, txtPos int) (string, int, error) {
+func getValue(fieldIndex string, tagValue string, ptype string, separator string, txt string, txtPos int) (string, int, error) {
 	var value string
 	var addPos int
 	switch ptype {
@@ -151,13 +396,16 @@ func getValue(fieldIndex string, tagValue string, ptype string, txt string, txtP
 				return "", txtPos, fmt.Errorf("invalid tag value (should be numeric)")
 			}
 		}
-		if txtPos+fieldLen > len(txt) {
+		if txtPos+fieldLen > utf8.RuneCountInString(txt) {
 			return "", txtPos, fmt.Errorf("unexpected end of txt for parsing this field")
 		}
-		value = txt[txtPos : txtPos+fieldLen]
+		value = substring(txt, txtPos, txtPos+fieldLen)
 		addPos = fieldLen
 	case "csv":
-		txtSplit := strings.Split(txt, ",")
+		txtSplit, err := splitCsv(txt, separator)
+		if err != nil {
+			return "", txtPos, err
+		}
 		if txtPos >= len(txtSplit) {
 			return "", txtPos, fmt.Errorf("unexpected end of csv for parsing this field")
 		}
@@ -177,22 +425,30 @@ func getValue(fieldIndex string, tagValue string, ptype string, txt string, txtP
 // txtPos has the position of txt to start to parse
 //
 // returns a substring parsed transformed in a integer (8, 16, 32 or 64), the len of this string and a possible error
-func getDecimal(fieldType string, fieldIndex string, tagValue string, ptype string, txt string, txtPos int) (int64, int, error) {
-	var dimN int = 32
-	var err error
-	value, txtPos, err := getValue(fieldIndex, tagValue, ptype, txt, txtPos)
+func getDecimal(fieldType string, fieldIndex string, tagValue string, ptype string, separator string, txt string, txtPos int) (int64, int, error) {
+	value, txtPos, err := getValue(fieldIndex, tagValue, ptype, separator, txt, txtPos)
 	if err != nil {
 		return 0, 0, err
 	}
+	dec, err := toDecimal(fieldType, value)
+	if err != nil {
+		return 0, 0, err
+	}
+	return dec, txtPos, nil
+}
+
+// toDecimal converts a value to an integer of the size of the field type (int, int8, int16, int32 or int64)
+func toDecimal(fieldType string, value string) (int64, error) {
+	var dimN int = 32
 	dim := fieldType[3:]
 	if dim != "" {
 		dimN, _ = strconv.Atoi(dim)
 	}
 	dec, err := strconv.ParseInt(value, 10, dimN)
 	if err != nil {
-		return 0, 0, fmt.Errorf("parsing integer error")
+		return 0, fmt.Errorf("parsing integer error")
 	}
-	return dec, txtPos, nil
+	return dec, nil
 }
 
 // getStrings parse string and returns a string value and its length based on field parameters (Index(type) and tag)
@@ -203,14 +459,48 @@ func getDecimal(fieldType string, fieldIndex string, tagValue string, ptype stri
 // txtPos has the position of txt to start to parse
 //
 // returns a substring parsed, the len of this string and a possible error
-func getString(fieldIndex string, tagValue string, ptype string, txt string, txtPos int) (string, int, error) {
-	value, txtPos, err := getValue(fieldIndex, tagValue, ptype, txt, txtPos)
+func getString(fieldIndex string, tagValue string, ptype string, separator string, txt string, txtPos int) (string, int, error) {
+	value, txtPos, err := getValue(fieldIndex, tagValue, ptype, separator, txt, txtPos)
 	if err != nil {
 		return "", 0, err
 	}
 	return value, txtPos, nil
 }
 
+// getMoney parse string and returns a Money value and its length based on field parameters (Index(type) and tag)
+//
+// fieldIndex describes field type (D - decimal/integer, S - string, T - Time, M - Money)
+// tagValue has the tag value of struct field
+// txt has a string that is parsed
+// txtPos has the position of txt to start to parse
+//
+// returns a substring parsed transformed in cents (digits without separator are cents), the len of this string and a possible error
+func getMoney(fieldIndex string, tagValue string, ptype string, separator string, txt string, txtPos int) (ports.Money, int, error) {
+	value, txtPos, err := getValue(fieldIndex, tagValue, ptype, separator, txt, txtPos)
+	if err != nil {
+		return 0, 0, err
+	}
+	m, err := toMoney(tagValue, value)
+	if err != nil {
+		return 0, 0, err
+	}
+	return m, txtPos, nil
+}
+
+// toMoney converts a value to Money. Values of fields tagged units are amounts in units that may have thousands
+// separators and a decimal comma, like 1.234,56 (csv only, as the tag has no length)
+func toMoney(tagValue string, value string) (ports.Money, error) {
+	parse := ports.ParseMoney
+	if tagValue == unitsTag {
+		parse = ports.ParseUnits
+	}
+	m, err := parse(value)
+	if err != nil {
+		return 0, fmt.Errorf("parsing money error")
+	}
+	return m, nil
+}
+
 // getStrings parse string and returns a Time value and its length based on field parameters (Index(type) and tag)
 //
 // fieldIndex describes field type (D - decimal/integer, S - string, T - Time)
@@ -219,20 +509,29 @@ func getString(fieldIndex string, tagValue string, ptype string, txt string, txt
 // txtPos has the position of txt to start to parse
 //
 // returns a substring parsed transformed in a Time variable, the len of this string and a possible error
-func getTime(fieldIndex string, tagValue string, ptype string, txt string, txtPos int) (time.Time, int, error) {
-	value, txtPos, err := getValue(fieldIndex, tagValue, ptype, txt, txtPos)
+func getTime(fieldIndex string, tagValue string, ptype string, separator string, txt string, txtPos int) (time.Time, int, error) {
+	value, txtPos, err := getValue(fieldIndex, tagValue, ptype, separator, txt, txtPos)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	t, err := toTime(tagValue, value)
 	if err != nil {
 		return time.Time{}, 0, err
 	}
+	return t, txtPos, nil
+}
+
+// toTime converts a value to a Time with the format of a tag (ex yyyymmdd)
+func toTime(tagValue string, value string) (time.Time, error) {
 	rFormat := time_replacer[tagValue]
 	if rFormat == "" {
-		return time.Time{}, 0, fmt.Errorf("invalid datetime tag value (should be for ex yyyymmdd)")
+		return time.Time{}, fmt.Errorf("invalid datetime tag value (should be for ex yyyymmdd)")
 	}
 	t, err := time.Parse(rFormat, value)
 	if err != nil {
-		return time.Time{}, 0, fmt.Errorf(fmt.Sprintf("%v", err))
+		return time.Time{}, fmt.Errorf(fmt.Sprintf("%v", err))
 	}
-	return t, txtPos, nil
+	return t, nil
 }
 
 // getFieldByName try to find a structure field parameters (type, index)
@@ -1,9 +1,12 @@
 package string_parser
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/lavinas/cielo-edi/internal/core/ports"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -56,6 +59,13 @@ func TestParseCSVOK(t *testing.T) {
 	dat, _ := time.Parse("2006-01-02", "2021-05-15")
 	assert.Equal(t, dat, header.ProcDate)
 	assert.Equal(t, "V1.04 - 07/10 - EEVD", header.LayoutVersion)
+	// a quoted value with a comma is a single value
+	quoted := strings.Replace(headerlineCsv, "NESPRESSO PJM             ", `"NESPRESSO, PJM"`, 1)
+	header = HeaderCSV{}
+	assert.Nil(t, sp.Parse(&header, quoted))
+	assert.Equal(t, "NESPRESSO, PJM", header.HeadQquarterName)
+	assert.Equal(t, 297, header.Sequence)
+	assert.Equal(t, "V1.04 - 07/10 - EEVD", header.LayoutVersion)
 }
 
 func TestParseFieldOk(t *testing.T) {
@@ -416,6 +426,9 @@ func TestParseErrorShortString(t *testing.T) {
 	err := sp.Parse(&header, "910238632322021063020210630202106300008358CIELO04I                    ")
 	assert.NotNil(t, err)
 	assert.Equal(t, "LayoutVersion: unexpected end of txt for parsing this field", err.Error())
+	var ferr *ports.FieldError
+	assert.True(t, errors.As(err, &ferr))
+	assert.Equal(t, "LayoutVersion", ferr.Field)
 }
 
 func TestParseErrorInProcDate(t *testing.T) {
@@ -442,3 +455,178 @@ func TestParseErrorInProcDate(t *testing.T) {
 func TestParseOkCsv(t *testing.T) {
 
 }
+
+func TestInspect(t *testing.T) {
+	sp := *NewStringParser("position")
+	header := Header{}
+	fields := sp.Inspect(&header, "9102386323220210631202106302021063000083X8CIELO04I                    01")
+	assert.Len(t, fields, 12)
+	assert.Equal(t, "RegisterType", fields[0].Name)
+	assert.Equal(t, "9", fields[0].Value)
+	assert.Equal(t, 11, fields[2].Offset)
+	assert.Equal(t, 8, fields[2].Length)
+	assert.Equal(t, "20210631", fields[2].Raw)
+	assert.Equal(t, `parsing time "20210631": day out of range`, fields[2].Error)
+	assert.Equal(t, "2021-06-30", fields[3].Value)
+	assert.Equal(t, "00083X8", fields[5].Raw)
+	assert.Equal(t, "parsing integer error", fields[5].Error)
+	assert.Equal(t, "CIELO", fields[6].Value)
+	assert.Equal(t, 70, fields[10].Offset)
+	assert.Equal(t, "01", fields[10].Raw)
+	assert.Equal(t, "unexpected end of txt for parsing this field", fields[10].Error)
+	assert.Equal(t, "", fields[11].Raw)
+	sp = *NewStringParser("csv")
+	headerCsv := HeaderCSV{}
+	fields = sp.Inspect(&headerCsv, headerlineCsv)
+	assert.Len(t, fields, 10)
+	assert.Equal(t, 9, fields[9].Offset)
+	assert.Equal(t, "V1.04 - 07/10 - EEVD", fields[9].Value)
+	assert.Len(t, sp.Inspect(nil, headerlineCsv), 0)
+}
+
+func TestParseAccented(t *testing.T) {
+	type Name struct {
+		Name string `txt:"8"`
+		Code int    `txt:"3"`
+	}
+	sp := *NewStringParser("position")
+	name := Name{}
+	err := sp.Parse(&name, "CAFÉ SÃO123")
+	assert.Nil(t, err)
+	assert.Equal(t, "CAFÉ SÃO", name.Name)
+	assert.Equal(t, 123, name.Code)
+	err = sp.Parse(&name, "CAF\xc9 S\xc3O456")
+	assert.Nil(t, err)
+	assert.Equal(t, 456, name.Code)
+	err = sp.Parse(&name, "CAFÉ SÃO12")
+	assert.NotNil(t, err)
+	fields := sp.Inspect(&name, "ÇÇÇÇÇÇÇÇ1")
+	assert.Equal(t, "ÇÇÇÇÇÇÇÇ", fields[0].Raw)
+	assert.Equal(t, 8, fields[1].Offset)
+	assert.Equal(t, "1", fields[1].Raw)
+	assert.Equal(t, "BC", substring("ABCD", 1, 3))
+	assert.Equal(t, "ÃO", substring("SÃO", 1, 3))
+	assert.Equal(t, "", substring("SÃO", 3, 3))
+}
+
+func TestParseEmbedded(t *testing.T) {
+	type Summary struct {
+		Establishment int64 `txt:"5"`
+		Number        int   `txt:"3"`
+	}
+	type Payment struct {
+		RegisterType int8 `txt:"1"`
+		Summary
+		Brand string `txt:"3"`
+	}
+	sp := *NewStringParser("position")
+	payment := Payment{}
+	err := sp.Parse(&payment, "1123450071VC")
+	assert.Nil(t, err)
+	assert.Equal(t, int8(1), payment.RegisterType)
+	assert.Equal(t, int64(12345), payment.Establishment)
+	assert.Equal(t, 7, payment.Number)
+	assert.Equal(t, "1VC", payment.Brand)
+	err = sp.Parse(&payment, "11234X0071VC")
+	var ferr *ports.FieldError
+	assert.True(t, errors.As(err, &ferr))
+	assert.Equal(t, "Establishment", ferr.Field)
+	fields := sp.Inspect(&payment, "1123450071VC")
+	assert.Len(t, fields, 4)
+	assert.Equal(t, "Number", fields[2].Name)
+	assert.Equal(t, 6, fields[2].Offset)
+	assert.Equal(t, "7", fields[2].Value)
+	assert.Equal(t, "1VC", fields[3].Raw)
+}
+
+func TestParseMoney(t *testing.T) {
+	type Amounts struct {
+		Gross    ports.Money `txt:"15"`
+		Discount ports.Money `txt:"6"`
+	}
+	sp := *NewStringParser("position")
+	amounts := Amounts{}
+	err := sp.Parse(&amounts, "000000000123456-00012")
+	assert.Nil(t, err)
+	assert.Equal(t, ports.Money(123456), amounts.Gross)
+	assert.Equal(t, ports.Money(-12), amounts.Discount)
+	fields := sp.Inspect(&amounts, "000000000123456-00012")
+	assert.Equal(t, "1234.56", fields[0].Value)
+	assert.Equal(t, "-0.12", fields[1].Value)
+	err = sp.Parse(&amounts, "0000000001234X6000012")
+	assert.NotNil(t, err)
+	assert.Equal(t, "Gross: parsing money error", err.Error())
+	sp = *NewStringParser("csv")
+	err = sp.Parse(&amounts, "1234.56,-0.5")
+	assert.Nil(t, err)
+	assert.Equal(t, ports.Money(123456), amounts.Gross)
+	assert.Equal(t, ports.Money(-50), amounts.Discount)
+}
+
+func TestParseColumns(t *testing.T) {
+	type Sale struct {
+		Date   time.Time   `txt:"yyyy-mm-dd"`
+		Amount ports.Money `txt:"15"`
+		Nsu    string      `txt:"12"`
+		Card   string      `txt:"19"`
+	}
+	sp := NewStringParser("csv")
+	columns := map[string]string{"date": "Data Venda", "amount": "valor", "nsu": "NSU"}
+	err := sp.SetColumns(`Loja;NSU;"Valor";Data Venda`, columns)
+	assert.Nil(t, err)
+	assert.Nil(t, sp.SetDateFormat("dd/mm/yyyy"))
+	sale := Sale{}
+	err = sp.Parse(&sale, `12;000123;"1234,56";10/03/2021`)
+	assert.Nil(t, err)
+	assert.Equal(t, "2021-03-10", sale.Date.Format("2006-01-02"))
+	assert.Equal(t, ports.Money(123456), sale.Amount)
+	assert.Equal(t, "000123", sale.Nsu)
+	assert.Equal(t, "", sale.Card)
+	// comma separated
+	err = sp.SetColumns("date,amount", map[string]string{"Date": "DATE", "Amount": "amount"})
+	assert.Nil(t, err)
+	assert.Nil(t, sp.SetDateFormat(""))
+	sale = Sale{}
+	assert.Nil(t, sp.Parse(&sale, "2021-03-10,12.30"))
+	assert.Equal(t, ports.Money(1230), sale.Amount)
+	// amounts in units
+	type UnitSale struct {
+		Amount ports.Money `txt:"units"`
+	}
+	assert.Nil(t, sp.SetColumns("amount;date", map[string]string{"amount": "amount"}))
+	unitSale := UnitSale{}
+	assert.Nil(t, sp.Parse(&unitSale, "150;2021-03-10"))
+	assert.Equal(t, ports.Money(15000), unitSale.Amount)
+	assert.Nil(t, sp.Parse(&unitSale, "1.234,56;2021-03-10"))
+	assert.Equal(t, ports.Money(123456), unitSale.Amount)
+	assert.NotNil(t, sp.Parse(&unitSale, "1,2345;2021-03-10"))
+	assert.NotNil(t, NewStringParser("position").Parse(&unitSale, "150"))
+	assert.Nil(t, sp.SetColumns("date,amount", map[string]string{"Date": "DATE", "Amount": "amount"}))
+	err = sp.Parse(&sale, "2021-03-10")
+	assert.NotNil(t, err)
+	assert.Equal(t, "Amount: unexpected end of csv for parsing this field", err.Error())
+	// quoted values with the separator keep the columns in place
+	type Export struct {
+		Description string      `txt:"30"`
+		Amount      ports.Money `txt:"units"`
+		Nsu         string      `txt:"12"`
+	}
+	columns = map[string]string{"description": "Descrição", "amount": "Valor", "nsu": "NSU"}
+	assert.Nil(t, sp.SetColumns(`"Descrição";"Valor";"NSU"`, columns))
+	export := Export{}
+	assert.Nil(t, sp.Parse(&export, `"CAFÉ; PÃO";"1.234,56";000123`))
+	assert.Equal(t, "CAFÉ; PÃO", export.Description)
+	assert.Equal(t, ports.Money(123456), export.Amount)
+	assert.Equal(t, "000123", export.Nsu)
+	assert.Nil(t, sp.SetColumns("Descrição,Valor,NSU", columns))
+	assert.Nil(t, sp.Parse(&export, `"CAFE, PAO","1.234,56",000124`))
+	assert.Equal(t, "CAFE, PAO", export.Description)
+	assert.Equal(t, ports.Money(123456), export.Amount)
+	assert.Equal(t, "000124", export.Nsu)
+	// errors
+	err = sp.SetColumns("date,amount", map[string]string{"Date": "Data"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "column Data not found on the csv header", err.Error())
+	assert.NotNil(t, sp.SetDateFormat("dd.mm.yyyy"))
+	assert.NotNil(t, NewStringParser("position").SetColumns("date", map[string]string{}))
+}